@@ -0,0 +1,219 @@
+// Package client is a typed Go client for the movies API.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github/MahfujulSagor/movies_crud/internals/apperr"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultTimeout    = 30 * time.Second
+	defaultMaxRetries = 2
+	defaultBackoff    = 200 * time.Millisecond
+	maxBackoff        = 5 * time.Second
+	userAgent         = "movies-go-client"
+)
+
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	authorize  func(*http.Request)
+	maxRetries int
+	backoff    time.Duration
+	userAgent  string
+
+	Movies *MoviesService
+}
+
+type Option func(*Client)
+
+// WithHTTPClient replaces the underlying HTTP client, e.g. to add tracing transports
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.httpClient = hc }
+}
+
+// WithTimeout bounds each attempt, including reading the response body
+func WithTimeout(d time.Duration) Option {
+	return func(c *Client) { c.httpClient.Timeout = d }
+}
+
+// WithAPIKey authenticates with an API key sent as X-API-Key
+func WithAPIKey(key string) Option {
+	return func(c *Client) {
+		c.authorize = func(r *http.Request) { r.Header.Set("X-API-Key", key) }
+	}
+}
+
+// WithBearerToken authenticates with a session token or SSO JWT
+func WithBearerToken(token string) Option {
+	return func(c *Client) {
+		c.authorize = func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+token) }
+	}
+}
+
+// WithRetries sets how often idempotent requests are retried and the initial backoff; zero disables retries
+func WithRetries(max int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = max
+		c.backoff = backoff
+	}
+}
+
+func WithUserAgent(ua string) Option {
+	return func(c *Client) { c.userAgent = ua }
+}
+
+// New returns a client for the API rooted at baseURL, e.g. "http://localhost:8080"
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(strings.TrimSuffix(baseURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid base URL %q: scheme must be http or https", baseURL)
+	}
+
+	c := &Client{
+		baseURL:    u,
+		httpClient: &http.Client{Timeout: defaultTimeout},
+		maxRetries: defaultMaxRetries,
+		backoff:    defaultBackoff,
+		userAgent:  userAgent,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.Movies = &MoviesService{client: c}
+
+	return c, nil
+}
+
+// do sends the request and decodes a JSON response into out; non-2xx responses become *Error
+func (c *Client) do(ctx context.Context, method string, path string, query url.Values, in any, out any) (*http.Response, error) {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return nil, fmt.Errorf("encoding request: %w", err)
+		}
+	}
+
+	u := *c.baseURL
+	u.Path += path
+	u.RawQuery = query.Encode()
+
+	//? POSTs are only replayed under an Idempotency-Key, which makes the server run them once
+	var key string
+	if method == http.MethodPost {
+		key = idempotencyKey(ctx)
+	}
+	retries := c.maxRetries
+	if method == http.MethodPost && key == "" {
+		retries = 0
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := c.send(ctx, method, u.String(), body, key)
+		if err == nil && resp.StatusCode < 300 {
+			defer resp.Body.Close()
+			if out != nil {
+				if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+					return resp, fmt.Errorf("decoding response: %w", err)
+				}
+			}
+			return resp, nil
+		}
+
+		if err == nil {
+			err = decodeError(resp)
+		}
+		if attempt >= retries || !retryable(err) || ctx.Err() != nil {
+			return resp, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return resp, ctx.Err()
+		case <-time.After(c.delay(attempt, err)):
+		}
+	}
+}
+
+func (c *Client) send(ctx context.Context, method string, target string, body []byte, key string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", c.userAgent)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if key != "" {
+		req.Header.Set("Idempotency-Key", key)
+	}
+	if c.authorize != nil {
+		c.authorize(req)
+	}
+
+	return c.httpClient.Do(req)
+}
+
+// delay is exponential backoff with full jitter, but never shorter than a server's Retry-After
+func (c *Client) delay(attempt int, err error) time.Duration {
+	d := min(c.backoff<<attempt, maxBackoff)
+	if d > 0 {
+		d = rand.N(d) + 1
+	}
+
+	var apiErr *Error
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > d {
+		d = apiErr.RetryAfter
+	}
+	return d
+}
+
+func retryable(err error) bool {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		//? The first attempt with our key is still running; its response is replayed once done
+		return apiErr.Code == apperr.CodeIdempotencyInProgress
+	}
+
+	//? Transport errors (connection refused, reset, timeout) are worth another attempt
+	return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
+
+func decodeError(resp *http.Response) error {
+	defer resp.Body.Close()
+
+	apiErr := &Error{StatusCode: resp.StatusCode}
+	if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		apiErr.RetryAfter = time.Duration(secs) * time.Second
+	}
+
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err := json.Unmarshal(data, &apiErr.Problem); err != nil || apiErr.Code == "" {
+		//? Not a problem document, e.g. an error page from a proxy
+		apiErr.Title = http.StatusText(resp.StatusCode)
+		apiErr.Detail = strings.TrimSpace(string(data))
+	}
+
+	return apiErr
+}
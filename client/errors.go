@@ -0,0 +1,57 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"github/MahfujulSagor/movies_crud/internals/apperr"
+	"github/MahfujulSagor/movies_crud/internals/utils/response"
+	"net/http"
+	"time"
+)
+
+// Error is a non-2xx response, decoded from the server's problem+json body
+type Error struct {
+	StatusCode int
+	// RetryAfter is set when the server asked the caller to back off
+	RetryAfter time.Duration
+	response.Problem
+}
+
+func (e *Error) Error() string {
+	if e.Detail != "" {
+		return fmt.Sprintf("movies api: %d %s: %s", e.StatusCode, e.Code, e.Detail)
+	}
+	return fmt.Sprintf("movies api: %d %s", e.StatusCode, e.Title)
+}
+
+// Code returns the stable error code of err, or "" when err did not come from the API
+func Code(err error) string {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr.Problem.Code
+	}
+	return ""
+}
+
+// IsNotFound reports whether err is a 404 from the API
+func IsNotFound(err error) bool {
+	return statusOf(err) == http.StatusNotFound
+}
+
+// IsConflict reports whether err is a 409 from the API, e.g. a duplicate movie title
+func IsConflict(err error) bool {
+	return statusOf(err) == http.StatusConflict
+}
+
+// IsValidation reports whether err carries field-level validation errors
+func IsValidation(err error) bool {
+	return Code(err) == apperr.CodeValidationFailed
+}
+
+func statusOf(err error) int {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode
+	}
+	return 0
+}
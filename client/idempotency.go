@@ -0,0 +1,27 @@
+package client
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+type idempotencyKeyCtx struct{}
+
+// WithIdempotencyKey makes POSTs sent with ctx carry key, so the server runs them at most once
+// and the client may retry them. Reuse the same key when retrying after a crash or restart.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyCtx{}, key)
+}
+
+// NewIdempotencyKey returns a random key suitable for WithIdempotencyKey
+func NewIdempotencyKey() string {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+func idempotencyKey(ctx context.Context) string {
+	key, _ := ctx.Value(idempotencyKeyCtx{}).(string)
+	return key
+}
@@ -0,0 +1,118 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"github/MahfujulSagor/movies_crud/internals/types"
+	"github/MahfujulSagor/movies_crud/internals/utils/response"
+	"iter"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+)
+
+// MaxPageSize is the largest page the server returns; bigger limits are capped
+const MaxPageSize = 50
+
+type MoviesService struct {
+	client *Client
+}
+
+// ListOptions selects a page of movies; a zero Limit uses the server default of 10
+type ListOptions struct {
+	Limit  int
+	Offset int
+}
+
+func (o ListOptions) query() url.Values {
+	q := url.Values{}
+	if o.Limit > 0 {
+		q.Set("limit", strconv.Itoa(o.Limit))
+	}
+	if o.Offset > 0 {
+		q.Set("offset", strconv.Itoa(o.Offset))
+	}
+	return q
+}
+
+// Create stores a movie and returns its ID. Unless ctx already carries one, a fresh
+// Idempotency-Key is generated so the request is retried without risking a duplicate.
+func (s *MoviesService) Create(ctx context.Context, movie *types.Movie) (int64, error) {
+	if idempotencyKey(ctx) == "" {
+		ctx = WithIdempotencyKey(ctx, NewIdempotencyKey())
+	}
+
+	var msg response.Message
+	resp, err := s.client.do(ctx, http.MethodPost, "/api/v1/movies", nil, movie, &msg)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := strconv.ParseInt(path.Base(resp.Header.Get("Location")), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("movie created but response has no usable Location header: %q", resp.Header.Get("Location"))
+	}
+	return id, nil
+}
+
+func (s *MoviesService) Get(ctx context.Context, id int64) (*types.Movie, error) {
+	var movie types.Movie
+	if _, err := s.client.do(ctx, http.MethodGet, moviePath(id), nil, nil, &movie); err != nil {
+		return nil, err
+	}
+	return &movie, nil
+}
+
+// List returns a single page of movies
+func (s *MoviesService) List(ctx context.Context, opts ListOptions) ([]*types.Movie, error) {
+	var movies []*types.Movie
+	if _, err := s.client.do(ctx, http.MethodGet, "/api/v1/movies", opts.query(), nil, &movies); err != nil {
+		return nil, err
+	}
+	return movies, nil
+}
+
+// All iterates over every movie from opts.Offset on, fetching pages of opts.Limit lazily.
+// Iteration stops after the first error, which is yielded with a nil movie.
+func (s *MoviesService) All(ctx context.Context, opts ListOptions) iter.Seq2[*types.Movie, error] {
+	return func(yield func(*types.Movie, error) bool) {
+		if opts.Limit <= 0 || opts.Limit > MaxPageSize {
+			opts.Limit = MaxPageSize
+		}
+
+		for {
+			page, err := s.List(ctx, opts)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			for _, movie := range page {
+				if !yield(movie, nil) {
+					return
+				}
+			}
+
+			if len(page) < opts.Limit {
+				return
+			}
+			opts.Offset += len(page)
+		}
+	}
+}
+
+// Update replaces the movie with the given ID
+func (s *MoviesService) Update(ctx context.Context, id int64, movie *types.Movie) error {
+	_, err := s.client.do(ctx, http.MethodPut, moviePath(id), nil, movie, nil)
+	return err
+}
+
+func (s *MoviesService) Delete(ctx context.Context, id int64) error {
+	_, err := s.client.do(ctx, http.MethodDelete, moviePath(id), nil, nil, nil)
+	return err
+}
+
+func moviePath(id int64) string {
+	return "/api/v1/movies/" + strconv.FormatInt(id, 10)
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"github/MahfujulSagor/movies_crud/client"
+	"github/MahfujulSagor/movies_crud/internals/auth"
+	"github/MahfujulSagor/movies_crud/internals/db/sqlite"
+	"github/MahfujulSagor/movies_crud/internals/events"
+	"github/MahfujulSagor/movies_crud/internals/http/clientip"
+	"github/MahfujulSagor/movies_crud/internals/http/handlers/apikeys"
+	"github/MahfujulSagor/movies_crud/internals/http/handlers/health"
+	"github/MahfujulSagor/movies_crud/internals/idempotency"
+	"github/MahfujulSagor/movies_crud/internals/logger"
+	"github/MahfujulSagor/movies_crud/internals/ratelimit"
+	"github/MahfujulSagor/movies_crud/internals/types"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testServer serves the real routes and middleware over a fresh database. wrap, when set, sits in
+// front of the mux so a test can simulate failures between the client and the server.
+func testServer(t *testing.T, wrap func(http.Handler) http.Handler) (*httptest.Server, *sqlite.SQLite, string) {
+	t.Helper()
+	if logger.Info == nil {
+		logger.Info = log.New(io.Discard, "", 0)
+		logger.Error = log.New(io.Discard, "", 0)
+	}
+
+	cfg := testConfig(t)
+	store, err := sqlite.New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	apiRoutes, err := routes(cfg, store, nil, events.NewBus(0), health.NewState())
+	if err != nil {
+		t.Fatal(err)
+	}
+	authn, err := auth.NewAuthenticator(cfg, store, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolver, err := clientip.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	mount(mux, apiRoutes, authn, ratelimit.New(cfg, ratelimit.NewMemoryStore(), resolver), idempotency.New(cfg, store))
+
+	var handler http.Handler = mux
+	if wrap != nil {
+		handler = wrap(mux)
+	}
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	key, err := apikeys.Create(store, "client tests", []string{auth.ScopeMoviesRead, auth.ScopeMoviesWrite, auth.ScopeMoviesDelete})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server, store, key.Key
+}
+
+func testClient(t *testing.T, server *httptest.Server, key string, opts ...client.Option) *client.Client {
+	t.Helper()
+	c, err := client.New(server.URL, append([]client.Option{client.WithAPIKey(key)}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func clientMovie(title string) *types.Movie {
+	return &types.Movie{
+		Title:    title,
+		Rating:   7,
+		Director: &types.Director{Name: "Director of " + title, Age: 50},
+		Cast:     &types.Cast{Actor: "Actor", Actress: "Actress"},
+	}
+}
+
+func TestClientMovieLifecycle(t *testing.T) {
+	server, _, key := testServer(t, nil)
+	c := testClient(t, server, key)
+	ctx := context.Background()
+
+	id, err := c.Movies.Create(ctx, clientMovie("Heat"))
+	if err != nil {
+		t.Fatal("create:", err)
+	}
+
+	got, err := c.Movies.Get(ctx, id)
+	if err != nil {
+		t.Fatal("get:", err)
+	}
+	if got.ID != id || got.Title != "Heat" || got.Director.Name != "Director of Heat" {
+		t.Fatalf("get returned %+v, want movie %d named Heat", got, id)
+	}
+
+	update := clientMovie("Heat (1995)")
+	update.Rating = 9
+	if err := c.Movies.Update(ctx, id, update); err != nil {
+		t.Fatal("update:", err)
+	}
+	got, err = c.Movies.Get(ctx, id)
+	if err != nil {
+		t.Fatal("get after update:", err)
+	}
+	if got.Title != "Heat (1995)" || got.Rating != 9 {
+		t.Fatalf("get after update returned %q rated %d", got.Title, got.Rating)
+	}
+
+	if err := c.Movies.Delete(ctx, id); err != nil {
+		t.Fatal("delete:", err)
+	}
+	_, err = c.Movies.Get(ctx, id)
+	if !client.IsNotFound(err) {
+		t.Fatalf("get after delete returned %v, want not found", err)
+	}
+	if err := c.Movies.Delete(ctx, id); !client.IsNotFound(err) {
+		t.Fatalf("second delete returned %v, want not found", err)
+	}
+}
+
+func TestClientListPagination(t *testing.T) {
+	server, _, key := testServer(t, nil)
+	c := testClient(t, server, key)
+	ctx := context.Background()
+
+	titles := []string{"Alien", "Brazil", "Casablanca", "Dune", "Easy Rider"}
+	for _, title := range titles {
+		if _, err := c.Movies.Create(ctx, clientMovie(title)); err != nil {
+			t.Fatal("create:", err)
+		}
+	}
+
+	first, err := c.Movies.List(ctx, client.ListOptions{Limit: 2})
+	if err != nil {
+		t.Fatal("list:", err)
+	}
+	second, err := c.Movies.List(ctx, client.ListOptions{Limit: 2, Offset: 2})
+	if err != nil {
+		t.Fatal("list:", err)
+	}
+	if len(first) != 2 || len(second) != 2 {
+		t.Fatalf("pages have %d and %d movies, want 2 each", len(first), len(second))
+	}
+	if first[0].ID == second[0].ID || first[1].ID == second[1].ID {
+		t.Fatal("offset 2 returned the same movies as the first page")
+	}
+
+	seen := map[int64]bool{}
+	for movie, err := range c.Movies.All(ctx, client.ListOptions{Limit: 2}) {
+		if err != nil {
+			t.Fatal("all:", err)
+		}
+		if seen[movie.ID] {
+			t.Fatalf("all returned movie %d twice", movie.ID)
+		}
+		seen[movie.ID] = true
+	}
+	if len(seen) != len(titles) {
+		t.Fatalf("all returned %d movies, want %d", len(seen), len(titles))
+	}
+}
+
+func TestClientDecodesProblems(t *testing.T) {
+	server, _, key := testServer(t, nil)
+	ctx := context.Background()
+
+	invalid := clientMovie("Vertigo")
+	invalid.Rating = 11
+	_, err := testClient(t, server, key).Movies.Create(ctx, invalid)
+	if !client.IsValidation(err) {
+		t.Fatalf("create with rating 11 returned %v, want a validation error", err)
+	}
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest || len(apiErr.Errors) == 0 {
+		t.Fatalf("validation error = %+v, want 400 with field errors", err)
+	}
+
+	_, err = testClient(t, server, "mk_not_a_real_key").Movies.Get(ctx, 1)
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized || client.Code(err) == "" {
+		t.Fatalf("get with a bad key returned %v, want 401 with a code", err)
+	}
+}
+
+func TestClientDecodesNonProblemErrors(t *testing.T) {
+	server, _, key := testServer(t, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/plain")
+			w.WriteHeader(http.StatusForbidden)
+			io.WriteString(w, "blocked by proxy\n")
+		})
+	})
+
+	_, err := testClient(t, server, key).Movies.Get(context.Background(), 1)
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("got %v, want *client.Error", err)
+	}
+	if apiErr.StatusCode != http.StatusForbidden || apiErr.Title != "Forbidden" || apiErr.Detail != "blocked by proxy" {
+		t.Fatalf("decoded %+v, want the proxy page as title and detail", apiErr)
+	}
+}
+
+func TestClientRetriesUnavailable(t *testing.T) {
+	var failures atomic.Int32
+	failures.Store(2)
+	server, _, key := testServer(t, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if failures.Add(-1) >= 0 {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+
+	c := testClient(t, server, key, client.WithRetries(2, time.Millisecond))
+	if _, err := c.Movies.List(context.Background(), client.ListOptions{}); err != nil {
+		t.Fatal("list after two 503s:", err)
+	}
+
+	failures.Store(3)
+	_, err := c.Movies.List(context.Background(), client.ListOptions{})
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("list after three 503s returned %v, want the last 503", err)
+	}
+}
+
+func TestClientRetriedCreateRunsOnce(t *testing.T) {
+	//? The first create runs on the server but its response is lost, as if a proxy timed out
+	var lost atomic.Bool
+	lost.Store(true)
+	server, store, key := testServer(t, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodPost && lost.CompareAndSwap(true, false) {
+				next.ServeHTTP(httptest.NewRecorder(), r)
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+
+	c := testClient(t, server, key, client.WithRetries(1, time.Millisecond))
+	id, err := c.Movies.Create(context.Background(), clientMovie("Ran"))
+	if err != nil {
+		t.Fatal("create:", err)
+	}
+
+	movies, err := store.GetMovieList(context.Background(), 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(movies) != 1 || movies[0].ID != id {
+		t.Fatalf("store has %d movies after a retried create, want only movie %d", len(movies), id)
+	}
+}
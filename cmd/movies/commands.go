@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github/MahfujulSagor/movies_crud/internals/auth"
+	"github/MahfujulSagor/movies_crud/internals/config"
+	"github/MahfujulSagor/movies_crud/internals/db"
+	"github/MahfujulSagor/movies_crud/internals/http/handlers/apikeys"
+	"github/MahfujulSagor/movies_crud/internals/http/handlers/users"
+	"github/MahfujulSagor/movies_crud/internals/openapi"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+)
+
+// commandArgs returns the positional arguments left after config flags were parsed
+func commandArgs() []string {
+	if flag.Parsed() {
+		return flag.Args()
+	}
+	return os.Args[1:]
+}
+
+type commandStore interface {
+	db.APIKeyStore
+	db.UserStore
+}
+
+func runCommand(store commandStore, apiRoutes []openapi.Route, args []string) error {
+	switch args[0] {
+	case "openapi":
+		return runOpenAPICommand(apiRoutes, args[1:])
+	case "apikey":
+		return runAPIKeyCommand(store, args[1:])
+	case "user":
+		return runUserCommand(store, args[1:])
+	default:
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
+
+// runConfigCommand prints the configuration in effect, after defaults and environment overrides, with secrets redacted
+func runConfigCommand(cfg *config.Config, args []string) error {
+	if len(args) != 1 || args[0] != "print" {
+		return fmt.Errorf("usage: movies config print")
+	}
+
+	out, err := yaml.Marshal(cfg.Redacted())
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(out)
+	return err
+}
+
+func runAPIKeyCommand(store db.APIKeyStore, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: movies apikey <create|list|revoke> [flags]")
+	}
+
+	switch args[0] {
+	case "create":
+		fs := flag.NewFlagSet("apikey create", flag.ContinueOnError)
+		name := fs.String("name", "", "Human-readable name for the key")
+		scopes := fs.String("scopes", auth.ScopeMoviesRead, "Comma-separated scopes ("+strings.Join(auth.Scopes, ", ")+")")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		if *name == "" {
+			return fmt.Errorf("-name is required")
+		}
+
+		scopeList := strings.Split(*scopes, ",")
+		for i := range scopeList {
+			scopeList[i] = strings.TrimSpace(scopeList[i])
+		}
+		if err := auth.ValidateScopes(scopeList); err != nil {
+			return err
+		}
+
+		created, err := apikeys.Create(store, *name, scopeList)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Created API key %d (%s)\n", created.ID, created.Prefix)
+		fmt.Println("Store it now, it will not be shown again:")
+		fmt.Println(created.Key)
+		return nil
+
+	case "list":
+		keys, err := store.ListAPIKeys()
+		if err != nil {
+			return err
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tNAME\tPREFIX\tSCOPES\tCREATED\tREVOKED")
+		for _, k := range keys {
+			revoked := "-"
+			if k.RevokedAt != nil {
+				revoked = k.RevokedAt.Format("2006-01-02 15:04")
+			}
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\n",
+				k.ID, k.Name, k.Prefix, strings.Join(k.Scopes, ","), k.CreatedAt.Format("2006-01-02 15:04"), revoked)
+		}
+		return tw.Flush()
+
+	case "revoke":
+		if len(args) < 2 {
+			return fmt.Errorf("usage: movies apikey revoke <id>")
+		}
+
+		id, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid ID: %w", err)
+		}
+
+		revoked_id, err := store.RevokeAPIKey(id)
+		if errors.Is(err, db.ErrNotFound) {
+			return fmt.Errorf("API key %d not found or already revoked", id)
+		}
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Revoked API key %d\n", revoked_id)
+		return nil
+
+	default:
+		return fmt.Errorf("unknown apikey command %q", args[0])
+	}
+}
+
+func runUserCommand(store db.UserStore, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: movies user <create|list> [flags]")
+	}
+
+	switch args[0] {
+	case "create":
+		fs := flag.NewFlagSet("user create", flag.ContinueOnError)
+		username := fs.String("username", "", "Login name")
+		role := fs.String("role", auth.RoleViewer, "Role ("+strings.Join(auth.Roles, ", ")+")")
+		password := fs.String("password", "", "Password (read from stdin when omitted)")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		if *username == "" {
+			return fmt.Errorf("-username is required")
+		}
+		if !slices.Contains(auth.Roles, *role) {
+			return fmt.Errorf("unknown role %q (valid: %s)", *role, strings.Join(auth.Roles, ", "))
+		}
+
+		//? Prefer stdin so passwords do not end up in shell history
+		if *password == "" {
+			fmt.Fprint(os.Stderr, "Password: ")
+			line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+			if err != nil && line == "" {
+				return fmt.Errorf("reading password: %w", err)
+			}
+			*password = strings.TrimRight(line, "\r\n")
+		}
+		if len(*password) < 8 {
+			return fmt.Errorf("password must be at least 8 characters")
+		}
+
+		user, err := users.Create(store, *username, *password, *role)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Created user %d (%s, %s)\n", user.ID, user.Username, user.Role)
+		return nil
+
+	case "list":
+		list, err := store.ListUsers()
+		if err != nil {
+			return err
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tUSERNAME\tROLE\tCREATED")
+		for _, u := range list {
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", u.ID, u.Username, u.Role, u.CreatedAt.Format("2006-01-02 15:04"))
+		}
+		return tw.Flush()
+
+	default:
+		return fmt.Errorf("unknown user command %q", args[0])
+	}
+}
+
+// runOpenAPICommand prints the specification, or with -check compares it to a committed copy so CI catches drift
+func runOpenAPICommand(apiRoutes []openapi.Route, args []string) error {
+	fs := flag.NewFlagSet("openapi", flag.ContinueOnError)
+	out := fs.String("o", "", "Write the specification to this file instead of stdout")
+	check := fs.String("check", "", "Fail if the generated specification differs from this file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	generated, err := specJSON(apiRoutes)
+	if err != nil {
+		return err
+	}
+
+	if *check == "" {
+		if *out != "" {
+			return os.WriteFile(*out, generated, 0644)
+		}
+		_, err := os.Stdout.Write(generated)
+		return err
+	}
+
+	committed, err := os.ReadFile(*check)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(committed, generated) {
+		return fmt.Errorf("%s is out of date with the registered routes; regenerate it with `movies openapi -o %s`", *check, *check)
+	}
+
+	fmt.Println(*check, "is up to date")
+	return nil
+}
+
+// specJSON renders the specification as committed in api/openapi.json
+func specJSON(apiRoutes []openapi.Route) ([]byte, error) {
+	generated, err := json.MarshalIndent(document(apiRoutes), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(generated, '\n'), nil
+}
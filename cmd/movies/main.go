@@ -2,21 +2,55 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"github/MahfujulSagor/movies_crud/internals/auth"
+	"github/MahfujulSagor/movies_crud/internals/cache"
 	"github/MahfujulSagor/movies_crud/internals/config"
 	"github/MahfujulSagor/movies_crud/internals/db/sqlite"
-	"github/MahfujulSagor/movies_crud/internals/http/handlers/movies"
+	"github/MahfujulSagor/movies_crud/internals/events"
+	"github/MahfujulSagor/movies_crud/internals/http/clientip"
+	"github/MahfujulSagor/movies_crud/internals/http/handlers/health"
+	"github/MahfujulSagor/movies_crud/internals/http/secure"
+	"github/MahfujulSagor/movies_crud/internals/idempotency"
 	"github/MahfujulSagor/movies_crud/internals/logger"
+	"github/MahfujulSagor/movies_crud/internals/outbox"
+	"github/MahfujulSagor/movies_crud/internals/ratelimit"
+	"github/MahfujulSagor/movies_crud/internals/rpc"
+	"github/MahfujulSagor/movies_crud/internals/tlsconfig"
+	"github/MahfujulSagor/movies_crud/internals/webhooks"
+	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
+
+	"golang.org/x/net/netutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
 func main() {
 	//? Setup Config
-	cfg := config.MustLoad()
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	//? Config commands run before the logger and database, so they work even when those cannot start
+	if args := commandArgs(); len(args) > 0 && args[0] == "config" {
+		if err := runConfigCommand(cfg, args[1:]); err != nil {
+			log.Fatal("Command failed: ", err)
+		}
+		return
+	}
+
+	//? Check the database and log locations once, before anything is opened
+	if err := cfg.CheckPaths(); err != nil {
+		log.Fatal(err)
+	}
 
 	//? Setup Logger
 	logger.Init(cfg)
@@ -29,42 +63,220 @@ func main() {
 	}
 	logger.Info.Println("Connected to database", "env:", cfg.Env)
 
+	//? Serve movie reads through the cache when enabled
+	var store routeStore = db
+	var cached *cache.Store
+	if cfg.CacheConfig.Enabled {
+		cached = cache.New(cfg, db, cache.NewLRU(cfg.CacheConfig.Size))
+		store = appStore{cached, db, db, db, db, db, db}
+	}
+
+	//? Setup routes
+	state := health.NewState()
+	bus := events.NewBus(events.DefaultHistory)
+	apiRoutes, err := routes(cfg, store, cached, bus, state)
+	if err != nil {
+		logger.Error.Fatal("Failed to build routes:", err)
+	}
+
+	//? Run an admin command instead of the server when one is given
+	if args := commandArgs(); len(args) > 0 {
+		if err := runCommand(db, apiRoutes, args); err != nil {
+			logger.Error.Fatal("Command failed:", err)
+		}
+		return
+	}
+
+	//? Setup authentication
+	authn, err := auth.NewAuthenticator(cfg, db, db)
+	if err != nil {
+		logger.Error.Fatal("Failed to initialize authentication:", err)
+	}
+
+	//? Setup rate limiting; the resolver also tells HTTPS requests behind trusted proxies apart
+	resolver, err := clientip.New(cfg.HTTPConfig.TrustedProxies)
+	if err != nil {
+		logger.Error.Fatal("Invalid trusted proxy list:", err)
+	}
+	limiter := ratelimit.New(cfg, ratelimit.NewMemoryStore(), resolver)
+
+	//? Setup the outbox relay and its sinks
+	dispatcher := webhooks.New(cfg, db)
+	sinks, err := outbox.NewSinks(cfg, bus, dispatcher)
+	if err != nil {
+		logger.Error.Fatal("Invalid outbox sinks:", err)
+	}
+	relay := outbox.New(cfg, db, sinks)
+
 	//? Setup mux
 	mux := http.NewServeMux()
+	mount(mux, apiRoutes, authn, limiter, idempotency.New(cfg, db))
 
-	//? Setup routes
-	mux.HandleFunc("POST /api/v1/movies", movies.New(db))
-	mux.HandleFunc("GET /api/v1/movies/{id}", movies.GetByID(db))
-	mux.HandleFunc("GET /api/v1/movies", movies.GetList(db))
-	mux.HandleFunc("PUT /api/v1/movies", movies.Update(db))
-	mux.HandleFunc("DELETE /api/v1/movies/{id}", movies.DeleteByID(db))
+	//? Compress responses for clients that accept it; swappable so a config reload can change it
+	compression := &swapHandler{}
+	compressed, err := withCompression(cfg, mux)
+	if err != nil {
+		logger.Error.Fatal("Invalid compression settings:", err)
+	}
+	compression.Store(compressed)
+
+	//? Let browser apps on allowed origins call the API
+	corsPolicy := &swapHandler{}
+	corsHandler, err := withCORS(cfg, compression)
+	if err != nil {
+		logger.Error.Fatal("Invalid CORS settings:", err)
+	}
+	corsPolicy.Store(corsHandler)
 
-	//? Setup server
+	//? Security headers go on every response, preflights included
+	handler := secure.New(cfg, resolver).Handler(corsPolicy)
+
+	//? Load TLS certificates when configured
+	var certs *tlsconfig.Manager
+	if cfg.HTTPConfig.TLS.Enabled() {
+		certs, err = tlsconfig.New(cfg)
+		if err != nil {
+			logger.Error.Fatal("Invalid TLS settings:", err)
+		}
+	}
+
+	//? Setup server; slow or idle clients are cut off rather than holding connections forever
 	server := http.Server{
-		Addr:    fmt.Sprintf("%s:%d", cfg.HTTPConfig.Host, cfg.HTTPConfig.Port),
-		Handler: mux,
+		Addr:              fmt.Sprintf("%s:%d", cfg.HTTPConfig.Host, cfg.HTTPConfig.Port),
+		Handler:           handler,
+		ReadHeaderTimeout: cfg.HTTPConfig.ReadHeaderTimeout,
+		ReadTimeout:       cfg.HTTPConfig.ReadTimeout,
+		WriteTimeout:      cfg.HTTPConfig.WriteTimeout,
+		IdleTimeout:       cfg.HTTPConfig.IdleTimeout,
+		MaxHeaderBytes:    cfg.HTTPConfig.MaxHeaderBytes,
+	}
+	if certs != nil {
+		server.TLSConfig = certs.Config()
+	}
+
+	listener, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		logger.Error.Fatal("Failed to listen:", err)
+	}
+	if cfg.HTTPConfig.MaxConns > 0 {
+		listener = netutil.LimitListener(listener, cfg.HTTPConfig.MaxConns)
 	}
 
 	//? Start server and listen for shutdown signal
-	logger.Info.Println("Server listening on:", server.Addr)
+	logger.Info.Println("Server listening on:", server.Addr, "tls:", certs != nil)
 	var done = make(chan os.Signal, 1)
 
 	signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
 
 	go func() {
-		if err := server.ListenAndServe(); err != nil {
+		var err error
+		if certs != nil {
+			//? Certificates come from TLSConfig, so no file names are passed
+			err = server.ServeTLS(listener, "", "")
+		} else {
+			err = server.Serve(listener)
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
 			logger.Error.Fatal("Failed to start server:", err)
 		}
 	}()
+
+	//? Redirect plain HTTP to HTTPS when configured
+	var redirectServer *http.Server
+	if certs != nil && cfg.HTTPConfig.TLS.RedirectPort != 0 {
+		redirectServer = &http.Server{
+			Addr:              fmt.Sprintf("%s:%d", cfg.HTTPConfig.Host, cfg.HTTPConfig.TLS.RedirectPort),
+			Handler:           secure.RedirectHTTPS(cfg.HTTPConfig.Port),
+			ReadHeaderTimeout: cfg.HTTPConfig.ReadHeaderTimeout,
+			ReadTimeout:       cfg.HTTPConfig.ReadTimeout,
+			WriteTimeout:      cfg.HTTPConfig.WriteTimeout,
+			IdleTimeout:       cfg.HTTPConfig.IdleTimeout,
+			MaxHeaderBytes:    cfg.HTTPConfig.MaxHeaderBytes,
+		}
+		logger.Info.Println("Redirecting HTTP to HTTPS on:", redirectServer.Addr)
+
+		go func() {
+			if err := redirectServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logger.Error.Fatal("Failed to start redirect server:", err)
+			}
+		}()
+	}
+
+	//? Start the outbox relay, webhook delivery and certificate reloads; they stop when the server shuts down
+	workers, stopWorkers := context.WithCancel(context.Background())
+	go relay.Run(workers)
+	go dispatcher.Run(workers)
+	if certs != nil {
+		go certs.Watch(workers, cfg.HTTPConfig.TLS.ReloadInterval)
+	}
+
+	//? Start the gRPC server on its own port when configured, over TLS when HTTPS is
+	var grpcServer *rpc.Server
+	if cfg.GRPCConfig.Port != 0 {
+		lis, err := net.Listen("tcp", fmt.Sprintf("%s:%d", cfg.HTTPConfig.Host, cfg.GRPCConfig.Port))
+		if err != nil {
+			logger.Error.Fatal("Failed to listen for gRPC:", err)
+		}
+
+		var opts []grpc.ServerOption
+		if certs != nil {
+			opts = append(opts, grpc.Creds(credentials.NewTLS(certs.Config())))
+		}
+		grpcServer = rpc.New(store, authn, opts...)
+		logger.Info.Println("gRPC server listening on:", lis.Addr())
+
+		go func() {
+			if err := grpcServer.Serve(lis); err != nil {
+				logger.Error.Fatal("Failed to start gRPC server:", err)
+			}
+		}()
+	}
+
+	//? Apply config file changes and SIGHUP reloads to the running components
+	if path, err := config.Path(); err == nil {
+		rl := &reloader{current: cfg, mux: mux, compression: compression, cors: corsPolicy, limiter: limiter, cached: cached}
+		go config.Watch(workers, path, cfg.ReloadInterval, rl.apply)
+	}
 	<-done
 
 	logger.Info.Println("Server shutting down...")
 
-	//? Shutdown server gracefully within 10 seconds
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	//? Report unready first so no new traffic is routed here while draining
+	state.SetShuttingDown()
+
+	//? Keep serving until load balancers have seen /readyz fail; a second signal skips the wait
+	if delay := cfg.HTTPConfig.ShutdownDelay; delay > 0 {
+		logger.Info.Println("Draining for", delay, "before closing connections")
+		select {
+		case <-time.After(delay):
+		case <-done:
+		}
+	}
+
+	//? End SSE and WebSocket streams, which would otherwise hold shutdown for the whole grace period;
+	//? their clients reconnect with Last-Event-ID
+	bus.Close()
+
+	//? Let in-flight requests finish within the grace period, then close what is left
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.HTTPConfig.ShutdownGrace)
 	defer cancel()
+	if grpcServer != nil {
+		grpcServer.Shutdown(ctx)
+	}
+	if redirectServer != nil {
+		redirectServer.Shutdown(ctx)
+	}
 	if err := server.Shutdown(ctx); err != nil {
-		logger.Error.Fatal("Server forced to shutdown:", err)
+		logger.Error.Println("Server forced to shutdown:", err)
+		server.Close()
+	}
+
+	//? Stop background workers; unrelayed events stay in the outbox for the next start
+	stopWorkers()
+
+	//? Close the database last, once nothing can query it
+	if err := db.Close(); err != nil {
+		logger.Error.Println("Error closing database:", err)
 	}
 	logger.Info.Println("Server shut down gracefully")
 }
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"github/MahfujulSagor/movies_crud/internals/config"
+	"github/MahfujulSagor/movies_crud/internals/db/sqlite"
+	"github/MahfujulSagor/movies_crud/internals/events"
+	"github/MahfujulSagor/movies_crud/internals/http/handlers/health"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testConfig reads a configuration with only the required settings, so everything else has its default
+func testConfig(t *testing.T) *config.Config {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	data := "env: test\ndb_path: " + filepath.Join(dir, "movies.db") + "\n"
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := config.Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+func TestOpenAPISpecMatchesRoutes(t *testing.T) {
+	cfg := testConfig(t)
+	store, err := sqlite.New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	apiRoutes, err := routes(cfg, store, nil, events.NewBus(0), health.NewState())
+	if err != nil {
+		t.Fatal(err)
+	}
+	generated, err := specJSON(apiRoutes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	committed, err := os.ReadFile("../../api/openapi.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(committed, generated) {
+		t.Fatal("api/openapi.json is out of date with the registered routes; regenerate it with " +
+			"`go run ./cmd/movies -config <config with default cache_control and body_limits> openapi -o api/openapi.json`")
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"github/MahfujulSagor/movies_crud/internals/cache"
+	"github/MahfujulSagor/movies_crud/internals/config"
+	"github/MahfujulSagor/movies_crud/internals/http/compress"
+	"github/MahfujulSagor/movies_crud/internals/http/cors"
+	"github/MahfujulSagor/movies_crud/internals/logger"
+	"github/MahfujulSagor/movies_crud/internals/ratelimit"
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+// swapHandler serves through the handler stored last, so a reload takes effect on the next request
+type swapHandler struct {
+	current atomic.Pointer[http.Handler]
+}
+
+func (s *swapHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	(*s.current.Load()).ServeHTTP(w, r)
+}
+
+func (s *swapHandler) Store(h http.Handler) {
+	s.current.Store(&h)
+}
+
+// withCompression compresses responses from next for clients that accept it, when enabled
+func withCompression(cfg *config.Config, next http.Handler) (http.Handler, error) {
+	if !cfg.HTTPConfig.Compression.Enabled {
+		return next, nil
+	}
+	compressor, err := compress.New(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return compressor.Handler(next), nil
+}
+
+// withCORS lets browser apps on the allowed origins call next; no origins leaves CORS off
+func withCORS(cfg *config.Config, next http.Handler) (http.Handler, error) {
+	if len(cfg.HTTPConfig.CORS.AllowedOrigins) == 0 {
+		return next, nil
+	}
+	c, err := cors.New(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return c.Handler(next), nil
+}
+
+// reloader applies the settings in config.Reloadable to running components
+type reloader struct {
+	current     *config.Config
+	mux         http.Handler
+	compression *swapHandler
+	cors        *swapHandler
+	limiter     *ratelimit.Limiter
+	// cached is nil when the read cache is off
+	cached *cache.Store
+}
+
+// apply is called by config.Watch with every re-read configuration. Nothing changes unless the whole
+// configuration is valid and only reloadable settings differ.
+func (rl *reloader) apply(next *config.Config, err error) {
+	if err != nil {
+		logger.Error.Println("Config reload failed, keeping the current settings:", err)
+		return
+	}
+
+	changed, fixed := rl.current.Changes(next)
+	if len(fixed) > 0 {
+		logger.Error.Printf("Config reload rejected: %s cannot change without a restart; only %s can be reloaded",
+			strings.Join(fixed, ", "), strings.Join(config.Reloadable, ", "))
+		return
+	}
+	if len(changed) == 0 {
+		return
+	}
+
+	//? Build the handlers first, so a setting they reject leaves every component as it was
+	compressed, err := withCompression(next, rl.mux)
+	if err != nil {
+		logger.Error.Println("Config reload rejected, invalid compression settings:", err)
+		return
+	}
+	corsHandler, err := withCORS(next, rl.compression)
+	if err != nil {
+		logger.Error.Println("Config reload rejected, invalid CORS settings:", err)
+		return
+	}
+
+	logger.SetLevel(next.LoggingConfig.Level)
+	rl.limiter.Reload(next)
+	if rl.cached != nil {
+		rl.cached.Reload(next)
+	}
+	rl.compression.Store(compressed)
+	rl.cors.Store(corsHandler)
+
+	rl.current = next
+	logger.Info.Println("Config reloaded:", strings.Join(changed, ", "))
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github/MahfujulSagor/movies_crud/internals/cache"
+	"github/MahfujulSagor/movies_crud/internals/config"
+	"github/MahfujulSagor/movies_crud/internals/db/sqlite"
+	"github/MahfujulSagor/movies_crud/internals/http/clientip"
+	"github/MahfujulSagor/movies_crud/internals/logger"
+	"github/MahfujulSagor/movies_crud/internals/ratelimit"
+	"github/MahfujulSagor/movies_crud/internals/types"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ttlBackend is an LRU that remembers the TTL of the last entry stored
+type ttlBackend struct {
+	*cache.LRU
+	ttl time.Duration
+}
+
+func (b *ttlBackend) Set(key string, value []byte, ttl time.Duration) {
+	b.ttl = ttl
+	b.LRU.Set(key, value, ttl)
+}
+
+// testReloader wires a reloader the way main does, over a rate-limited handler and a cached movie
+type testReloader struct {
+	*reloader
+	backend *ttlBackend
+	movieID int64
+	// errors holds what the reload logged as failed or rejected
+	errors *bytes.Buffer
+}
+
+func newTestReloader(t *testing.T) *testReloader {
+	t.Helper()
+	cfg := testConfig(t)
+	//? apply sets the log level, which needs the logger's real output
+	cfg.LoggingConfig.File = filepath.Join(t.TempDir(), "app.log")
+	logger.Init(cfg)
+	errs := &bytes.Buffer{}
+	logger.Error = log.New(errs, "", 0)
+
+	store, err := sqlite.New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	id, err := store.CreateMovie(context.Background(), &types.Movie{Title: "Heat", Rating: 8,
+		Director: &types.Director{Name: "Michael Mann", Age: 80}, Cast: &types.Cast{Actor: "Al Pacino", Actress: "Diane Venora"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resolver, err := clientip.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	limiter := ratelimit.New(cfg, ratelimit.NewMemoryStore(), resolver)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/movies", limiter.Limit("read", func(w http.ResponseWriter, r *http.Request) {}))
+
+	compression, corsPolicy := &swapHandler{}, &swapHandler{}
+	compression.Store(mux)
+	corsPolicy.Store(compression)
+
+	backend := &ttlBackend{LRU: cache.NewLRU(10)}
+	rl := &reloader{current: cfg, mux: mux, compression: compression, cors: corsPolicy, limiter: limiter,
+		cached: cache.New(cfg, store, backend)}
+	return &testReloader{reloader: rl, backend: backend, movieID: id, errors: errs}
+}
+
+// next copies the current configuration with change applied, leaving the current one untouched
+func (rl *testReloader) next(change func(*config.Config)) *config.Config {
+	next := *rl.current
+	change(&next)
+	return &next
+}
+
+// serve sends a cross-origin GET through the reloadable handlers
+func (rl *testReloader) serve() http.Header {
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/movies", nil)
+	r.Header.Set("Origin", "https://admin.example.com")
+	rec := httptest.NewRecorder()
+	rl.cors.ServeHTTP(rec, r)
+	return rec.Header()
+}
+
+// cachedTTL stores the movie afresh and reports the TTL it was cached for
+func (rl *testReloader) cachedTTL(t *testing.T) time.Duration {
+	t.Helper()
+	rl.backend.LRU = cache.NewLRU(10)
+	if _, err := rl.cached.GetMovieByID(context.Background(), rl.movieID); err != nil {
+		t.Fatal(err)
+	}
+	return rl.backend.ttl
+}
+
+// unchanged fails the test if anything reloadable moved away from the defaults
+func (rl *testReloader) unchanged(t *testing.T, current *config.Config) {
+	t.Helper()
+	if rl.current != current {
+		t.Error("the current configuration was replaced")
+	}
+	h := rl.serve()
+	if h.Get("RateLimit-Policy") != "" || h.Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("settings were applied: %v", h)
+	}
+	if ttl := rl.cachedTTL(t); ttl != current.CacheConfig.TTL {
+		t.Errorf("movies cached for %s, want %s", ttl, current.CacheConfig.TTL)
+	}
+}
+
+// reloadable changes a setting from each component that apply reloads
+func reloadable(c *config.Config) {
+	c.RateLimitConfig.Enabled = true
+	c.RateLimitConfig.Groups = config.Map[config.RateLimitRule]{"read": {Requests: 5, Per: time.Minute, Burst: 2}}
+	c.CacheConfig.TTL = time.Minute
+	c.HTTPConfig.CORS.AllowedOrigins = []string{"https://admin.example.com"}
+}
+
+func TestReloadAppliesReloadableSettings(t *testing.T) {
+	rl := newTestReloader(t)
+	rl.unchanged(t, rl.current)
+
+	next := rl.next(reloadable)
+	rl.apply(next, nil)
+
+	if rl.current != next {
+		t.Fatal("the reloaded configuration did not become current")
+	}
+	h := rl.serve()
+	if h.Get("RateLimit-Policy") != "5;w=60;burst=2" {
+		t.Errorf("RateLimit-Policy %q, want the reloaded rule", h.Get("RateLimit-Policy"))
+	}
+	if h.Get("Access-Control-Allow-Origin") != "https://admin.example.com" {
+		t.Errorf("Access-Control-Allow-Origin %q, want the reloaded origin", h.Get("Access-Control-Allow-Origin"))
+	}
+	if ttl := rl.cachedTTL(t); ttl != time.Minute {
+		t.Errorf("movies cached for %s, want the reloaded 1m", ttl)
+	}
+	if rl.errors.Len() != 0 {
+		t.Errorf("reload logged errors: %s", rl.errors)
+	}
+}
+
+func TestReloadRejects(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		next func(rl *testReloader) (*config.Config, error)
+		log  string
+	}{
+		{"port change", func(rl *testReloader) (*config.Config, error) {
+			return rl.next(func(c *config.Config) {
+				reloadable(c)
+				c.HTTPConfig.Port = 9000
+			}), nil
+		}, "Config reload rejected: http.port cannot change without a restart"},
+		{"invalid CORS", func(rl *testReloader) (*config.Config, error) {
+			return rl.next(func(c *config.Config) {
+				reloadable(c)
+				c.HTTPConfig.CORS.AllowedOrigins = []string{"*"}
+				c.HTTPConfig.CORS.AllowCredentials = true
+			}), nil
+		}, "Config reload rejected, invalid CORS settings"},
+		{"invalid file", func(rl *testReloader) (*config.Config, error) {
+			return nil, errors.New("http.port: 70000 is outside 1-65535")
+		}, "Config reload failed, keeping the current settings"},
+	} {
+		rl := newTestReloader(t)
+		current := rl.current
+		rl.apply(tc.next(rl))
+
+		//? Nothing is applied, not even the settings that could have been
+		rl.unchanged(t, current)
+		if !strings.Contains(rl.errors.String(), tc.log) {
+			t.Errorf("%s: logged %q, want %q", tc.name, rl.errors, tc.log)
+		}
+	}
+}
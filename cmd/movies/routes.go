@@ -0,0 +1,265 @@
+package main
+
+import (
+	"fmt"
+	"github/MahfujulSagor/movies_crud/internals/auth"
+	"github/MahfujulSagor/movies_crud/internals/cache"
+	"github/MahfujulSagor/movies_crud/internals/config"
+	"github/MahfujulSagor/movies_crud/internals/db"
+	"github/MahfujulSagor/movies_crud/internals/events"
+	"github/MahfujulSagor/movies_crud/internals/http/compress"
+	"github/MahfujulSagor/movies_crud/internals/http/handlers/apikeys"
+	eventshandler "github/MahfujulSagor/movies_crud/internals/http/handlers/events"
+	"github/MahfujulSagor/movies_crud/internals/http/handlers/graphql"
+	"github/MahfujulSagor/movies_crud/internals/http/handlers/health"
+	"github/MahfujulSagor/movies_crud/internals/http/handlers/movies"
+	"github/MahfujulSagor/movies_crud/internals/http/handlers/users"
+	webhookshandler "github/MahfujulSagor/movies_crud/internals/http/handlers/webhooks"
+	"github/MahfujulSagor/movies_crud/internals/http/httpcache"
+	"github/MahfujulSagor/movies_crud/internals/http/negotiate"
+	"github/MahfujulSagor/movies_crud/internals/idempotency"
+	"github/MahfujulSagor/movies_crud/internals/openapi"
+	"github/MahfujulSagor/movies_crud/internals/ratelimit"
+	"github/MahfujulSagor/movies_crud/internals/types"
+	"github/MahfujulSagor/movies_crud/internals/utils/response"
+	"net/http"
+)
+
+// revalidate lets clients keep movie reads but check them with If-Modified-Since before each use
+const revalidate = "private, no-cache"
+
+// apiInfo is the document header; bump Version when the public contract changes
+var apiInfo = openapi.Info{Title: "Movies API", Version: "1.0.0"}
+
+type routeStore interface {
+	db.DB
+	db.MovieFinder
+	db.APIKeyStore
+	db.UserStore
+	db.WebhookStore
+	db.IdempotencyStore
+	db.HealthChecker
+}
+
+// appStore serves movie reads and writes through the cache and everything else straight from storage
+type appStore struct {
+	*cache.Store
+	db.MovieFinder
+	db.APIKeyStore
+	db.UserStore
+	db.WebhookStore
+	db.IdempotencyStore
+	db.HealthChecker
+}
+
+// routes is the single source of truth for both the mux and the OpenAPI document; cached is nil when caching is off
+func routes(cfg *config.Config, store routeStore, cached *cache.Store, bus *events.Bus, state *health.State) ([]openapi.Route, error) {
+	schema, err := graphql.NewSchema(store)
+	if err != nil {
+		return nil, err
+	}
+
+	page := []openapi.Param{
+		{Name: "limit", Type: "integer", Description: "Page size, default 10, capped at 50"},
+		{Name: "offset", Type: "integer", Description: "Number of movies to skip"},
+	}
+
+	feed := []openapi.Param{
+		{Name: "movie_id", Type: "integer", Description: "Only events for this movie"},
+		{Name: "director_id", Type: "integer", Description: "Only events for this director's movies"},
+		{Name: "last_event_id", Type: "integer", Description: "Resume after this event; SSE clients can send Last-Event-ID instead"},
+	}
+
+	//? Movie routes answer in any format the client asks for; CSV only suits lists
+	one, many, bodies := negotiate.Formats(false), negotiate.Formats(true), negotiate.Decodable()
+
+	apiRoutes := []openapi.Route{
+		//? Health
+		{Method: http.MethodGet, Pattern: "/healthz", Tag: "health", Summary: "Liveness probe",
+			Response: health.Report{}, Handler: health.Liveness()},
+		{Method: http.MethodGet, Pattern: "/readyz", Tag: "health", Summary: "Readiness probe",
+			Response: health.Report{}, Handler: health.Readiness(store, state)},
+		{Method: http.MethodGet, Pattern: "/health/details", Tag: "health", Summary: "Detailed health, requires the health token",
+			Response: health.Details{}, Handler: health.Detailed(store, state, cfg.HealthConfig.Token, cached)},
+
+		//? Movies
+		{Method: http.MethodPost, Pattern: "/api/v1/movies", Tag: "movies", Summary: "Create a movie",
+			Scope: auth.ScopeMoviesWrite, RateGroup: ratelimit.GroupWrite, Idempotent: true, Produces: one, Consumes: bodies,
+			Request: types.Movie{}, Response: response.Message{}, Status: http.StatusCreated, Handler: movies.New(store)},
+		{Method: http.MethodGet, Pattern: "/api/v1/movies/{id}", Tag: "movies", Summary: "Get a movie",
+			Scope: auth.ScopeMoviesRead, RateGroup: ratelimit.GroupRead, CacheControl: revalidate, Conditional: true, Produces: one,
+			Response: types.Movie{}, Handler: movies.GetByID(store)},
+		{Method: http.MethodGet, Pattern: "/api/v1/movies", Tag: "movies", Summary: "List movies",
+			Scope: auth.ScopeMoviesRead, RateGroup: ratelimit.GroupRead, Query: page, CacheControl: revalidate, Conditional: true, Produces: many,
+			Response: []types.Movie{}, Handler: movies.GetList(store)},
+		{Method: http.MethodPut, Pattern: "/api/v1/movies/{id}", Tag: "movies", Summary: "Replace a movie",
+			Scope: auth.ScopeMoviesWrite, RateGroup: ratelimit.GroupWrite, Produces: one, Consumes: bodies,
+			Request: types.Movie{}, Response: response.Message{}, Handler: movies.Update(store)},
+		{Method: http.MethodDelete, Pattern: "/api/v1/movies/{id}", Tag: "movies", Summary: "Delete a movie",
+			Scope: auth.ScopeMoviesDelete, RateGroup: ratelimit.GroupWrite, Produces: one,
+			Response: response.Message{}, Handler: movies.DeleteByID(store)},
+
+		//? Admin
+		{Method: http.MethodPost, Pattern: "/api/v1/admin/api-keys", Tag: "admin", Summary: "Create an API key",
+			Scope: auth.ScopeKeysAdmin, RateGroup: ratelimit.GroupAdmin,
+			Request: types.APIKey{}, Response: apikeys.CreatedKey{}, Status: http.StatusCreated, Handler: apikeys.New(store)},
+		{Method: http.MethodGet, Pattern: "/api/v1/admin/api-keys", Tag: "admin", Summary: "List API keys",
+			Scope: auth.ScopeKeysAdmin, RateGroup: ratelimit.GroupAdmin,
+			Response: []types.APIKey{}, Handler: apikeys.List(store)},
+		{Method: http.MethodDelete, Pattern: "/api/v1/admin/api-keys/{id}", Tag: "admin", Summary: "Revoke an API key",
+			Scope: auth.ScopeKeysAdmin, RateGroup: ratelimit.GroupAdmin,
+			Response: response.Message{}, Handler: apikeys.Revoke(store)},
+		{Method: http.MethodPost, Pattern: "/api/v1/admin/users", Tag: "admin", Summary: "Create a user",
+			Scope: auth.ScopeUsersAdmin, RateGroup: ratelimit.GroupAdmin,
+			Request: users.NewUser{}, Response: types.User{}, Status: http.StatusCreated, Handler: users.New(store)},
+		{Method: http.MethodGet, Pattern: "/api/v1/admin/users", Tag: "admin", Summary: "List users",
+			Scope: auth.ScopeUsersAdmin, RateGroup: ratelimit.GroupAdmin,
+			Response: []types.User{}, Handler: users.List(store)},
+
+		{Method: http.MethodPost, Pattern: "/api/v1/admin/webhooks", Tag: "webhooks", Summary: "Subscribe a URL to catalogue events",
+			Scope: auth.ScopeWebhooksAdmin, RateGroup: ratelimit.GroupAdmin,
+			Request: types.Webhook{}, Response: types.Webhook{}, Status: http.StatusCreated, Handler: webhookshandler.New(store, cfg.WebhookConfig.AllowPrivateNetworks)},
+		{Method: http.MethodGet, Pattern: "/api/v1/admin/webhooks", Tag: "webhooks", Summary: "List webhooks",
+			Scope: auth.ScopeWebhooksAdmin, RateGroup: ratelimit.GroupAdmin,
+			Response: []types.Webhook{}, Handler: webhookshandler.List(store)},
+		{Method: http.MethodDelete, Pattern: "/api/v1/admin/webhooks/{id}", Tag: "webhooks", Summary: "Delete a webhook and its delivery history",
+			Scope: auth.ScopeWebhooksAdmin, RateGroup: ratelimit.GroupAdmin,
+			Response: response.Message{}, Handler: webhookshandler.Delete(store)},
+		{Method: http.MethodGet, Pattern: "/api/v1/admin/webhooks/{id}/deliveries", Tag: "webhooks", Summary: "List a webhook's deliveries, newest first",
+			Scope: auth.ScopeWebhooksAdmin, RateGroup: ratelimit.GroupAdmin, Query: []openapi.Param{
+				{Name: "limit", Type: "integer", Description: "Page size, default 20, capped at 100"},
+				{Name: "offset", Type: "integer", Description: "Number of deliveries to skip"},
+			},
+			Response: []types.WebhookDelivery{}, Handler: webhookshandler.Deliveries(store)},
+		{Method: http.MethodPost, Pattern: "/api/v1/admin/webhook-deliveries/{id}/redeliver", Tag: "webhooks", Summary: "Queue a delivery to be sent again",
+			Scope: auth.ScopeWebhooksAdmin, RateGroup: ratelimit.GroupAdmin,
+			Response: types.WebhookDelivery{}, Status: http.StatusAccepted, Handler: webhookshandler.Redeliver(store)},
+
+		//? Sessions
+		{Method: http.MethodPost, Pattern: "/api/v1/auth/login", Tag: "auth", Summary: "Exchange credentials for a session token",
+			RateGroup: ratelimit.GroupAuth,
+			Request:   users.Credentials{}, Response: users.Session{}, Handler: users.Login(store, cfg.SessionConfig.TTL)},
+		{Method: http.MethodPost, Pattern: "/api/v1/auth/logout", Tag: "auth", Summary: "Revoke the current session token",
+			RateGroup: ratelimit.GroupAuth,
+			Response:  response.Message{}, Handler: users.Logout(store)},
+
+		//? Change feed
+		{Method: http.MethodGet, Pattern: "/api/v1/events", Tag: "events", Summary: "Stream movie changes as Server-Sent Events",
+			Scope: auth.ScopeMoviesRead, RateGroup: ratelimit.GroupRead, Query: feed, QueryToken: true,
+			Response: events.Event{}, MediaType: "text/event-stream", Handler: eventshandler.Stream(bus)},
+		{Method: http.MethodGet, Pattern: "/api/v1/events/ws", Tag: "events", Summary: "Stream movie changes over a WebSocket",
+			Scope: auth.ScopeMoviesRead, RateGroup: ratelimit.GroupRead, Query: feed, QueryToken: true,
+			Status: http.StatusSwitchingProtocols, Handler: eventshandler.WebSocket(bus)},
+
+		//? GraphQL; mutations check their own scopes
+		{Method: http.MethodPost, Pattern: "/graphql", Tag: "graphql", Summary: "Execute a GraphQL query or mutation",
+			Scope: auth.ScopeMoviesRead, RateGroup: ratelimit.GroupRead,
+			Request: graphql.Request{}, Response: graphql.Result{}, Handler: graphql.Handler(schema, store)},
+	}
+
+	if cfg.Env == "development" {
+		apiRoutes = append(apiRoutes, openapi.Route{Method: http.MethodGet, Pattern: "/graphql", Hidden: true, Handler: graphql.Playground()})
+	}
+
+	if err := applyCacheControl(apiRoutes, cfg.HTTPConfig.CacheControl); err != nil {
+		return nil, err
+	}
+	if err := applyBodyLimits(apiRoutes, cfg.HTTPConfig.MaxBodySize, cfg.HTTPConfig.BodyLimits); err != nil {
+		return nil, err
+	}
+
+	return apiRoutes, nil
+}
+
+// applyCacheControl replaces route policies with the configured ones
+func applyCacheControl(routes []openapi.Route, policies map[string]string) error {
+	for key, policy := range policies {
+		found := false
+		for i := range routes {
+			if routes[i].Method+" "+routes[i].Pattern == key {
+				routes[i].CacheControl = policy
+				found = true
+			}
+		}
+		if !found {
+			return fmt.Errorf("http.cache_control: no route %q", key)
+		}
+	}
+	return nil
+}
+
+// applyBodyLimits gives every route the default body limit, then the configured per-route ones
+func applyBodyLimits(routes []openapi.Route, limit int64, limits map[string]int64) error {
+	for i := range routes {
+		if routes[i].MaxBody == 0 {
+			routes[i].MaxBody = limit
+		}
+	}
+
+	for key, limit := range limits {
+		found := false
+		for i := range routes {
+			if routes[i].Method+" "+routes[i].Pattern == key {
+				routes[i].MaxBody = limit
+				found = true
+			}
+		}
+		if !found {
+			return fmt.Errorf("http.body_limits: no route %q", key)
+		}
+	}
+	return nil
+}
+
+// document generates the OpenAPI description of routes
+func document(routes []openapi.Route) *openapi.Document {
+	return openapi.Generate(apiInfo, routes, response.Problem{})
+}
+
+// mount registers routes on mux behind authentication, rate limiting, body limits, idempotency keys
+// and content negotiation, plus the docs endpoints
+func mount(mux *http.ServeMux, routes []openapi.Route, authn *auth.Authenticator, limiter *ratelimit.Limiter, guard *idempotency.Guard) {
+	router := openapi.NewRouter(mux, func(rt openapi.Route, next http.HandlerFunc) http.HandlerFunc {
+		if rt.Idempotent {
+			next = guard.Protect(next)
+		}
+		//? Outside the guard, so a write the client cannot read the answer to is refused before it runs
+		if len(rt.Produces) > 0 {
+			next = response.Negotiate(rt.Produces, next)
+		}
+		//? Decoded and capped before the idempotency guard and handlers read it
+		if rt.MaxBody > 0 {
+			next = compress.Body(rt.MaxBody, next)
+		}
+		next = httpcache.Headers(rt.CacheControl, vary(rt), next)
+		if rt.RateGroup != "" {
+			next = limiter.Limit(rt.RateGroup, next)
+		}
+		if rt.Scope != "" {
+			next = authn.Require(rt.Scope, next)
+			//? Outside authentication, so floods of bad keys are throttled before each costs a lookup
+			next = limiter.LimitIP(ratelimit.GroupIP, next)
+		}
+		if rt.QueryToken {
+			next = auth.QueryToken(next)
+		}
+		return next
+	})
+
+	for _, rt := range routes {
+		router.Handle(rt)
+	}
+
+	router.Handle(openapi.Route{Method: http.MethodGet, Pattern: "/openapi.json", Hidden: true, CacheControl: "public, max-age=300", Handler: openapi.Spec(document(router.Routes()))})
+	router.Handle(openapi.Route{Method: http.MethodGet, Pattern: "/docs", Hidden: true, CacheControl: "public, max-age=300", Handler: openapi.UI()})
+	router.Handle(openapi.Route{Method: http.MethodGet, Pattern: "/docs/{file}", Hidden: true, CacheControl: "public, max-age=300", Handler: openapi.Assets()})
+}
+
+// vary lists the request headers a route's response depends on, so shared caches key on them
+func vary(rt openapi.Route) []string {
+	headers := []string{"Accept"}
+	if rt.Scope != "" {
+		headers = append(headers, "Authorization", "X-API-Key")
+	}
+	return headers
+}
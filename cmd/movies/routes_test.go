@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+// TestUpdateTakesTheIDFromThePath pins PUT to /api/v1/movies/{id}. The original PUT /api/v1/movies
+// could never succeed, since the handler has always read the ID from the path.
+func TestUpdateTakesTheIDFromThePath(t *testing.T) {
+	server, _, key := testServer(t, nil)
+	c := testClient(t, server, key)
+
+	id, err := c.Movies.Create(context.Background(), clientMovie("Heat"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := json.Marshal(clientMovie("Heat (Director's Cut)"))
+
+	put := func(path string) int {
+		req, _ := http.NewRequest(http.MethodPut, server.URL+path, bytes.NewReader(body))
+		req.Header.Set("X-API-Key", key)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if status := put(fmt.Sprintf("/api/v1/movies/%d", id)); status != http.StatusOK {
+		t.Fatalf("PUT /api/v1/movies/%d returned %d, want 200", id, status)
+	}
+	if status := put("/api/v1/movies"); status != http.StatusMethodNotAllowed {
+		t.Fatalf("PUT /api/v1/movies returned %d, want 405", status)
+	}
+}
@@ -0,0 +1,178 @@
+package apperr
+
+import (
+	"errors"
+	"fmt"
+	"github/MahfujulSagor/movies_crud/internals/db"
+	"net/http"
+)
+
+// Kind classifies an error and determines its HTTP status
+type Kind string
+
+const (
+	KindBadRequest           Kind = "bad_request"
+	KindValidation           Kind = "validation"
+	KindUnauthorized         Kind = "unauthorized"
+	KindForbidden            Kind = "forbidden"
+	KindNotFound             Kind = "not_found"
+	KindConflict             Kind = "conflict"
+	KindUnprocessable        Kind = "unprocessable"
+	KindNotAcceptable        Kind = "not_acceptable"
+	KindUnsupportedMediaType Kind = "unsupported_media_type"
+	KindTooLarge             Kind = "too_large"
+	KindRateLimited          Kind = "rate_limited"
+	KindUnavailable          Kind = "unavailable"
+	KindInternal             Kind = "internal"
+)
+
+// Stable machine-readable codes. Clients may switch on these, so never rename one.
+const (
+	CodeBadRequest            string = "bad_request"
+	CodeEmptyBody             string = "empty_body"
+	CodeMalformedBody         string = "malformed_body"
+	CodeInvalidID             string = "invalid_id"
+	CodeInvalidQuery          string = "invalid_query"
+	CodeQueryTooComplex       string = "query_too_complex"
+	CodeValidationFailed      string = "validation_failed"
+	CodeUnauthorized          string = "unauthorized"
+	CodeInvalidCredentials    string = "invalid_credentials"
+	CodeInvalidToken          string = "invalid_token"
+	CodeForbidden             string = "forbidden"
+	CodeMovieNotFound         string = "movie_not_found"
+	CodeAPIKeyNotFound        string = "api_key_not_found"
+	CodeNotFound              string = "not_found"
+	CodeConflict              string = "conflict"
+	CodeConstraint            string = "constraint_violation"
+	CodeUserNotFound          string = "user_not_found"
+	CodeWebhookNotFound       string = "webhook_not_found"
+	CodeDeliveryNotFound      string = "delivery_not_found"
+	CodeInvalidIdempotencyKey string = "invalid_idempotency_key"
+	CodeIdempotencyKeyReused  string = "idempotency_key_reused"
+	CodeIdempotencyInProgress string = "idempotency_in_progress"
+	CodeNotAcceptable         string = "not_acceptable"
+	CodeUnsupportedMediaType  string = "unsupported_media_type"
+	CodeUnsupportedEncoding   string = "unsupported_encoding"
+	CodeBodyTooLarge          string = "body_too_large"
+	CodeRateLimited           string = "rate_limited"
+	CodeUnavailable           string = "unavailable"
+	CodeInternal              string = "internal_error"
+)
+
+var statuses = map[Kind]int{
+	KindBadRequest:           http.StatusBadRequest,
+	KindValidation:           http.StatusBadRequest,
+	KindUnauthorized:         http.StatusUnauthorized,
+	KindForbidden:            http.StatusForbidden,
+	KindNotFound:             http.StatusNotFound,
+	KindConflict:             http.StatusConflict,
+	KindUnprocessable:        http.StatusUnprocessableEntity,
+	KindNotAcceptable:        http.StatusNotAcceptable,
+	KindUnsupportedMediaType: http.StatusUnsupportedMediaType,
+	KindTooLarge:             http.StatusRequestEntityTooLarge,
+	KindRateLimited:          http.StatusTooManyRequests,
+	KindUnavailable:          http.StatusServiceUnavailable,
+	KindInternal:             http.StatusInternalServerError,
+}
+
+// FieldError describes one invalid request field
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+type Error struct {
+	Kind   Kind
+	Code   string
+	Detail string
+	Fields []FieldError
+	// Err is the underlying cause; it is logged but never sent to clients
+	Err error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s: %v", e.Code, e.Detail, e.Err)
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Detail)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (e *Error) Status() int {
+	if status, ok := statuses[e.Kind]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+func New(kind Kind, code string, format string, args ...any) *Error {
+	return &Error{Kind: kind, Code: code, Detail: fmt.Sprintf(format, args...)}
+}
+
+func BadRequest(code string, format string, args ...any) *Error {
+	return New(KindBadRequest, code, format, args...)
+}
+
+func Unauthorized(code string, format string, args ...any) *Error {
+	return New(KindUnauthorized, code, format, args...)
+}
+
+func Forbidden(format string, args ...any) *Error {
+	return New(KindForbidden, CodeForbidden, format, args...)
+}
+
+func NotFound(code string, format string, args ...any) *Error {
+	return New(KindNotFound, code, format, args...)
+}
+
+func Conflict(code string, format string, args ...any) *Error {
+	return New(KindConflict, code, format, args...)
+}
+
+func Validation(fields []FieldError) *Error {
+	return &Error{
+		Kind:   KindValidation,
+		Code:   CodeValidationFailed,
+		Detail: "request validation failed",
+		Fields: fields,
+	}
+}
+
+// Internal hides err from the client behind a generic message
+func Internal(err error) *Error {
+	return &Error{
+		Kind:   KindInternal,
+		Code:   CodeInternal,
+		Detail: "internal server error",
+		Err:    err,
+	}
+}
+
+// FromDB maps storage sentinel errors, returning notFound for db.ErrNotFound. Clients get a fixed
+// detail; the storage error, which can carry driver text and SQL, is kept in Err for the logs.
+func FromDB(err error, notFound *Error) *Error {
+	switch {
+	case errors.Is(err, db.ErrNotFound):
+		notFound.Err = err
+		return notFound
+	case errors.Is(err, db.ErrConflict):
+		return &Error{Kind: KindConflict, Code: CodeConflict, Detail: "a record with the same unique values already exists", Err: err}
+	case errors.Is(err, db.ErrConstraint):
+		return &Error{Kind: KindConflict, Code: CodeConstraint, Detail: "the change would break a reference or constraint between records", Err: err}
+	default:
+		return Internal(err)
+	}
+}
+
+// From returns err as an *Error, treating anything unclassified as internal
+func From(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	return Internal(err)
+}
@@ -0,0 +1,32 @@
+package apperr
+
+import (
+	"errors"
+	"fmt"
+	"github/MahfujulSagor/movies_crud/internals/db"
+	"strings"
+	"testing"
+)
+
+func TestFromDBHidesStorageErrors(t *testing.T) {
+	driverText := "UNIQUE constraint failed: movies.title"
+	for _, tc := range []struct {
+		err  error
+		code string
+	}{
+		{fmt.Errorf("%w: %s", db.ErrConflict, driverText), CodeConflict},
+		{fmt.Errorf("%w: FOREIGN KEY constraint failed", db.ErrConstraint), CodeConstraint},
+		{errors.New("disk I/O error"), CodeInternal},
+	} {
+		got := FromDB(tc.err, NotFound(CodeNotFound, "not found"))
+		if got.Code != tc.code {
+			t.Errorf("FromDB(%q) has code %q, want %q", tc.err, got.Code, tc.code)
+		}
+		if strings.Contains(got.Detail, "constraint failed") || strings.Contains(got.Detail, "I/O") {
+			t.Errorf("FromDB(%q) exposes storage text in detail %q", tc.err, got.Detail)
+		}
+		if !errors.Is(got, tc.err) {
+			t.Errorf("FromDB(%q) dropped the storage error needed for logging", tc.err)
+		}
+	}
+}
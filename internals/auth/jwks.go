@@ -0,0 +1,220 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github/MahfujulSagor/movies_crud/internals/logger"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// jwk is the subset of RFC 7517 fields needed for RSA, EC P-256 and Ed25519 keys
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type verificationKey struct {
+	kid string
+	alg string
+	key crypto.PublicKey
+}
+
+// minRefresh bounds how often an unknown kid can trigger a reload of the key set
+const minRefresh = time.Minute
+
+// KeySet holds the public keys from a JWKS document loaded from a file or URL
+type KeySet struct {
+	load func() ([]byte, error)
+
+	mu       sync.RWMutex
+	keys     []verificationKey
+	loadedAt time.Time
+}
+
+func NewFileKeySet(path string) (*KeySet, error) {
+	ks := &KeySet{load: func() ([]byte, error) {
+		return os.ReadFile(path)
+	}}
+	return ks, ks.refresh()
+}
+
+func NewURLKeySet(url string) (*KeySet, error) {
+	client := &http.Client{Timeout: 5 * time.Second}
+	ks := &KeySet{load: func() ([]byte, error) {
+		resp, err := client.Get(url)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("fetching JWKS: unexpected status %s", resp.Status)
+		}
+		return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	}}
+	return ks, ks.refresh()
+}
+
+func (ks *KeySet) refresh() error {
+	data, err := ks.load()
+	if err != nil {
+		return err
+	}
+
+	var doc struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("parsing JWKS: %w", err)
+	}
+
+	var keys []verificationKey
+	for _, k := range doc.Keys {
+		//? Skip encryption keys and key types we cannot verify with
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		//? One bad or unsupported key, e.g. a new type published by the provider, must not disable the rest
+		vk, err := k.verificationKey()
+		if err != nil {
+			logger.Error.Printf("Skipping JWK %q: %v", k.Kid, err)
+			continue
+		}
+		keys = append(keys, vk)
+	}
+
+	if len(keys) == 0 {
+		return fmt.Errorf("JWKS contains no usable signing keys")
+	}
+
+	ks.mu.Lock()
+	ks.keys = keys
+	ks.loadedAt = time.Now()
+	ks.mu.Unlock()
+
+	return nil
+}
+
+// lookup finds the key for kid and alg, reloading the set once if the kid is unknown
+func (ks *KeySet) lookup(kid, alg string) (crypto.PublicKey, error) {
+	if key := ks.find(kid, alg); key != nil {
+		return key, nil
+	}
+
+	ks.mu.RLock()
+	stale := time.Since(ks.loadedAt) > minRefresh
+	ks.mu.RUnlock()
+
+	if stale {
+		if err := ks.refresh(); err != nil {
+			return nil, err
+		}
+		if key := ks.find(kid, alg); key != nil {
+			return key, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no key found for kid %q", kid)
+}
+
+func (ks *KeySet) find(kid, alg string) crypto.PublicKey {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	for _, k := range ks.keys {
+		if k.alg != alg {
+			continue
+		}
+		if kid == "" || k.kid == kid {
+			return k.key
+		}
+	}
+	return nil
+}
+
+func (k jwk) verificationKey() (verificationKey, error) {
+	vk := verificationKey{kid: k.Kid}
+
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeSegment(k.N)
+		if err != nil {
+			return vk, err
+		}
+		e, err := decodeSegment(k.E)
+		if err != nil {
+			return vk, err
+		}
+		vk.alg = AlgRS256
+		vk.key = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+
+	case "EC":
+		if k.Crv != "P-256" {
+			return vk, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeSegment(k.X)
+		if err != nil {
+			return vk, err
+		}
+		y, err := decodeSegment(k.Y)
+		if err != nil {
+			return vk, err
+		}
+		point := append([]byte{4}, append(x, y...)...)
+		pub, err := ecdsa.ParseUncompressedPublicKey(elliptic.P256(), point)
+		if err != nil {
+			return vk, err
+		}
+		vk.alg = AlgES256
+		vk.key = pub
+
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return vk, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeSegment(k.X)
+		if err != nil {
+			return vk, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return vk, fmt.Errorf("invalid Ed25519 key size %d", len(x))
+		}
+		vk.alg = AlgEdDSA
+		vk.key = ed25519.PublicKey(x)
+
+	default:
+		return vk, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+
+	//? An explicit alg on the key must agree with the key type
+	if k.Alg != "" && k.Alg != vk.alg {
+		return vk, fmt.Errorf("key alg %q does not match key type %q", k.Alg, k.Kty)
+	}
+
+	return vk, nil
+}
+
+func decodeSegment(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(s)
+}
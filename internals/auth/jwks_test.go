@@ -0,0 +1,227 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"github/MahfujulSagor/movies_crud/internals/logger"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func init() {
+	logger.Info = log.New(io.Discard, "", 0)
+	logger.Error = log.New(io.Discard, "", 0)
+}
+
+// testKey is a locally generated signing key with its public half as a JWK
+type testKey struct {
+	kid    string
+	alg    string
+	signer crypto.Signer
+	jwk    map[string]string
+}
+
+func newRSAKey(t *testing.T, kid string) testKey {
+	t.Helper()
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testKey{kid: kid, alg: AlgRS256, signer: priv, jwk: map[string]string{
+		"kty": "RSA", "kid": kid, "use": "sig", "alg": AlgRS256,
+		"n": encodeSegment(priv.N.Bytes()),
+		"e": encodeSegment(big.NewInt(int64(priv.E)).Bytes()),
+	}}
+}
+
+func newECKey(t *testing.T, kid string) testKey {
+	t.Helper()
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	point, err := priv.PublicKey.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testKey{kid: kid, alg: AlgES256, signer: priv, jwk: map[string]string{
+		"kty": "EC", "kid": kid, "crv": "P-256",
+		"x": encodeSegment(point[1:33]),
+		"y": encodeSegment(point[33:]),
+	}}
+}
+
+func newEdKey(t *testing.T, kid string) testKey {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testKey{kid: kid, alg: AlgEdDSA, signer: priv, jwk: map[string]string{
+		"kty": "OKP", "kid": kid, "crv": "Ed25519",
+		"x": encodeSegment(pub),
+	}}
+}
+
+// sign issues a token for sub that expires in an hour
+func (k testKey) sign(t *testing.T, sub string) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": k.alg, "kid": k.kid, "typ": "JWT"})
+	payload, _ := json.Marshal(map[string]any{"sub": sub, "exp": time.Now().Add(time.Hour).Unix()})
+	input := encodeSegment(header) + "." + encodeSegment(payload)
+
+	var sig []byte
+	switch priv := k.signer.(type) {
+	case *rsa.PrivateKey:
+		sum := sha256.Sum256([]byte(input))
+		s, err := rsa.SignPKCS1v15(rand.Reader, priv, crypto.SHA256, sum[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig = s
+	case *ecdsa.PrivateKey:
+		sum := sha256.Sum256([]byte(input))
+		r, s, err := ecdsa.Sign(rand.Reader, priv, sum[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	case ed25519.PrivateKey:
+		sig = ed25519.Sign(priv, []byte(input))
+	}
+	return input + "." + encodeSegment(sig)
+}
+
+func encodeSegment(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func jwksDocument(t *testing.T, keys ...map[string]string) []byte {
+	t.Helper()
+	data, err := json.Marshal(map[string]any{"keys": keys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func writeJWKS(t *testing.T, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// badKeys are entries a provider might publish that this verifier cannot use
+var badKeys = []map[string]string{
+	{"kty": "RSA", "kid": "bad-modulus", "n": "not base64!", "e": "AQAB"},
+	{"kty": "EC", "kid": "p384", "crv": "P-384", "x": "AA", "y": "AA"},
+	{"kty": "EC", "kid": "off-curve", "crv": "P-256", "x": encodeSegment(make([]byte, 32)), "y": encodeSegment(make([]byte, 32))},
+	{"kty": "OKP", "kid": "short-ed", "crv": "Ed25519", "x": "AAAA"},
+	{"kty": "oct", "kid": "hmac", "k": "c2VjcmV0"},
+	{"kty": "RSA", "kid": "wrong-alg", "alg": "PS256", "n": "AQAB", "e": "AQAB"},
+}
+
+func TestKeySetSkipsUnusableKeys(t *testing.T) {
+	good := []testKey{newRSAKey(t, "rsa"), newECKey(t, "ec"), newEdKey(t, "ed")}
+
+	jwks := append([]map[string]string{}, badKeys...)
+	for _, k := range good {
+		jwks = append(jwks, k.jwk)
+	}
+
+	keys, err := NewFileKeySet(writeJWKS(t, jwksDocument(t, jwks...)))
+	if err != nil {
+		t.Fatal("key set with some unusable keys was rejected:", err)
+	}
+	if len(keys.keys) != len(good) {
+		t.Fatalf("loaded %d keys, want the %d usable ones", len(keys.keys), len(good))
+	}
+
+	v := &JWTVerifier{Keys: keys}
+	for _, k := range good {
+		claims, err := v.Verify(k.sign(t, "user-"+k.kid))
+		if err != nil {
+			t.Errorf("%s token: %v", k.alg, err)
+			continue
+		}
+		if claims.Subject != "user-"+k.kid {
+			t.Errorf("%s token has subject %q", k.alg, claims.Subject)
+		}
+	}
+}
+
+func TestKeySetRejectsSetWithoutUsableKeys(t *testing.T) {
+	if _, err := NewFileKeySet(writeJWKS(t, jwksDocument(t, badKeys...))); err == nil {
+		t.Fatal("key set with only unusable keys was accepted")
+	}
+}
+
+func TestVerifyRejectsUnknownAndMismatchedKeys(t *testing.T) {
+	known, other := newEdKey(t, "known"), newEdKey(t, "other")
+	keys, err := NewFileKeySet(writeJWKS(t, jwksDocument(t, known.jwk)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := &JWTVerifier{Keys: keys}
+
+	if _, err := v.Verify(other.sign(t, "someone")); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("token signed with an unknown kid returned %v, want ErrInvalidToken", err)
+	}
+
+	//? Signed by another key but claiming the known kid
+	other.kid = known.kid
+	if _, err := v.Verify(other.sign(t, "someone")); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("token with a forged kid returned %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestURLKeySetPicksUpRotatedKeys(t *testing.T) {
+	old, rotated := newECKey(t, "2025"), newRSAKey(t, "2026")
+
+	var published atomic.Pointer[[]byte]
+	first := jwksDocument(t, old.jwk)
+	published.Store(&first)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(*published.Load())
+	}))
+	defer server.Close()
+
+	keys, err := NewURLKeySet(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := &JWTVerifier{Keys: keys}
+
+	//? The provider rotates and also starts publishing a key type we cannot use
+	next := jwksDocument(t, badKeys[4], rotated.jwk)
+	published.Store(&next)
+	keys.mu.Lock()
+	keys.loadedAt = time.Now().Add(-2 * minRefresh)
+	keys.mu.Unlock()
+
+	if _, err := v.Verify(rotated.sign(t, "rotated")); err != nil {
+		t.Fatal("token signed with the rotated key:", err)
+	}
+	if _, err := v.Verify(old.sign(t, "old")); err == nil {
+		t.Fatal("token signed with the retired key was accepted")
+	}
+}
@@ -0,0 +1,269 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	AlgRS256 string = "RS256"
+	AlgES256 string = "ES256"
+	AlgEdDSA string = "EdDSA"
+)
+
+var ErrInvalidToken = errors.New("invalid token")
+
+// Claims are the registered claims plus the raw payload for role extraction
+type Claims struct {
+	Issuer    string
+	Subject   string
+	Audience  []string
+	ExpiresAt time.Time
+	NotBefore time.Time
+	Raw       map[string]any
+}
+
+type JWTVerifier struct {
+	Keys      *KeySet
+	Issuer    string
+	Audience  []string
+	ClockSkew time.Duration
+	// RolesClaim is a dot-separated path to the roles array, e.g. "realm_access.roles"
+	RolesClaim string
+	// RoleScopes maps a role from the token to the scopes it grants
+	RoleScopes map[string][]string
+}
+
+func (v *JWTVerifier) Verify(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed token", ErrInvalidToken)
+	}
+
+	//? Header
+	rawHeader, err := decodeSegment(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("%w: bad header encoding", ErrInvalidToken)
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := json.Unmarshal(rawHeader, &header); err != nil {
+		return nil, fmt.Errorf("%w: bad header", ErrInvalidToken)
+	}
+
+	//? Only asymmetric algorithms are accepted, which also rules out "none"
+	if !slices.Contains([]string{AlgRS256, AlgES256, AlgEdDSA}, header.Alg) {
+		return nil, fmt.Errorf("%w: unsupported alg %q", ErrInvalidToken, header.Alg)
+	}
+
+	key, err := v.Keys.lookup(header.Kid, header.Alg)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	//? Signature
+	sig, err := decodeSegment(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: bad signature encoding", ErrInvalidToken)
+	}
+	signingInput := []byte(parts[0] + "." + parts[1])
+	if !verifySignature(header.Alg, key, signingInput, sig) {
+		return nil, fmt.Errorf("%w: signature verification failed", ErrInvalidToken)
+	}
+
+	//? Claims
+	rawPayload, err := decodeSegment(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("%w: bad payload encoding", ErrInvalidToken)
+	}
+	claims, err := parseClaims(rawPayload)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	if err := v.validateClaims(claims); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	return claims, nil
+}
+
+func (v *JWTVerifier) validateClaims(c *Claims) error {
+	now := time.Now()
+
+	if v.Issuer != "" && c.Issuer != v.Issuer {
+		return fmt.Errorf("unexpected issuer %q", c.Issuer)
+	}
+
+	if len(v.Audience) > 0 && !slices.ContainsFunc(c.Audience, func(a string) bool {
+		return slices.Contains(v.Audience, a)
+	}) {
+		return fmt.Errorf("token audience %v not accepted", c.Audience)
+	}
+
+	if c.ExpiresAt.IsZero() {
+		return fmt.Errorf("missing exp claim")
+	}
+	if now.After(c.ExpiresAt.Add(v.ClockSkew)) {
+		return fmt.Errorf("token expired")
+	}
+	if !c.NotBefore.IsZero() && now.Before(c.NotBefore.Add(-v.ClockSkew)) {
+		return fmt.Errorf("token not yet valid")
+	}
+
+	return nil
+}
+
+// Identity maps verified claims to the caller identity, granting scopes via roles and the scope claim
+func (v *JWTVerifier) Identity(c *Claims) *Identity {
+	id := &Identity{
+		Subject: "jwt:" + c.Subject,
+		Name:    c.Subject,
+		Method:  "jwt",
+	}
+	if name, ok := c.Raw["preferred_username"].(string); ok && name != "" {
+		id.Name = name
+	}
+
+	id.Roles = stringSlice(lookupClaim(c.Raw, v.RolesClaim))
+
+	scopes := ScopesForRoles(v.RoleScopes, id.Roles...)
+	if scope, ok := c.Raw["scope"].(string); ok {
+		for _, s := range strings.Fields(scope) {
+			if slices.Contains(Scopes, s) {
+				scopes = append(scopes, s)
+			}
+		}
+	}
+	slices.Sort(scopes)
+	id.Scopes = slices.Compact(scopes)
+
+	return id
+}
+
+func verifySignature(alg string, key crypto.PublicKey, input, sig []byte) bool {
+	switch alg {
+	case AlgRS256:
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return false
+		}
+		sum := sha256.Sum256(input)
+		return rsa.VerifyPKCS1v15(pub, crypto.SHA256, sum[:], sig) == nil
+
+	case AlgES256:
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok || len(sig) != 64 {
+			return false
+		}
+		//? JWS encodes ECDSA signatures as fixed-width r||s rather than ASN.1
+		sum := sha256.Sum256(input)
+		r := new(big.Int).SetBytes(sig[:32])
+		s := new(big.Int).SetBytes(sig[32:])
+		return ecdsa.Verify(pub, sum[:], r, s)
+
+	case AlgEdDSA:
+		pub, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return false
+		}
+		return ed25519.Verify(pub, input, sig)
+	}
+
+	return false
+}
+
+func parseClaims(payload []byte) (*Claims, error) {
+	var raw map[string]any
+	dec := json.NewDecoder(strings.NewReader(string(payload)))
+	dec.UseNumber()
+	if err := dec.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("bad payload")
+	}
+
+	c := &Claims{Raw: raw}
+	c.Issuer, _ = raw["iss"].(string)
+	c.Subject, _ = raw["sub"].(string)
+
+	switch aud := raw["aud"].(type) {
+	case string:
+		c.Audience = []string{aud}
+	case []any:
+		c.Audience = stringSlice(aud)
+	}
+
+	var err error
+	if c.ExpiresAt, err = numericDate(raw["exp"]); err != nil {
+		return nil, fmt.Errorf("invalid exp claim")
+	}
+	if c.NotBefore, err = numericDate(raw["nbf"]); err != nil {
+		return nil, fmt.Errorf("invalid nbf claim")
+	}
+
+	if c.Subject == "" {
+		return nil, fmt.Errorf("missing sub claim")
+	}
+
+	return c, nil
+}
+
+func numericDate(v any) (time.Time, error) {
+	if v == nil {
+		return time.Time{}, nil
+	}
+	n, ok := v.(json.Number)
+	if !ok {
+		return time.Time{}, fmt.Errorf("not a number")
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(int64(f), 0), nil
+}
+
+func lookupClaim(raw map[string]any, path string) any {
+	if path == "" {
+		return nil
+	}
+
+	var cur any = raw
+	for _, part := range strings.Split(path, ".") {
+		m, ok := cur.(map[string]any)
+		if !ok {
+			return nil
+		}
+		cur = m[part]
+	}
+	return cur
+}
+
+func stringSlice(v any) []string {
+	items, ok := v.([]any)
+	if !ok {
+		if s, ok := v.(string); ok {
+			return strings.Fields(s)
+		}
+		return nil
+	}
+
+	var out []string
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -0,0 +1,218 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"github/MahfujulSagor/movies_crud/internals/apperr"
+	"github/MahfujulSagor/movies_crud/internals/config"
+	"github/MahfujulSagor/movies_crud/internals/db"
+	"github/MahfujulSagor/movies_crud/internals/logger"
+	"github/MahfujulSagor/movies_crud/internals/utils/response"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+var errMissingCredentials = errors.New("missing credentials")
+
+// Authenticator resolves API keys, user sessions and, when configured, JWT bearer tokens to an Identity
+type Authenticator struct {
+	Keys  db.APIKeyStore
+	Users db.UserStore
+	JWT   *JWTVerifier
+}
+
+// Require authenticates the request and checks the caller was granted scope
+func (a *Authenticator) Require(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		identity, err := a.authenticate(r)
+		if err != nil {
+			if errors.Is(err, errMissingCredentials) || errors.Is(err, ErrInvalidToken) {
+				challenge := `Bearer realm="movies"`
+				if errors.Is(err, ErrInvalidToken) {
+					challenge += `, error="invalid_token"`
+				}
+				w.Header().Set("WWW-Authenticate", challenge)
+				response.WriteProblem(w, r, unauthorized(err))
+				logger.Error.Println("Authentication failed for", r.Method, r.URL.Path+":", err)
+				return
+			}
+
+			response.WriteProblem(w, r, apperr.Internal(err))
+			logger.Error.Println("Error authenticating request:", err)
+			return
+		}
+
+		//? Scope check
+		if !identity.HasScope(scope) {
+			response.WriteProblem(w, r, apperr.Forbidden("missing required scope: %s", scope))
+			logger.Error.Println("Caller", identity.Subject, "lacks scope", scope)
+			return
+		}
+
+		logger.Info.Println("Authenticated", identity.Subject, "via", identity.Method, "for", r.Method, r.URL.Path)
+
+		ctx := WithIdentity(r.Context(), identity)
+		ctx = db.WithActor(ctx, identity.Subject)
+		next(w, r.WithContext(ctx))
+	}
+}
+
+// Check authenticates raw X-API-Key and Authorization values and checks scope, for transports other than HTTP
+func (a *Authenticator) Check(apiKey string, authorization string, scope string) (*Identity, error) {
+	identity, err := a.credentials(apiKey, authorization)
+	if err != nil {
+		if errors.Is(err, errMissingCredentials) || errors.Is(err, ErrInvalidToken) {
+			return nil, unauthorized(err)
+		}
+		return nil, apperr.Internal(err)
+	}
+
+	if !identity.HasScope(scope) {
+		return nil, apperr.Forbidden("missing required scope: %s", scope)
+	}
+
+	return identity, nil
+}
+
+// QueryToken lets clients that cannot set headers, such as EventSource and browser
+// WebSockets, pass their token as ?access_token=; headers still take precedence
+func QueryToken(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		token := query.Get("access_token")
+		if token != "" && r.Header.Get("Authorization") == "" && r.Header.Get("X-API-Key") == "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		//? Keep the token out of anything downstream that logs the URL
+		query.Del("access_token")
+		r.URL.RawQuery = query.Encode()
+
+		next(w, r)
+	}
+}
+
+// BearerToken returns the token of an Authorization header using the Bearer scheme, which is case-insensitive
+func BearerToken(authorization string) (string, bool) {
+	scheme, token, ok := strings.Cut(authorization, " ")
+	token = strings.TrimSpace(token)
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return token, true
+}
+
+func (a *Authenticator) authenticate(r *http.Request) (*Identity, error) {
+	return a.credentials(r.Header.Get("X-API-Key"), r.Header.Get("Authorization"))
+}
+
+func (a *Authenticator) credentials(apiKey string, authorization string) (*Identity, error) {
+	if apiKey != "" {
+		return a.apiKeyIdentity(apiKey)
+	}
+
+	token, ok := BearerToken(authorization)
+	if !ok {
+		return nil, errMissingCredentials
+	}
+
+	//? API keys and sessions carry fixed prefixes; anything else is treated as a JWT
+	if strings.HasPrefix(token, sessionPrefix) {
+		return a.sessionIdentity(token)
+	}
+	if strings.HasPrefix(token, keyPrefix) || a.JWT == nil {
+		return a.apiKeyIdentity(token)
+	}
+
+	claims, err := a.JWT.Verify(token)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.JWT.Identity(claims), nil
+}
+
+func (a *Authenticator) apiKeyIdentity(key string) (*Identity, error) {
+	//* Look up key by hash
+	apiKey, err := a.Keys.GetAPIKeyByHash(HashKey(key))
+	if errors.Is(err, db.ErrNotFound) {
+		return nil, fmt.Errorf("%w: unknown or revoked API key", ErrInvalidToken)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if apiKey.RevokedAt != nil {
+		return nil, fmt.Errorf("%w: unknown or revoked API key", ErrInvalidToken)
+	}
+
+	return &Identity{
+		Subject: "apikey:" + strconv.FormatInt(apiKey.ID, 10),
+		Name:    apiKey.Name,
+		Method:  "apikey",
+		Scopes:  apiKey.Scopes,
+	}, nil
+}
+
+func (a *Authenticator) sessionIdentity(token string) (*Identity, error) {
+	user, err := a.Users.GetUserBySession(HashKey(token))
+	if errors.Is(err, db.ErrNotFound) {
+		return nil, fmt.Errorf("%w: unknown or expired session", ErrInvalidToken)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &Identity{
+		Subject: "user:" + strconv.FormatInt(user.ID, 10),
+		Name:    user.Username,
+		Method:  "session",
+		Roles:   []string{user.Role},
+		Scopes:  ScopesForRoles(Policy, user.Role),
+	}, nil
+}
+
+// NewAuthenticator wires API key lookup and, if a JWKS source is configured, JWT validation
+func NewAuthenticator(cfg *config.Config, keys db.APIKeyStore, users db.UserStore) (*Authenticator, error) {
+	a := &Authenticator{Keys: keys, Users: users}
+
+	jwtCfg := cfg.JWTConfig
+	if jwtCfg.JWKSFile == "" && jwtCfg.JWKSURL == "" {
+		return a, nil
+	}
+
+	var ks *KeySet
+	var err error
+	if jwtCfg.JWKSFile != "" {
+		ks, err = NewFileKeySet(jwtCfg.JWKSFile)
+	} else {
+		ks, err = NewURLKeySet(jwtCfg.JWKSURL)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("loading JWKS: %w", err)
+	}
+
+	roleScopes := jwtCfg.RoleScopes
+	if len(roleScopes) == 0 {
+		roleScopes = Policy
+	}
+
+	a.JWT = &JWTVerifier{
+		Keys:       ks,
+		Issuer:     jwtCfg.Issuer,
+		Audience:   jwtCfg.Audience,
+		ClockSkew:  jwtCfg.ClockSkew,
+		RolesClaim: jwtCfg.RolesClaim,
+		RoleScopes: roleScopes,
+	}
+
+	return a, nil
+}
+
+func unauthorized(err error) *apperr.Error {
+	if errors.Is(err, ErrInvalidToken) {
+		return apperr.Unauthorized(apperr.CodeInvalidToken, "%v", err)
+	}
+	return apperr.Unauthorized(apperr.CodeUnauthorized, "%v", err)
+}
@@ -0,0 +1,159 @@
+package auth
+
+import (
+	"encoding/json"
+	"github/MahfujulSagor/movies_crud/internals/apperr"
+	"github/MahfujulSagor/movies_crud/internals/config"
+	"github/MahfujulSagor/movies_crud/internals/db"
+	"github/MahfujulSagor/movies_crud/internals/db/sqlite"
+	"github/MahfujulSagor/movies_crud/internals/types"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newStore(t *testing.T) *sqlite.SQLite {
+	t.Helper()
+	store, err := sqlite.New(&config.Config{DBPath: filepath.Join(t.TempDir(), "movies.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+// storeKey saves a key with scopes and returns its plaintext and ID
+func storeKey(t *testing.T, store db.APIKeyStore, scopes ...string) (string, int64) {
+	t.Helper()
+	plaintext, prefix, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := store.CreateAPIKey(&types.APIKey{Name: "test", Prefix: prefix, Scopes: scopes, CreatedAt: time.Now().UTC()}, HashKey(plaintext))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return plaintext, id
+}
+
+// call sends headers through Require(scope) and returns the response and the identity next saw
+func call(a *Authenticator, scope string, headers map[string]string) (*httptest.ResponseRecorder, *Identity) {
+	var seen *Identity
+	handler := a.Require(scope, func(w http.ResponseWriter, r *http.Request) {
+		seen, _ = FromContext(r.Context())
+		if db.ActorFromContext(r.Context()) != seen.Subject {
+			w.WriteHeader(http.StatusTeapot)
+		}
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/movies", nil)
+	for name, value := range headers {
+		r.Header.Set(name, value)
+	}
+	rec := httptest.NewRecorder()
+	handler(rec, r)
+	return rec, seen
+}
+
+func problemCode(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+	var problem struct {
+		Code string `json:"code"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("body %q is not a problem: %v", rec.Body, err)
+	}
+	return problem.Code
+}
+
+func TestRequireAcceptsKeysInEitherHeader(t *testing.T) {
+	store := newStore(t)
+	a := &Authenticator{Keys: store, Users: store}
+	key, id := storeKey(t, store, ScopeMoviesRead)
+
+	for _, headers := range []map[string]string{
+		{"X-API-Key": key},
+		{"Authorization": "Bearer " + key},
+		{"Authorization": "bearer  " + key},
+	} {
+		rec, identity := call(a, ScopeMoviesRead, headers)
+		if rec.Code != http.StatusOK || identity == nil {
+			t.Fatalf("%v: status %d, body %s", headers, rec.Code, rec.Body)
+		}
+		if identity.Method != "apikey" || identity.Subject != "apikey:"+strconv.FormatInt(id, 10) {
+			t.Errorf("%v: identity %+v", headers, identity)
+		}
+	}
+}
+
+func TestRequireRejectsBadCredentials(t *testing.T) {
+	store := newStore(t)
+	a := &Authenticator{Keys: store, Users: store}
+	readOnly, _ := storeKey(t, store, ScopeMoviesRead)
+	revoked, revokedID := storeKey(t, store, ScopeMoviesRead, ScopeMoviesDelete)
+	if _, err := store.RevokeAPIKey(revokedID); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name      string
+		headers   map[string]string
+		status    int
+		code      string
+		challenge string
+	}{
+		{"no credentials", nil, http.StatusUnauthorized, apperr.CodeUnauthorized, `Bearer realm="movies"`},
+		{"other scheme", map[string]string{"Authorization": "Basic " + readOnly}, http.StatusUnauthorized, apperr.CodeUnauthorized, `Bearer realm="movies"`},
+		{"unknown key", map[string]string{"X-API-Key": keyPrefix + "unknown"}, http.StatusUnauthorized, apperr.CodeInvalidToken, `Bearer realm="movies", error="invalid_token"`},
+		{"revoked key", map[string]string{"X-API-Key": revoked}, http.StatusUnauthorized, apperr.CodeInvalidToken, `Bearer realm="movies", error="invalid_token"`},
+		{"missing scope", map[string]string{"X-API-Key": readOnly}, http.StatusForbidden, apperr.CodeForbidden, ""},
+	} {
+		rec, identity := call(a, ScopeMoviesDelete, tc.headers)
+		if identity != nil {
+			t.Errorf("%s: the handler ran", tc.name)
+		}
+		if rec.Code != tc.status || problemCode(t, rec) != tc.code {
+			t.Errorf("%s: got %d %s, want %d %s", tc.name, rec.Code, problemCode(t, rec), tc.status, tc.code)
+		}
+		if got := rec.Header().Get("WWW-Authenticate"); got != tc.challenge {
+			t.Errorf("%s: WWW-Authenticate %q, want %q", tc.name, got, tc.challenge)
+		}
+	}
+}
+
+func TestValidateScopes(t *testing.T) {
+	if err := ValidateScopes([]string{ScopeMoviesRead, ScopeKeysAdmin}); err != nil {
+		t.Errorf("known scopes rejected: %v", err)
+	}
+	if err := ValidateScopes([]string{ScopeMoviesRead, "movies:*"}); err == nil {
+		t.Error("unknown scope accepted")
+	}
+}
+
+func TestQueryTokenMovesTokenOutOfURL(t *testing.T) {
+	for _, tc := range []struct {
+		name, target, header, wantAuth string
+	}{
+		{"token only in query", "/api/v1/events?access_token=abc&movie_id=1", "", "Bearer abc"},
+		{"header wins", "/api/v1/events?access_token=abc&movie_id=1", "Bearer header", "Bearer header"},
+	} {
+		var auth, query string
+		handler := QueryToken(func(w http.ResponseWriter, r *http.Request) {
+			auth, query = r.Header.Get("Authorization"), r.URL.RawQuery
+		})
+
+		r := httptest.NewRequest(http.MethodGet, tc.target, nil)
+		if tc.header != "" {
+			r.Header.Set("Authorization", tc.header)
+		}
+		handler(httptest.NewRecorder(), r)
+
+		if auth != tc.wantAuth || strings.Contains(query, "access_token") || query != "movie_id=1" {
+			t.Errorf("%s: Authorization %q and query %q", tc.name, auth, query)
+		}
+	}
+}
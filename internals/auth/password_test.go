@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestHashPasswordRoundTrip(t *testing.T) {
+	hash, err := HashPassword("correct horse battery staple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=65536,t=3,p=4$") {
+		t.Fatalf("hash %q is not argon2id with the configured parameters", hash)
+	}
+
+	//? Salted, so hashing again gives a different string
+	if again, _ := HashPassword("correct horse battery staple"); again == hash {
+		t.Fatal("two hashes of the same password are equal")
+	}
+
+	for password, want := range map[string]bool{
+		"correct horse battery staple":  true,
+		"correct horse battery staple ": false,
+		"Correct horse battery staple":  false,
+		"":                              false,
+	} {
+		if ok, err := VerifyPassword(password, hash); err != nil || ok != want {
+			t.Errorf("VerifyPassword(%q) = %v, %v; want %v", password, ok, err, want)
+		}
+	}
+}
+
+func TestVerifyPasswordRejectsMalformedHashes(t *testing.T) {
+	hash, err := HashPassword("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(hash, "$")
+
+	for name, encoded := range map[string]string{
+		"bcrypt":        "$2a$10$abcdefghijklmnopqrstuuabcdefghijklmnopqrstuvwxyz01234",
+		"argon2i":       strings.Replace(hash, "argon2id", "argon2i", 1),
+		"old version":   strings.Replace(hash, "v=19", "v=16", 1),
+		"bad params":    strings.Join([]string{"", parts[1], parts[2], "m=x", parts[4], parts[5]}, "$"),
+		"bad salt":      strings.Join([]string{"", parts[1], parts[2], parts[3], "!!", parts[5]}, "$"),
+		"missing parts": strings.Join(parts[:5], "$"),
+	} {
+		if ok, err := VerifyPassword("secret", encoded); err == nil || ok {
+			t.Errorf("%s hash verified: %v, %v", name, ok, err)
+		}
+	}
+}
+
+func TestScopesForRoles(t *testing.T) {
+	for _, tc := range []struct {
+		roles []string
+		want  []string
+	}{
+		{[]string{RoleViewer}, []string{ScopeCastsRead, ScopeDirectorsRead, ScopeMoviesRead}},
+		{[]string{RoleViewer, RoleEditor}, []string{
+			ScopeCastsRead, ScopeCastsWrite, ScopeDirectorsRead, ScopeDirectorsWrite, ScopeMoviesRead, ScopeMoviesWrite,
+		}},
+		{[]string{"superuser"}, nil},
+	} {
+		if got := ScopesForRoles(Policy, tc.roles...); !slices.Equal(got, tc.want) {
+			t.Errorf("ScopesForRoles(%v) = %v, want %v", tc.roles, got, tc.want)
+		}
+	}
+
+	//? Only admins manage keys, users and webhooks, and only they delete movies
+	admin := ScopesForRoles(Policy, RoleAdmin)
+	for _, scope := range Scopes {
+		if !slices.Contains(admin, scope) {
+			t.Errorf("admin lacks %s", scope)
+		}
+	}
+	editor := ScopesForRoles(Policy, RoleEditor)
+	for _, scope := range []string{ScopeMoviesDelete, ScopeKeysAdmin, ScopeUsersAdmin, ScopeWebhooksAdmin} {
+		if slices.Contains(editor, scope) {
+			t.Errorf("editor has %s", scope)
+		}
+	}
+}
+
+func TestBearerToken(t *testing.T) {
+	for header, want := range map[string]string{
+		"Bearer abc":   "abc",
+		"bearer abc":   "abc",
+		"BEARER  abc ": "abc",
+		"Basic abc":    "",
+		"Bearer":       "",
+		"Bearer   ":    "",
+		"":             "",
+	} {
+		token, ok := BearerToken(header)
+		if token != want || ok != (want != "") {
+			t.Errorf("BearerToken(%q) = %q, %v; want %q", header, token, ok, want)
+		}
+	}
+}
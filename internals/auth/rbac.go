@@ -0,0 +1,34 @@
+package auth
+
+import "slices"
+
+const (
+	RoleViewer string = "viewer"
+	RoleEditor string = "editor"
+	RoleAdmin  string = "admin"
+)
+
+var Roles = []string{RoleViewer, RoleEditor, RoleAdmin}
+
+// Policy maps each role to the operations it may perform on movies, directors and casts
+var Policy = map[string][]string{
+	RoleViewer: {
+		ScopeMoviesRead, ScopeDirectorsRead, ScopeCastsRead,
+	},
+	RoleEditor: {
+		ScopeMoviesRead, ScopeMoviesWrite,
+		ScopeDirectorsRead, ScopeDirectorsWrite,
+		ScopeCastsRead, ScopeCastsWrite,
+	},
+	RoleAdmin: Scopes,
+}
+
+// ScopesForRoles resolves roles through policy, ignoring unknown roles
+func ScopesForRoles(policy map[string][]string, roles ...string) []string {
+	var scopes []string
+	for _, role := range roles {
+		scopes = append(scopes, policy[role]...)
+	}
+	slices.Sort(scopes)
+	return slices.Compact(scopes)
+}
@@ -0,0 +1,217 @@
+// Package cache is a read-through cache in front of movie storage, invalidated by writes.
+package cache
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"github/MahfujulSagor/movies_crud/internals/config"
+	"github/MahfujulSagor/movies_crud/internals/db"
+	"github/MahfujulSagor/movies_crud/internals/logger"
+	"github/MahfujulSagor/movies_crud/internals/types"
+	"sync/atomic"
+	"time"
+)
+
+// Backend stores encoded values. Implementations must be safe for concurrent use; a shared
+// cache such as Redis can replace the in-process LRU by implementing it.
+type Backend interface {
+	Get(key string) ([]byte, bool)
+	// Set stores value for ttl; zero keeps it until evicted
+	Set(key string, value []byte, ttl time.Duration)
+	Delete(keys ...string)
+}
+
+// listGeneration is bumped on every write, so list pages cached under the old value are never read again
+const listGeneration = "movies:list:generation"
+
+// Stats reports cache effectiveness since startup
+type Stats struct {
+	Hits      uint64  `json:"hits"`
+	Misses    uint64  `json:"misses"`
+	HitRatio  float64 `json:"hit_ratio"`
+	Entries   int     `json:"entries,omitempty"`
+	Evictions uint64  `json:"evictions,omitempty"`
+}
+
+// Store decorates a db.DB, caching movie and list reads and invalidating them on writes made through it.
+// Writes from other processes are only seen once the cached entries expire.
+type Store struct {
+	db.DB
+	backend Backend
+	// ttl and listTTL hold time.Durations, swapped by Reload
+	ttl     atomic.Int64
+	listTTL atomic.Int64
+	hits    atomic.Uint64
+	misses  atomic.Uint64
+}
+
+func New(cfg *config.Config, inner db.DB, backend Backend) *Store {
+	s := &Store{DB: inner, backend: backend}
+	s.Reload(cfg)
+	return s
+}
+
+// Reload applies new TTLs to entries stored from now on; cached entries keep theirs
+func (s *Store) Reload(cfg *config.Config) {
+	s.ttl.Store(int64(cfg.CacheConfig.TTL))
+	s.listTTL.Store(int64(cfg.CacheConfig.ListTTL))
+}
+
+func (s *Store) GetMovieByID(ctx context.Context, id int64) (*types.Movie, error) {
+	key := movieKey(id)
+	gen := s.generation(key+":generation", time.Duration(s.ttl.Load()))
+
+	var movie types.Movie
+	if s.lookup(gen, key, &movie) {
+		return &movie, nil
+	}
+
+	found, err := s.DB.GetMovieByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	s.store(gen, key, found, time.Duration(s.ttl.Load()))
+	return found, nil
+}
+
+func (s *Store) GetMovieList(ctx context.Context, limit int, offset int) ([]*types.Movie, error) {
+	gen := s.generation(listGeneration, 0)
+	key := fmt.Sprintf("movies:list:%d:%d:%d", gen, limit, offset)
+
+	var movies []*types.Movie
+	if s.lookup(gen, key, &movies) {
+		return movies, nil
+	}
+
+	movies, err := s.DB.GetMovieList(ctx, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	s.store(gen, key, movies, time.Duration(s.listTTL.Load()))
+	return movies, nil
+}
+
+func (s *Store) MoviesLastModified(ctx context.Context) (time.Time, error) {
+	gen := s.generation(listGeneration, 0)
+	key := fmt.Sprintf("movies:modified:%d", gen)
+
+	var modified time.Time
+	if s.lookup(gen, key, &modified) {
+		return modified, nil
+	}
+
+	modified, err := s.DB.MoviesLastModified(ctx)
+	if err != nil {
+		return time.Time{}, err
+	}
+	s.store(gen, key, modified, time.Duration(s.listTTL.Load()))
+	return modified, nil
+}
+
+func (s *Store) CreateMovie(ctx context.Context, movie *types.Movie) (int64, error) {
+	id, err := s.DB.CreateMovie(ctx, movie)
+	if err != nil {
+		return id, err
+	}
+
+	s.invalidate(id)
+	return id, nil
+}
+
+func (s *Store) UpdateMovie(ctx context.Context, id int64, movie *types.Movie) (int64, error) {
+	updated, err := s.DB.UpdateMovie(ctx, id, movie)
+	if err != nil {
+		return updated, err
+	}
+
+	s.invalidate(id)
+	return updated, nil
+}
+
+func (s *Store) DeleteMovieByID(ctx context.Context, id int64) (int64, error) {
+	deleted, err := s.DB.DeleteMovieByID(ctx, id)
+	if err != nil {
+		return deleted, err
+	}
+
+	s.invalidate(id)
+	return deleted, nil
+}
+
+func (s *Store) CacheStats() Stats {
+	stats := Stats{Hits: s.hits.Load(), Misses: s.misses.Load()}
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRatio = float64(stats.Hits) / float64(total)
+	}
+
+	//? Only backends that track their size report it
+	if sized, ok := s.backend.(interface {
+		Len() int
+		Evictions() uint64
+	}); ok {
+		stats.Entries = sized.Len()
+		stats.Evictions = sized.Evictions()
+	}
+	return stats
+}
+
+// invalidate moves the movie and the list pages to new generations, so entries tagged with the old
+// ones are never read again, including any a concurrent read stores after this returns
+func (s *Store) invalidate(id int64) {
+	key := movieKey(id)
+	s.backend.Delete(key)
+	s.backend.Set(key+":generation", newGeneration(), time.Duration(s.ttl.Load()))
+	s.backend.Set(listGeneration, newGeneration(), 0)
+}
+
+// generation returns the generation stored at genKey, starting a new one if it was evicted
+func (s *Store) generation(genKey string, ttl time.Duration) uint64 {
+	value, ok := s.backend.Get(genKey)
+	if !ok || len(value) != 8 {
+		value = newGeneration()
+		s.backend.Set(genKey, value, ttl)
+	}
+	return binary.BigEndian.Uint64(value)
+}
+
+// lookup decodes the entry at key into out if it was stored under generation gen
+func (s *Store) lookup(gen uint64, key string, out any) bool {
+	data, ok := s.backend.Get(key)
+	if ok && len(data) >= 8 && binary.BigEndian.Uint64(data) == gen {
+		if err := json.Unmarshal(data[8:], out); err == nil {
+			s.hits.Add(1)
+			return true
+		}
+	}
+	if ok {
+		s.backend.Delete(key)
+	}
+
+	s.misses.Add(1)
+	return false
+}
+
+// store caches an encoded copy tagged with the generation read before value was loaded, so callers
+// can never modify what later readers get. A write that invalidated in the meantime has moved the
+// generation on, so a stale value stored here is never served; the tag and the check on read need
+// no lock, which keeps this safe on a shared backend too.
+func (s *Store) store(gen uint64, key string, value any, ttl time.Duration) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		logger.Error.Println("Error encoding cache entry", key+":", err)
+		return
+	}
+	entry := binary.BigEndian.AppendUint64(make([]byte, 0, 8+len(data)), gen)
+	s.backend.Set(key, append(entry, data...), ttl)
+}
+
+func movieKey(id int64) string {
+	return fmt.Sprintf("movies:%d", id)
+}
+
+// newGeneration is time based, so a generation recreated after eviction never matches stale pages
+func newGeneration() []byte {
+	return binary.BigEndian.AppendUint64(nil, uint64(time.Now().UnixNano()))
+}
@@ -0,0 +1,100 @@
+package cache
+
+import (
+	"context"
+	"github/MahfujulSagor/movies_crud/internals/config"
+	"github/MahfujulSagor/movies_crud/internals/db"
+	"github/MahfujulSagor/movies_crud/internals/types"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeDB serves one movie whose title the test changes through UpdateMovie
+type fakeDB struct {
+	db.DB
+	mu    sync.Mutex
+	title string
+	reads int
+}
+
+func (f *fakeDB) GetMovieByID(ctx context.Context, id int64) (*types.Movie, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.reads++
+	return &types.Movie{ID: id, Title: f.title}, nil
+}
+
+func (f *fakeDB) UpdateMovie(ctx context.Context, id int64, movie *types.Movie) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.title = movie.Title
+	return id, nil
+}
+
+// pausingBackend holds the first Set of key until resume is closed, to interleave a write with it
+type pausingBackend struct {
+	*LRU
+	key    string
+	once   sync.Once
+	paused chan struct{}
+	resume chan struct{}
+}
+
+func (b *pausingBackend) Set(key string, value []byte, ttl time.Duration) {
+	if key == b.key {
+		b.once.Do(func() {
+			close(b.paused)
+			<-b.resume
+		})
+	}
+	b.LRU.Set(key, value, ttl)
+}
+
+func testStore(backend Backend) (*Store, *fakeDB) {
+	cfg := &config.Config{}
+	cfg.CacheConfig.TTL = time.Minute
+	cfg.CacheConfig.ListTTL = time.Minute
+	inner := &fakeDB{title: "Old title"}
+	return New(cfg, inner, backend), inner
+}
+
+func TestGetMovieByIDServesFromCache(t *testing.T) {
+	s, inner := testStore(NewLRU(100))
+	for range 3 {
+		if _, err := s.GetMovieByID(context.Background(), 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if inner.reads != 1 {
+		t.Fatalf("storage read %d times for three lookups, want 1", inner.reads)
+	}
+}
+
+func TestReadStoredAfterInvalidationIsNotServed(t *testing.T) {
+	backend := &pausingBackend{LRU: NewLRU(100), key: movieKey(1), paused: make(chan struct{}), resume: make(chan struct{})}
+	s, _ := testStore(backend)
+	ctx := context.Background()
+
+	//? The read loads the old title, then stalls just before caching it
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.GetMovieByID(ctx, 1)
+	}()
+	<-backend.paused
+
+	if _, err := s.UpdateMovie(ctx, 1, &types.Movie{Title: "New title"}); err != nil {
+		t.Fatal(err)
+	}
+	close(backend.resume)
+	<-done
+
+	movie, err := s.GetMovieByID(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if movie.Title != "New title" {
+		t.Fatalf("cache served %q after the update, want %q", movie.Title, "New title")
+	}
+}
@@ -1,57 +1,263 @@
 package config
 
 import (
+	"errors"
 	"flag"
+	"fmt"
 	"log"
+	"net/url"
 	"os"
+	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
 	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
 )
 
 type HTTPConfig struct {
-	Host string `yaml:"host"`
-	Port int    `yaml:"port"`
+	Host           string   `yaml:"host" env:"HTTP_HOST" env-default:"localhost"`
+	Port           int      `yaml:"port" env:"HTTP_PORT" env-default:"8080"`
+	TrustedProxies []string `yaml:"trusted_proxies" env:"HTTP_TRUSTED_PROXIES" env-separator:","`
+	// CacheControl overrides route policies, keyed by "METHOD /pattern" as in the OpenAPI document
+	CacheControl Map[string] `yaml:"cache_control" env:"HTTP_CACHE_CONTROL"`
+	// MaxBodySize caps request bodies in bytes after decompression; BodyLimits overrides it per "METHOD /pattern"
+	MaxBodySize int64             `yaml:"max_body_size" env:"HTTP_MAX_BODY_SIZE" env-default:"1048576"`
+	BodyLimits  Map[int64]        `yaml:"body_limits" env:"HTTP_BODY_LIMITS"`
+	Compression CompressionConfig `yaml:"compression"`
+	CORS        CORSConfig        `yaml:"cors"`
+	Security    SecurityConfig    `yaml:"security"`
+	TLS         TLSConfig         `yaml:"tls"`
+	// ReadHeaderTimeout bounds reading the request headers, ReadTimeout the whole request and
+	// WriteTimeout the response; event streams lift the last two for themselves
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" env:"HTTP_READ_HEADER_TIMEOUT" env-default:"5s"`
+	ReadTimeout       time.Duration `yaml:"read_timeout" env:"HTTP_READ_TIMEOUT" env-default:"30s"`
+	WriteTimeout      time.Duration `yaml:"write_timeout" env:"HTTP_WRITE_TIMEOUT" env-default:"60s"`
+	// IdleTimeout closes keep-alive connections that wait this long for the next request
+	IdleTimeout    time.Duration `yaml:"idle_timeout" env:"HTTP_IDLE_TIMEOUT" env-default:"120s"`
+	MaxHeaderBytes int           `yaml:"max_header_bytes" env:"HTTP_MAX_HEADER_BYTES" env-default:"1048576"`
+	// MaxConns caps open connections, idle and upgraded ones included; further clients wait to be
+	// accepted. 0 leaves it unlimited
+	MaxConns int `yaml:"max_conns" env:"HTTP_MAX_CONNS"`
+	// ShutdownGrace is how long in-flight requests may take to finish once shutdown starts
+	ShutdownGrace time.Duration `yaml:"shutdown_grace" env:"HTTP_SHUTDOWN_GRACE" env-default:"10s"`
+	// ShutdownDelay is how long the server keeps serving after /readyz turns unready, so load
+	// balancers stop routing to it before connections close. 0 shuts down at once
+	ShutdownDelay time.Duration `yaml:"shutdown_delay" env:"HTTP_SHUTDOWN_DELAY" env-default:"5s"`
 }
 
+// TLSConfig serves HTTPS, and gRPC over TLS, when CertFile and KeyFile are set. The files are
+// checked every ReloadInterval and swapped in when they change.
+type TLSConfig struct {
+	CertFile   string `yaml:"cert_file" env:"HTTP_TLS_CERT_FILE"`
+	KeyFile    string `yaml:"key_file" env:"HTTP_TLS_KEY_FILE"`
+	MinVersion string `yaml:"min_version" env:"HTTP_TLS_MIN_VERSION" env-default:"1.2"`
+	// CipherSuites limits the TLS 1.2 suites by Go name; empty keeps Go's secure defaults
+	CipherSuites []string `yaml:"cipher_suites" env:"HTTP_TLS_CIPHER_SUITES" env-separator:","`
+	// ClientCAFile enables mutual TLS; ClientAuth "require" refuses clients without a certificate
+	// signed by one of its CAs, "optional" only verifies certificates that are presented
+	ClientCAFile   string        `yaml:"client_ca_file" env:"HTTP_TLS_CLIENT_CA_FILE"`
+	ClientAuth     string        `yaml:"client_auth" env:"HTTP_TLS_CLIENT_AUTH" env-default:"require"`
+	ReloadInterval time.Duration `yaml:"reload_interval" env:"HTTP_TLS_RELOAD_INTERVAL" env-default:"30s"`
+	// RedirectPort serves plain HTTP redirects to HTTPS on the same host; 0 leaves it off
+	RedirectPort int `yaml:"redirect_port" env:"HTTP_TLS_REDIRECT_PORT"`
+}
+
+// Enabled reports whether a certificate is configured
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != "" || c.KeyFile != ""
+}
+
+// CompressionConfig enables response compression with the first of Algorithms ("zstd", "br", "gzip")
+// the client accepts, for responses of at least MinSize bytes
+type CompressionConfig struct {
+	Enabled    bool     `yaml:"enabled" env:"HTTP_COMPRESSION_ENABLED"`
+	Algorithms []string `yaml:"algorithms" env:"HTTP_COMPRESSION_ALGORITHMS" env-separator:"," env-default:"zstd,br,gzip"`
+	MinSize    int      `yaml:"min_size" env:"HTTP_COMPRESSION_MIN_SIZE" env-default:"1024"`
+}
+
+// CORSConfig lets browser apps on AllowedOrigins call the API; an empty list leaves CORS off.
+// Origins are exact ("https://admin.example.com"), "*", or a subdomain wildcard ("https://*.example.com").
+type CORSConfig struct {
+	AllowedOrigins   []string      `yaml:"allowed_origins" env:"CORS_ALLOWED_ORIGINS" env-separator:","`
+	AllowedMethods   []string      `yaml:"allowed_methods" env:"CORS_ALLOWED_METHODS" env-separator:"," env-default:"GET,POST,PUT,DELETE"`
+	AllowedHeaders   []string      `yaml:"allowed_headers" env:"CORS_ALLOWED_HEADERS" env-separator:"," env-default:"Authorization,Content-Type,Content-Encoding,X-API-Key,Idempotency-Key,If-Modified-Since,Accept-Language"`
+	ExposedHeaders   []string      `yaml:"exposed_headers" env:"CORS_EXPOSED_HEADERS" env-separator:"," env-default:"Location,Last-Modified,Retry-After,RateLimit-Limit,RateLimit-Remaining,RateLimit-Reset,RateLimit-Policy,Idempotent-Replayed"`
+	AllowCredentials bool          `yaml:"allow_credentials" env:"CORS_ALLOW_CREDENTIALS"`
+	MaxAge           time.Duration `yaml:"max_age" env:"CORS_MAX_AGE" env-default:"10m"`
+}
+
+// SecurityConfig tunes the hardening headers sent with every response
+type SecurityConfig struct {
+	// HSTSMaxAge is sent as Strict-Transport-Security on HTTPS requests; 0 leaves it off
+	HSTSMaxAge            time.Duration `yaml:"hsts_max_age" env:"HTTP_HSTS_MAX_AGE"`
+	HSTSIncludeSubdomains bool          `yaml:"hsts_include_subdomains" env:"HTTP_HSTS_INCLUDE_SUBDOMAINS"`
+	// PageCSP is the Content-Security-Policy of HTML pages such as /docs; other responses get default-src 'none'
+	PageCSP string `yaml:"page_csp" env:"HTTP_PAGE_CSP" env-default:"default-src 'self'; style-src 'self' 'unsafe-inline'; img-src 'self' data:; frame-ancestors 'none'"`
+}
+
+// LoggingConfig sets the least severe level logged: "debug", "info", "warn" or "error"
 type LoggingConfig struct {
-	Level string `yaml:"level"`
-	File  string `yaml:"file"`
+	Level string `yaml:"level" env:"LOG_LEVEL" env-default:"info"`
+	File  string `yaml:"file" env:"LOG_FILE" env-default:"logs/app.log"`
+}
+
+type HealthConfig struct {
+	Token string `yaml:"token" env:"HEALTH_TOKEN"`
+}
+
+type JWTConfig struct {
+	JWKSFile   string        `yaml:"jwks_file" env:"JWT_JWKS_FILE"`
+	JWKSURL    string        `yaml:"jwks_url" env:"JWT_JWKS_URL"`
+	Issuer     string        `yaml:"issuer" env:"JWT_ISSUER"`
+	Audience   []string      `yaml:"audience" env:"JWT_AUDIENCE" env-separator:","`
+	ClockSkew  time.Duration `yaml:"clock_skew" env:"JWT_CLOCK_SKEW" env-default:"30s"`
+	RolesClaim string        `yaml:"roles_claim" env:"JWT_ROLES_CLAIM" env-default:"roles"`
+	RoleScopes Map[[]string] `yaml:"role_scopes" env:"JWT_ROLE_SCOPES"`
+}
+
+type SessionConfig struct {
+	TTL time.Duration `yaml:"ttl" env:"SESSION_TTL" env-default:"12h"`
+}
+
+type RateLimitRule struct {
+	Requests int           `yaml:"requests"`
+	Per      time.Duration `yaml:"per"`
+	Burst    int           `yaml:"burst"`
+}
+
+type RateLimitConfig struct {
+	Enabled bool               `yaml:"enabled" env:"RATE_LIMIT_ENABLED"`
+	Groups  Map[RateLimitRule] `yaml:"groups" env:"RATE_LIMIT_GROUPS"`
+}
+
+// GRPCConfig enables the gRPC server on Port, bound to the HTTP host; 0 leaves it off
+type GRPCConfig struct {
+	Port int `yaml:"port" env:"GRPC_PORT"`
+}
+
+// WebhookConfig tunes outgoing webhook delivery
+type WebhookConfig struct {
+	MaxAttempts int           `yaml:"max_attempts" env:"WEBHOOK_MAX_ATTEMPTS" env-default:"8"`
+	BaseBackoff time.Duration `yaml:"base_backoff" env:"WEBHOOK_BASE_BACKOFF" env-default:"30s"`
+	MaxBackoff  time.Duration `yaml:"max_backoff" env:"WEBHOOK_MAX_BACKOFF" env-default:"1h"`
+	Timeout     time.Duration `yaml:"timeout" env:"WEBHOOK_TIMEOUT" env-default:"10s"`
+	// AllowPrivateNetworks lets webhooks reach loopback, private and link-local addresses, for receivers
+	// on the same host or network; off, such URLs are refused at creation and delivery
+	AllowPrivateNetworks bool `yaml:"allow_private_networks" env:"WEBHOOK_ALLOW_PRIVATE_NETWORKS"`
+}
+
+// OutboxConfig controls the relay that forwards committed events to Sinks
+// ("bus", "webhook", "log", "file"); File is the JSON-lines path for the file sink
+type OutboxConfig struct {
+	Sinks        []string      `yaml:"sinks" env:"OUTBOX_SINKS" env-separator:"," env-default:"bus,webhook"`
+	File         string        `yaml:"file" env:"OUTBOX_FILE"`
+	PollInterval time.Duration `yaml:"poll_interval" env:"OUTBOX_POLL_INTERVAL" env-default:"250ms"`
+	BatchSize    int           `yaml:"batch_size" env:"OUTBOX_BATCH_SIZE" env-default:"100"`
+	MaxBackoff   time.Duration `yaml:"max_backoff" env:"OUTBOX_MAX_BACKOFF" env-default:"5m"`
+	// Retention is how long relayed messages are kept before being purged
+	Retention time.Duration `yaml:"retention" env:"OUTBOX_RETENTION" env-default:"168h"`
+}
+
+// IdempotencyConfig sets how long responses to Idempotency-Key requests are kept for replay
+type IdempotencyConfig struct {
+	TTL time.Duration `yaml:"ttl" env:"IDEMPOTENCY_TTL" env-default:"24h"`
+}
+
+// CacheConfig enables the in-process read cache for movies; Size is the maximum number of entries
+type CacheConfig struct {
+	Enabled bool          `yaml:"enabled" env:"CACHE_ENABLED"`
+	Size    int           `yaml:"size" env:"CACHE_SIZE" env-default:"1000"`
+	TTL     time.Duration `yaml:"ttl" env:"CACHE_TTL" env-default:"5m"`
+	// ListTTL is kept short because list pages are invalidated as a whole on every write
+	ListTTL time.Duration `yaml:"list_ttl" env:"CACHE_LIST_TTL" env-default:"30s"`
 }
 
 type Config struct {
-	Env           string `yaml:"env" env:"ENV" env-required:"true"`
-	DBPath        string `yaml:"db_path"`
-	HTTPConfig    `yaml:"http"`
-	LoggingConfig `yaml:"logging"`
+	Env               string `yaml:"env" env:"ENV" env-required:"true"`
+	DBPath            string `yaml:"db_path" env:"DB_PATH" env-default:"db/movies.db"`
+	HTTPConfig        `yaml:"http"`
+	LoggingConfig     `yaml:"logging"`
+	HealthConfig      `yaml:"health"`
+	JWTConfig         `yaml:"jwt"`
+	SessionConfig     `yaml:"session"`
+	RateLimitConfig   `yaml:"rate_limit"`
+	GRPCConfig        `yaml:"grpc"`
+	WebhookConfig     `yaml:"webhooks"`
+	OutboxConfig      `yaml:"outbox"`
+	IdempotencyConfig `yaml:"idempotency"`
+	CacheConfig       `yaml:"cache"`
+	// ReloadInterval is how often the file is checked for changes; SIGHUP reloads it at once
+	ReloadInterval time.Duration `yaml:"reload_interval" env:"CONFIG_RELOAD_INTERVAL" env-default:"5s"`
 }
 
-func MustLoad() *Config {
-	if err := godotenv.Load(); err != nil {
-		log.Println("No .env file found")
-	}
+// Map is a map setting. In the environment it is written as a YAML flow mapping, such as
+// RATE_LIMIT_GROUPS='{read: {requests: 60, per: 1m, burst: 10}}'
+type Map[V any] map[string]V
 
-	var configPath string
-	configPath = os.Getenv("CONFIG_PATH")
+// SetValue parses an environment variable for cleanenv
+func (m *Map[V]) SetValue(s string) error {
+	//? The variable replaces the file's entries rather than adding to them
+	*m = nil
+	return yaml.Unmarshal([]byte(s), m)
+}
+
+var configFlag = flag.String("config", "", "Path to the configuration file")
+
+// Path returns the configuration file named by CONFIG_PATH, or by the -config flag
+func Path() (string, error) {
+	if path := os.Getenv("CONFIG_PATH"); path != "" {
+		return path, nil
+	}
 
-	if configPath == "" {
-		flags := flag.String("config", "", "Path to the configuration file")
+	if !flag.Parsed() {
 		flag.Parse()
-		configPath = *flags
+	}
+	if *configFlag == "" {
+		return "", errors.New("configuration file path must be provided via CONFIG_PATH environment variable or --config flag")
+	}
+	return *configFlag, nil
+}
+
+// Load reads the configuration file from Path, after loading a .env file if there is one
+func Load() (*Config, error) {
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found")
+	}
 
-		if configPath == "" {
-			log.Fatal("Configuration file path must be provided via CONFIG_PATH environment variable or --config flag")
-		}
+	path, err := Path()
+	if err != nil {
+		return nil, err
 	}
+	return Read(path)
+}
 
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		log.Fatal("Configuration file does not exist")
+// Read parses the file at path, applies environment overrides and defaults, and validates the result
+func Read(path string) (*Config, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("configuration file: %w", err)
 	}
 
 	var cfg Config
-	if err := cleanenv.ReadConfig(configPath, &cfg); err != nil {
-		log.Fatal("Failed to read configuration file:", err)
+	if err := cleanenv.ReadConfig(path, &cfg); err != nil {
+		return nil, fmt.Errorf("reading configuration file %s: %w", path, err)
 	}
 
-	return &cfg
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+const redacted = "[redacted]"
+
+// Redacted returns a copy that is safe to print, with secrets replaced
+func (c Config) Redacted() Config {
+	if c.HealthConfig.Token != "" {
+		c.HealthConfig.Token = redacted
+	}
+	//? Key set URLs may carry basic auth credentials
+	if u, err := url.Parse(c.JWTConfig.JWKSURL); err == nil && u.User != nil {
+		c.JWTConfig.JWKSURL = u.Redacted()
+	}
+	return c
 }
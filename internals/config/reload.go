@@ -0,0 +1,102 @@
+package config
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"reflect"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Reloadable lists the settings, by YAML path, that running components pick up without a restart.
+// A path covers everything below it.
+var Reloadable = []string{"logging.level", "http.cors", "http.compression", "rate_limit", "cache.ttl", "cache.list_ttl"}
+
+// Changes lists the settings that differ in next by YAML path, such as "http.port", split into
+// those that can be reloaded and those that need a restart
+func (c *Config) Changes(next *Config) (reloadable []string, fixed []string) {
+	var paths []string
+	diff(reflect.ValueOf(*c), reflect.ValueOf(*next), "", &paths)
+
+	for _, path := range paths {
+		if isReloadable(path) {
+			reloadable = append(reloadable, path)
+		} else {
+			fixed = append(fixed, path)
+		}
+	}
+	return reloadable, fixed
+}
+
+// Watch passes the configuration re-read from path to reload whenever the file changes, checked
+// every interval, or the process receives SIGHUP, until ctx is done. A file that fails to load or
+// validate is passed as the error.
+func Watch(ctx context.Context, path string, interval time.Duration, reload func(*Config, error)) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	last := stampOf(path)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+		case <-ticker.C:
+			if stampOf(path) == last {
+				continue
+			}
+		}
+
+		last = stampOf(path)
+		reload(Read(path))
+	}
+}
+
+// stamp identifies a version of the file; editors that write a new file and rename it change it too
+type stamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func stampOf(path string) stamp {
+	info, err := os.Stat(path)
+	//? A file missing mid-save is read again once it reappears
+	if err != nil {
+		return stamp{}
+	}
+	return stamp{modTime: info.ModTime(), size: info.Size()}
+}
+
+// diff appends the YAML path of every leaf setting that differs between a and b
+func diff(a, b reflect.Value, prefix string, paths *[]string) {
+	for i := 0; i < a.NumField(); i++ {
+		field := a.Type().Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+
+		if field.Type.Kind() == reflect.Struct {
+			diff(a.Field(i), b.Field(i), name, paths)
+			continue
+		}
+		if !reflect.DeepEqual(a.Field(i).Interface(), b.Field(i).Interface()) {
+			*paths = append(*paths, name)
+		}
+	}
+}
+
+func isReloadable(path string) bool {
+	for _, prefix := range Reloadable {
+		if path == prefix || strings.HasPrefix(path, prefix+".") {
+			return true
+		}
+	}
+	return false
+}
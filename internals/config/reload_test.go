@@ -0,0 +1,130 @@
+package config
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestChanges(t *testing.T) {
+	for _, tc := range []struct {
+		name              string
+		change            func(*Config)
+		reloadable, fixed []string
+	}{
+		{"nothing", func(c *Config) {}, nil, nil},
+		{"cache TTL", func(c *Config) { c.CacheConfig.TTL = time.Minute }, []string{"cache.ttl"}, nil},
+		{"rate limit groups", func(c *Config) {
+			c.RateLimitConfig.Groups = Map[RateLimitRule]{"read": {Requests: 5, Per: time.Minute}}
+		}, []string{"rate_limit.groups"}, nil},
+		{"CORS origins", func(c *Config) { c.HTTPConfig.CORS.AllowedOrigins = []string{"https://admin.example.com"} }, []string{"http.cors.allowed_origins"}, nil},
+		{"log level", func(c *Config) { c.LoggingConfig.Level = "warn" }, []string{"logging.level"}, nil},
+		{"HTTP port", func(c *Config) { c.HTTPConfig.Port = 9000 }, nil, []string{"http.port"}},
+		{"TLS certificate", func(c *Config) { c.HTTPConfig.TLS.CertFile = "server.crt" }, nil, []string{"http.tls.cert_file"}},
+		//? Only the cache's TTLs reload; switching it on needs the store rebuilt
+		{"cache switched on", func(c *Config) { c.CacheConfig.Enabled = true }, nil, []string{"cache.enabled"}},
+		{"log file", func(c *Config) { c.LoggingConfig.File = "other.log" }, nil, []string{"logging.file"}},
+		{"both", func(c *Config) {
+			c.CacheConfig.ListTTL = time.Second
+			c.DBPath = "other.db"
+		}, []string{"cache.list_ttl"}, []string{"db_path"}},
+	} {
+		current := testConfig(t)
+		next := *current
+		tc.change(&next)
+
+		reloadable, fixed := current.Changes(&next)
+		if !slices.Equal(reloadable, tc.reloadable) || !slices.Equal(fixed, tc.fixed) {
+			t.Errorf("%s: got %q and %q, want %q and %q", tc.name, reloadable, fixed, tc.reloadable, tc.fixed)
+		}
+	}
+}
+
+// reloads runs Watch on path and passes on what it reloads, until the test ends
+func reloads(t *testing.T, path string) chan error {
+	t.Helper()
+	got := make(chan error, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	go func() {
+		defer close(done)
+		Watch(ctx, path, 5*time.Millisecond, func(cfg *Config, err error) {
+			if err == nil && cfg.LoggingConfig.Level != "warn" {
+				t.Errorf("reloaded logging.level %q, want warn", cfg.LoggingConfig.Level)
+			}
+			got <- err
+		})
+	}()
+	return got
+}
+
+func writeConfig(t *testing.T, path, data string, at time.Time) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, at, at); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWatchRereadsChangedFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	start := time.Now().Add(-time.Hour)
+	writeConfig(t, path, "env: test\n", start)
+	got := reloads(t, path)
+
+	//? An untouched file is not re-read on every tick
+	select {
+	case err := <-got:
+		t.Fatalf("reloaded an unchanged file: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	writeConfig(t, path, "env: test\nlogging:\n  level: warn\n", start.Add(time.Minute))
+	if err := <-got; err != nil {
+		t.Fatalf("valid change: %v", err)
+	}
+
+	writeConfig(t, path, "env: test\nhttp:\n  port: 70000\n", start.Add(2*time.Minute))
+	if err := <-got; err == nil {
+		t.Fatal("an invalid file was passed on as a configuration")
+	}
+}
+
+func TestWatchReloadsOnSIGHUP(t *testing.T) {
+	//? Keep SIGHUP from ending the test binary should Watch not be listening yet
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig(t, path, "env: test\nlogging:\n  level: warn\n", time.Now().Add(-time.Hour))
+	got := reloads(t, path)
+
+	//? The file never changes, so only the signal can trigger the reload
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); {
+		if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case err := <-got:
+			if err != nil {
+				t.Fatal(err)
+			}
+			return
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+	t.Fatal("SIGHUP did not reload the file")
+}
@@ -0,0 +1,174 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// LogLevels are the accepted logging.level values, least severe first
+var LogLevels = []string{"debug", "info", "warn", "error"}
+
+// Problems lists everything wrong with a configuration, so it can all be fixed in one go
+type Problems []string
+
+func (p Problems) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(p, "\n  - ")
+}
+
+func (p *Problems) add(format string, args ...any) {
+	*p = append(*p, fmt.Sprintf(format, args...))
+}
+
+// Validate checks the settings that would otherwise fail late or confusingly at startup. It does
+// not touch the filesystem; CheckPaths does that once the server is about to start.
+func (c *Config) Validate() error {
+	var p Problems
+
+	p.duration("reload_interval", c.ReloadInterval)
+
+	p.port("http.port", c.HTTPConfig.Port, false)
+	p.port("grpc.port", c.GRPCConfig.Port, true)
+	p.port("http.tls.redirect_port", c.HTTPConfig.TLS.RedirectPort, true)
+	if c.GRPCConfig.Port != 0 && c.GRPCConfig.Port == c.HTTPConfig.Port {
+		p.add("grpc.port: %d is already used by http.port", c.GRPCConfig.Port)
+	}
+	if c.HTTPConfig.TLS.RedirectPort != 0 && c.HTTPConfig.TLS.RedirectPort == c.HTTPConfig.Port {
+		p.add("http.tls.redirect_port: %d is already used by http.port", c.HTTPConfig.TLS.RedirectPort)
+	}
+
+	if !slices.Contains(LogLevels, c.LoggingConfig.Level) {
+		p.add("logging.level: unknown level %q, want one of %s", c.LoggingConfig.Level, strings.Join(LogLevels, ", "))
+	}
+	if c.LoggingConfig.File == "" {
+		p.add("logging.file: must be set")
+	}
+
+	p.positive("http.max_body_size", c.HTTPConfig.MaxBodySize)
+	for route, limit := range c.HTTPConfig.BodyLimits {
+		p.positive(fmt.Sprintf("http.body_limits[%q]", route), limit)
+	}
+	p.positive("http.max_header_bytes", int64(c.HTTPConfig.MaxHeaderBytes))
+	if c.HTTPConfig.MaxConns < 0 {
+		p.add("http.max_conns: must not be negative, use 0 for no limit")
+	}
+	p.duration("http.read_header_timeout", c.HTTPConfig.ReadHeaderTimeout)
+	p.duration("http.read_timeout", c.HTTPConfig.ReadTimeout)
+	p.duration("http.write_timeout", c.HTTPConfig.WriteTimeout)
+	p.duration("http.idle_timeout", c.HTTPConfig.IdleTimeout)
+	p.duration("http.shutdown_grace", c.HTTPConfig.ShutdownGrace)
+	if c.HTTPConfig.ShutdownDelay < 0 {
+		p.add("http.shutdown_delay: must not be negative, use 0 for no delay")
+	}
+
+	tls := c.HTTPConfig.TLS
+	if tls.Enabled() && (tls.CertFile == "" || tls.KeyFile == "") {
+		p.add("http.tls: cert_file and key_file must be set together")
+	}
+	if tls.ClientCAFile != "" && !tls.Enabled() {
+		p.add("http.tls.client_ca_file: needs cert_file and key_file")
+	}
+	if tls.Enabled() {
+		p.duration("http.tls.reload_interval", tls.ReloadInterval)
+	}
+
+	for group, rule := range c.RateLimitConfig.Groups {
+		if rule.Requests <= 0 || rule.Per <= 0 {
+			p.add("rate_limit.groups.%s: requests and per must be positive", group)
+		}
+		if rule.Burst < 0 {
+			p.add("rate_limit.groups.%s: burst must not be negative", group)
+		}
+	}
+
+	if c.CacheConfig.Enabled {
+		p.positive("cache.size", int64(c.CacheConfig.Size))
+		p.duration("cache.ttl", c.CacheConfig.TTL)
+		p.duration("cache.list_ttl", c.CacheConfig.ListTTL)
+	}
+
+	p.positive("webhooks.max_attempts", int64(c.WebhookConfig.MaxAttempts))
+	p.positive("outbox.batch_size", int64(c.OutboxConfig.BatchSize))
+	p.duration("outbox.poll_interval", c.OutboxConfig.PollInterval)
+	p.duration("session.ttl", c.SessionConfig.TTL)
+	p.duration("idempotency.ttl", c.IdempotencyConfig.TTL)
+
+	if len(p) > 0 {
+		return p
+	}
+	return nil
+}
+
+func (p *Problems) port(name string, port int, optional bool) {
+	if optional && port == 0 {
+		return
+	}
+	if port < 1 || port > 65535 {
+		p.add("%s: %d is outside 1-65535", name, port)
+	}
+}
+
+func (p *Problems) positive(name string, n int64) {
+	if n <= 0 {
+		p.add("%s: must be positive, got %d", name, n)
+	}
+}
+
+func (p *Problems) duration(name string, d time.Duration) {
+	if d <= 0 {
+		p.add("%s: must be positive, got %s", name, d)
+	}
+}
+
+// CheckPaths checks that the database and log files can be written. It probes the filesystem, so
+// it runs once before the server starts rather than on every Read: "config print" and reloads skip it.
+func (c *Config) CheckPaths() error {
+	var p Problems
+
+	//? In-memory databases have no directory; DSN parameters follow the file name
+	dbPath, _, _ := strings.Cut(strings.TrimPrefix(c.DBPath, "file:"), "?")
+	if dbPath != "" && dbPath != ":memory:" {
+		if err := writableDir(filepath.Dir(dbPath), false); err != nil {
+			p.add("db_path: %v", err)
+		}
+	}
+
+	//? The logger creates its directory, so only the nearest existing parent has to be writable
+	if err := writableDir(filepath.Dir(c.LoggingConfig.File), true); err != nil {
+		p.add("logging.file: %v", err)
+	}
+
+	if len(p) > 0 {
+		return p
+	}
+	return nil
+}
+
+// writableDir checks that files can be created in dir. With missingOK, a directory that does not
+// exist yet passes when its nearest existing parent is writable.
+func writableDir(dir string, missingOK bool) error {
+	info, err := os.Stat(dir)
+	for missingOK && errors.Is(err, fs.ErrNotExist) && filepath.Dir(dir) != dir {
+		dir = filepath.Dir(dir)
+		info, err = os.Stat(dir)
+	}
+	if err != nil {
+		return fmt.Errorf("directory %s: %w", dir, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+
+	//? Permission bits do not tell the whole story (read-only mounts, ACLs), so try a write
+	f, err := os.CreateTemp(dir, ".movies-write-check-*")
+	if err != nil {
+		return fmt.Errorf("directory %s is not writable: %w", dir, err)
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
@@ -0,0 +1,137 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testConfig reads a minimal file, so every default is filled in as it would be at startup
+func testConfig(t *testing.T) *Config {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("env: test\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+func TestValidate(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		change func(*Config)
+		want   string
+	}{
+		{"defaults", func(c *Config) {}, ""},
+		{"port zero", func(c *Config) { c.HTTPConfig.Port = 0 }, "http.port: 0 is outside 1-65535"},
+		{"port too high", func(c *Config) { c.HTTPConfig.Port = 70000 }, "http.port: 70000 is outside 1-65535"},
+		{"gRPC off", func(c *Config) { c.GRPCConfig.Port = 0 }, ""},
+		{"gRPC on the HTTP port", func(c *Config) { c.GRPCConfig.Port = c.HTTPConfig.Port }, "grpc.port: 8080 is already used by http.port"},
+		{"redirect on the HTTP port", func(c *Config) { c.HTTPConfig.TLS.RedirectPort = c.HTTPConfig.Port }, "http.tls.redirect_port: 8080 is already used"},
+		{"zero duration", func(c *Config) { c.HTTPConfig.ReadTimeout = 0 }, "http.read_timeout: must be positive, got 0s"},
+		{"negative duration", func(c *Config) { c.SessionConfig.TTL = -time.Minute }, "session.ttl: must be positive, got -1m0s"},
+		{"negative shutdown delay", func(c *Config) { c.HTTPConfig.ShutdownDelay = -time.Second }, "http.shutdown_delay: must not be negative"},
+		{"unknown log level", func(c *Config) { c.LoggingConfig.Level = "verbose" }, `logging.level: unknown level "verbose"`},
+		{"no log file", func(c *Config) { c.LoggingConfig.File = "" }, "logging.file: must be set"},
+		{"no connection limit", func(c *Config) { c.HTTPConfig.MaxConns = 0 }, ""},
+		{"negative max_conns", func(c *Config) { c.HTTPConfig.MaxConns = -1 }, "http.max_conns: must not be negative"},
+		{"zero body limit", func(c *Config) { c.HTTPConfig.BodyLimits = Map[int64]{"POST /api/v1/movies": 0} }, `http.body_limits["POST /api/v1/movies"]: must be positive`},
+		{"certificate without key", func(c *Config) { c.HTTPConfig.TLS.CertFile = "server.crt" }, "http.tls: cert_file and key_file must be set together"},
+		{"key without certificate", func(c *Config) { c.HTTPConfig.TLS.KeyFile = "server.key" }, "http.tls: cert_file and key_file must be set together"},
+		{"client CA without TLS", func(c *Config) { c.HTTPConfig.TLS.ClientCAFile = "ca.crt" }, "http.tls.client_ca_file: needs cert_file and key_file"},
+		{"rate limit without window", func(c *Config) { c.RateLimitConfig.Groups = Map[RateLimitRule]{"read": {Requests: 10}} }, "rate_limit.groups.read: requests and per must be positive"},
+		//? Cache settings only matter once the cache is on
+		{"cache off", func(c *Config) { c.CacheConfig.TTL = 0 }, ""},
+		{"cache on", func(c *Config) { c.CacheConfig.Enabled, c.CacheConfig.TTL = true, 0 }, "cache.ttl: must be positive"},
+	} {
+		cfg := testConfig(t)
+		tc.change(cfg)
+		err := cfg.Validate()
+
+		if tc.want == "" {
+			if err != nil {
+				t.Errorf("%s: %v", tc.name, err)
+			}
+			continue
+		}
+		var problems Problems
+		if !errors.As(err, &problems) || len(problems) != 1 || !strings.HasPrefix(problems[0], tc.want) {
+			t.Errorf("%s: got %v, want one problem starting %q", tc.name, err, tc.want)
+		}
+	}
+}
+
+func TestValidateReportsEveryProblem(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.HTTPConfig.Port = 70000
+	cfg.LoggingConfig.Level = "verbose"
+	cfg.HTTPConfig.MaxConns = -1
+
+	var problems Problems
+	if err := cfg.Validate(); !errors.As(err, &problems) || len(problems) != 3 {
+		t.Fatalf("got %v, want three problems", err)
+	}
+}
+
+func TestCheckPaths(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name, dbPath, logFile, want string
+	}{
+		{"writable", filepath.Join(dir, "movies.db"), filepath.Join(dir, "app.log"), ""},
+		{"in-memory database", "file::memory:?cache=shared", filepath.Join(dir, "app.log"), ""},
+		{"DSN parameters", "file:" + filepath.Join(dir, "movies.db") + "?_fk=1", filepath.Join(dir, "app.log"), ""},
+		{"missing database directory", filepath.Join(dir, "missing", "movies.db"), filepath.Join(dir, "app.log"), "db_path: directory " + filepath.Join(dir, "missing")},
+		{"database under a file", filepath.Join(file, "movies.db"), filepath.Join(dir, "app.log"), "db_path: " + file + " is not a directory"},
+		//? The logger creates its directory, so a missing one is fine under a writable parent
+		{"missing log directory", filepath.Join(dir, "movies.db"), filepath.Join(dir, "logs", "today", "app.log"), ""},
+		{"log under a file", filepath.Join(dir, "movies.db"), filepath.Join(file, "logs", "app.log"), "logging.file: directory " + filepath.Join(file, "logs")},
+	} {
+		cfg := testConfig(t)
+		cfg.DBPath, cfg.LoggingConfig.File = tc.dbPath, tc.logFile
+		err := cfg.CheckPaths()
+
+		if tc.want == "" {
+			if err != nil {
+				t.Errorf("%s: %v", tc.name, err)
+			}
+			continue
+		}
+		var problems Problems
+		if !errors.As(err, &problems) || len(problems) != 1 || !strings.HasPrefix(problems[0], tc.want) {
+			t.Errorf("%s: got %v, want one problem starting %q", tc.name, err, tc.want)
+		}
+	}
+
+	//? The probe cleans up after itself and never creates the log directory
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Fatalf("checks left %d entries behind", len(entries))
+	}
+}
+
+func TestReadSkipsFilesystemChecks(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	data := "env: test\ndb_path: " + filepath.Join(dir, "missing", "movies.db") + "\n"
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	//? config print and reloads read the file too, and must not probe or fail on paths
+	if _, err := Read(path); err != nil {
+		t.Fatalf("Read checked the database directory: %v", err)
+	}
+}
@@ -1,11 +1,169 @@
 package db
 
-import "github/MahfujulSagor/movies_crud/internals/types"
+import (
+	"context"
+	"errors"
+	"github/MahfujulSagor/movies_crud/internals/types"
+	"time"
+)
+
+// Storage errors. Implementations wrap them with context, so compare using errors.Is.
+var (
+	ErrNotFound   = errors.New("not found")
+	ErrConflict   = errors.New("conflict")
+	ErrConstraint = errors.New("constraint violation")
+)
 
 type DB interface {
-	CreateMovie(movie *types.Movie) (int64, error)
-	GetMovieByID(id int64) (*types.Movie, error)
-	GetMovieList(limit int, offset int) ([]*types.Movie, error)
-	UpdateMovie(id int64, movie *types.Movie) (int64, error)
-	DeleteMovieByID(id int64) (int64, error)
+	CreateMovie(ctx context.Context, movie *types.Movie) (int64, error)
+	GetMovieByID(ctx context.Context, id int64) (*types.Movie, error)
+	GetMovieList(ctx context.Context, limit int, offset int) ([]*types.Movie, error)
+	UpdateMovie(ctx context.Context, id int64, movie *types.Movie) (int64, error)
+	DeleteMovieByID(ctx context.Context, id int64) (int64, error)
+	// MoviesLastModified is when any movie was last created, updated or deleted; zero for an untouched catalogue
+	MoviesLastModified(ctx context.Context) (time.Time, error)
+}
+
+// MovieFilter narrows a movie search; zero-valued fields match every movie
+type MovieFilter struct {
+	// TitleContains and DirectorContains match case-insensitive substrings
+	TitleContains    string
+	DirectorContains string
+	MinRating        int
+	MaxRating        int
+	ReleaseYear      int
+	DirectorIDs      []int64
+	CastIDs          []int64
+	// PerGroupLimit caps the movies returned for each director in DirectorIDs, or else each cast in
+	// CastIDs, so a batched lookup is bounded per key; 0 leaves them uncapped
+	PerGroupLimit int
+}
+
+// MovieFinder supports read paths that filter or batch lookups, such as GraphQL
+type MovieFinder interface {
+	// FindMovies returns matching movies ordered by ID; a limit of 0 returns all of them
+	FindMovies(ctx context.Context, filter MovieFilter, limit int, offset int) ([]*types.Movie, error)
+	GetDirectorsByIDs(ctx context.Context, ids []int64) ([]*types.Director, error)
+}
+
+type APIKeyStore interface {
+	CreateAPIKey(key *types.APIKey, hash string) (int64, error)
+	GetAPIKeyByHash(hash string) (*types.APIKey, error)
+	ListAPIKeys() ([]*types.APIKey, error)
+	RevokeAPIKey(id int64) (int64, error)
+}
+
+type UserStore interface {
+	CreateUser(user *types.User, passwordHash string) (int64, error)
+	// GetUserByUsername also returns the stored password hash for verification
+	GetUserByUsername(username string) (*types.User, string, error)
+	ListUsers() ([]*types.User, error)
+	CreateSession(userID int64, tokenHash string, expiresAt time.Time) error
+	// GetUserBySession returns the owner of an unexpired session, or ErrNotFound
+	GetUserBySession(tokenHash string) (*types.User, error)
+	DeleteSession(tokenHash string) error
+}
+
+// Webhook delivery states
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	// DeliveryDead means every retry failed; only a manual redelivery sends it again
+	DeliveryDead = "dead"
+)
+
+type WebhookStore interface {
+	CreateWebhook(ctx context.Context, hook *types.Webhook) (int64, error)
+	ListWebhooks(ctx context.Context) ([]*types.Webhook, error)
+	DeleteWebhook(ctx context.Context, id int64) (int64, error)
+	// WebhooksForEvent returns subscribers of eventType including their secrets
+	WebhooksForEvent(ctx context.Context, eventType string) ([]*types.Webhook, error)
+	// GetWebhook includes the secret, for signing deliveries
+	GetWebhook(ctx context.Context, id int64) (*types.Webhook, error)
+
+	// EnqueueDelivery queues a pending delivery. One whose webhook already has a delivery for the same
+	// EventID is not queued again; the existing delivery's ID is returned.
+	EnqueueDelivery(ctx context.Context, delivery *types.WebhookDelivery) (int64, error)
+	// DueDeliveries returns pending deliveries whose next attempt is at or before now
+	DueDeliveries(ctx context.Context, now time.Time, limit int) ([]*types.WebhookDelivery, error)
+	// UpdateDelivery stores the outcome of an attempt
+	UpdateDelivery(ctx context.Context, delivery *types.WebhookDelivery) error
+	GetDelivery(ctx context.Context, id int64) (*types.WebhookDelivery, error)
+	ListDeliveries(ctx context.Context, webhookID int64, limit int, offset int) ([]*types.WebhookDelivery, error)
+}
+
+// OutboxMessage is a domain event recorded in the same transaction as the change it describes
+type OutboxMessage struct {
+	ID            int64
+	AggregateType string
+	AggregateID   int64
+	EventType     string
+	// Payload is the JSON-encoded event
+	Payload       string
+	CreatedAt     time.Time
+	Attempts      int
+	LastError     string
+	NextAttemptAt time.Time
+}
+
+// OutboxStore is read by the relay that forwards committed events to their sinks
+type OutboxStore interface {
+	// PendingOutbox returns unrelayed messages due by now in commit order. A message is left out while
+	// an earlier one for the same aggregate waits to retry, so aggregates are still relayed in order.
+	PendingOutbox(ctx context.Context, now time.Time, limit int) ([]*OutboxMessage, error)
+	MarkRelayed(ctx context.Context, id int64, at time.Time) error
+	// MarkOutboxFailed records a failed relay and when to try again
+	MarkOutboxFailed(ctx context.Context, id int64, reason string, next time.Time) error
+	// PurgeOutbox deletes messages relayed before the cutoff and returns how many were removed
+	PurgeOutbox(ctx context.Context, before time.Time) (int64, error)
+}
+
+// IdempotencyRecord is the stored outcome of a request made with an Idempotency-Key
+type IdempotencyRecord struct {
+	// Key is scoped to the caller, so two clients can use the same header value
+	Key         string
+	Fingerprint string
+	// Status is 0 while the original request is still running
+	Status    int
+	Header    map[string]string
+	Body      []byte
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+type IdempotencyStore interface {
+	// ReserveIdempotencyKey claims rec.Key for a new request and returns nil, or returns the unexpired record already holding it
+	ReserveIdempotencyKey(ctx context.Context, rec *IdempotencyRecord) (*IdempotencyRecord, error)
+	// CompleteIdempotencyKey stores the response to replay for the reserved key
+	CompleteIdempotencyKey(ctx context.Context, rec *IdempotencyRecord) error
+	// ReleaseIdempotencyKey drops a reservation so the request can be retried from scratch
+	ReleaseIdempotencyKey(ctx context.Context, key string) error
+}
+
+// Stats describes the on-disk state of the database
+type Stats struct {
+	Path        string `json:"path"`
+	FileSize    int64  `json:"file_size"`
+	JournalMode string `json:"journal_mode"`
+	WALSize     int64  `json:"wal_size"`
+}
+
+type HealthChecker interface {
+	Ping(ctx context.Context) error
+	SchemaReady(ctx context.Context) error
+	Stats(ctx context.Context) (*Stats, error)
+}
+
+type actorKey struct{}
+
+// WithActor records who is performing the writes made with ctx, for the audit log
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+func ActorFromContext(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	return "system"
 }
@@ -0,0 +1,108 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"github/MahfujulSagor/movies_crud/internals/db"
+	"github/MahfujulSagor/movies_crud/internals/types"
+	"strings"
+	"time"
+)
+
+func (s *SQLite) CreateAPIKey(key *types.APIKey, hash string) (int64, error) {
+	res, err := s.DB.Exec("INSERT INTO api_keys(name, prefix, key_hash, scopes, created_at) VALUES (?, ?, ?, ?, ?)",
+		key.Name, key.Prefix, hash, strings.Join(key.Scopes, " "), key.CreatedAt)
+	if err != nil {
+		return 0, translateError(err)
+	}
+
+	return res.LastInsertId()
+}
+
+func (s *SQLite) GetAPIKeyByHash(hash string) (*types.APIKey, error) {
+	row := s.DB.QueryRow(`
+		SELECT id, name, prefix, scopes, created_at, revoked_at
+		FROM api_keys
+		WHERE key_hash = ?
+	`, hash)
+
+	key, err := scanAPIKey(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("%w: API key", db.ErrNotFound)
+		}
+
+		return nil, err
+	}
+
+	return key, nil
+}
+
+func (s *SQLite) ListAPIKeys() ([]*types.APIKey, error) {
+	rows, err := s.DB.Query(`
+		SELECT id, name, prefix, scopes, created_at, revoked_at
+		FROM api_keys
+		ORDER BY id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []*types.APIKey
+
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+func (s *SQLite) RevokeAPIKey(id int64) (int64, error) {
+	res, err := s.DB.Exec("UPDATE api_keys SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL", time.Now().UTC(), id)
+	if err != nil {
+		return 0, err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	if rowsAffected == 0 {
+		return 0, fmt.Errorf("%w: active API key %d", db.ErrNotFound, id)
+	}
+
+	return id, nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanAPIKey(row scanner) (*types.APIKey, error) {
+	var key types.APIKey
+	var scopes string
+	var revokedAt sql.NullTime
+
+	err := row.Scan(&key.ID, &key.Name, &key.Prefix, &scopes, &key.CreatedAt, &revokedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	key.Scopes = strings.Fields(scopes)
+	if revokedAt.Valid {
+		key.RevokedAt = &revokedAt.Time
+	}
+
+	return &key, nil
+}
@@ -0,0 +1,24 @@
+package sqlite
+
+import (
+	"errors"
+	"fmt"
+	"github/MahfujulSagor/movies_crud/internals/db"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// translateError maps SQLite constraint failures to the storage sentinel errors
+func translateError(err error) error {
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) || sqliteErr.Code != sqlite3.ErrConstraint {
+		return err
+	}
+
+	switch sqliteErr.ExtendedCode {
+	case sqlite3.ErrConstraintUnique, sqlite3.ErrConstraintPrimaryKey:
+		return fmt.Errorf("%w: %v", db.ErrConflict, err)
+	default:
+		return fmt.Errorf("%w: %v", db.ErrConstraint, err)
+	}
+}
@@ -0,0 +1,82 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"github/MahfujulSagor/movies_crud/internals/db"
+	"github/MahfujulSagor/movies_crud/internals/types"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTranslateError(t *testing.T) {
+	store := newTestDB(t)
+	if _, err := store.DB.Exec("INSERT INTO directors(id, name, age) VALUES (1, 'Mann', 81)"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name     string
+		stmt     string
+		sentinel error
+		driver   string
+	}{
+		{"unique", "INSERT INTO directors(name, age) VALUES ('Mann', 80)", db.ErrConflict, "UNIQUE constraint failed"},
+		{"primary key", "INSERT INTO directors(id, name, age) VALUES (1, 'Scott', 87)", db.ErrConflict, "UNIQUE constraint failed"},
+		{"foreign key", "INSERT INTO movies(title, rating, director_id, cast_id) VALUES ('Heat', 8, 99, 99)", db.ErrConstraint, "FOREIGN KEY constraint failed"},
+		{"not null", "INSERT INTO casts(actor) VALUES ('Pacino')", db.ErrConstraint, "NOT NULL constraint failed"},
+	} {
+		_, raw := store.DB.Exec(tc.stmt)
+		err := translateError(raw)
+		if !errors.Is(err, tc.sentinel) {
+			t.Errorf("%s: %v is not %v", tc.name, err, tc.sentinel)
+		}
+		//? The driver text stays in the message for the logs
+		if !strings.Contains(err.Error(), tc.driver) {
+			t.Errorf("%s: %q lost the driver error", tc.name, err)
+		}
+	}
+
+	_, raw := store.DB.Exec("INSERT INTO nowhere VALUES (1)")
+	if err := translateError(raw); err != raw || errors.Is(err, db.ErrConflict) || errors.Is(err, db.ErrConstraint) {
+		t.Errorf("non-constraint error became %v", err)
+	}
+	if translateError(nil) != nil {
+		t.Error("nil error was translated")
+	}
+}
+
+func TestStoreReturnsSentinels(t *testing.T) {
+	ctx := context.Background()
+	store := newTestDB(t)
+
+	if _, err := store.CreateMovie(ctx, testMovie("Heat")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.CreateUser(&types.User{Username: "ana", Role: "viewer", CreatedAt: time.Now()}, "hash"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name     string
+		call     func() error
+		sentinel error
+	}{
+		{"duplicate movie", func() error { _, err := store.CreateMovie(ctx, testMovie("Heat")); return err }, db.ErrConflict},
+		{"duplicate username", func() error {
+			_, err := store.CreateUser(&types.User{Username: "ana", Role: "admin", CreatedAt: time.Now()}, "hash")
+			return err
+		}, db.ErrConflict},
+		{"session for a missing user", func() error { return store.CreateSession(99, "token", time.Now().Add(time.Hour)) }, db.ErrConstraint},
+		{"get missing movie", func() error { _, err := store.GetMovieByID(ctx, 99); return err }, db.ErrNotFound},
+		{"update missing movie", func() error { _, err := store.UpdateMovie(ctx, 99, testMovie("Ronin")); return err }, db.ErrNotFound},
+		{"delete missing movie", func() error { _, err := store.DeleteMovieByID(ctx, 99); return err }, db.ErrNotFound},
+		{"missing user", func() error { _, _, err := store.GetUserByUsername("bob"); return err }, db.ErrNotFound},
+		{"revoke missing key", func() error { _, err := store.RevokeAPIKey(99); return err }, db.ErrNotFound},
+	} {
+		if err := tc.call(); !errors.Is(err, tc.sentinel) {
+			t.Errorf("%s: got %v, want %v", tc.name, err, tc.sentinel)
+		}
+	}
+}
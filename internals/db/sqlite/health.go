@@ -0,0 +1,55 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"github/MahfujulSagor/movies_crud/internals/db"
+	"os"
+	"strings"
+)
+
+// Tables created by New; readiness requires all of them
+var requiredTables = []string{"directors", "casts", "movies", "api_keys", "users", "sessions", "audit_log", "webhooks", "webhook_deliveries", "outbox", "idempotency_keys"}
+
+func (s *SQLite) Ping(ctx context.Context) error {
+	return s.DB.PingContext(ctx)
+}
+
+func (s *SQLite) SchemaReady(ctx context.Context) error {
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(requiredTables)), ",")
+	args := make([]any, len(requiredTables))
+	for i, t := range requiredTables {
+		args[i] = t
+	}
+
+	var count int
+	row := s.DB.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name IN ("+placeholders+")", args...)
+	if err := row.Scan(&count); err != nil {
+		return err
+	}
+
+	if count != len(requiredTables) {
+		return fmt.Errorf("schema incomplete: %d of %d tables present", count, len(requiredTables))
+	}
+
+	return nil
+}
+
+func (s *SQLite) Stats(ctx context.Context) (*db.Stats, error) {
+	stats := &db.Stats{Path: s.Path}
+
+	if err := s.DB.QueryRowContext(ctx, "PRAGMA journal_mode").Scan(&stats.JournalMode); err != nil {
+		return nil, err
+	}
+
+	//? In-memory databases have no files on disk
+	if fi, err := os.Stat(s.Path); err == nil {
+		stats.FileSize = fi.Size()
+	}
+	if fi, err := os.Stat(s.Path + "-wal"); err == nil {
+		stats.WALSize = fi.Size()
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,66 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"github/MahfujulSagor/movies_crud/internals/db"
+	"time"
+)
+
+func (s *SQLite) ReserveIdempotencyKey(ctx context.Context, rec *db.IdempotencyRecord) (*db.IdempotencyRecord, error) {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	//? Expired keys are dropped here rather than by a separate sweeper
+	if _, err := tx.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE expires_at < ?", time.Now().UTC()); err != nil {
+		return nil, err
+	}
+
+	var existing db.IdempotencyRecord
+	var header sql.NullString
+	err = tx.QueryRowContext(ctx, `
+		SELECT key, fingerprint, status, header, body, created_at, expires_at
+		FROM idempotency_keys
+		WHERE key = ?
+	`, rec.Key).Scan(&existing.Key, &existing.Fingerprint, &existing.Status, &header, &existing.Body,
+		&existing.CreatedAt, &existing.ExpiresAt)
+	if err == nil {
+		if header.Valid {
+			if err := json.Unmarshal([]byte(header.String), &existing.Header); err != nil {
+				return nil, err
+			}
+		}
+		return &existing, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO idempotency_keys(key, fingerprint, created_at, expires_at) VALUES (?, ?, ?, ?)",
+		rec.Key, rec.Fingerprint, rec.CreatedAt.UTC(), rec.ExpiresAt.UTC())
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return nil, translateError(tx.Commit())
+}
+
+func (s *SQLite) CompleteIdempotencyKey(ctx context.Context, rec *db.IdempotencyRecord) error {
+	header, err := json.Marshal(rec.Header)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.DB.ExecContext(ctx, "UPDATE idempotency_keys SET status = ?, header = ?, body = ? WHERE key = ?",
+		rec.Status, string(header), rec.Body, rec.Key)
+	return err
+}
+
+func (s *SQLite) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	_, err := s.DB.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE key = ?", key)
+	return err
+}
@@ -0,0 +1,105 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"github/MahfujulSagor/movies_crud/internals/db"
+	"github/MahfujulSagor/movies_crud/internals/events"
+	"github/MahfujulSagor/movies_crud/internals/types"
+	"time"
+)
+
+// recordMovieEvent writes a movie change to the outbox in the caller's transaction, so the event
+// exists if and only if the change commits. Deletions pass the movie as it was before the delete.
+func recordMovieEvent(ctx context.Context, tx *sql.Tx, eventType string, id int64, before *types.Movie) error {
+	movie := before
+	if movie == nil {
+		//? Reload inside the transaction to include the IDs assigned to the director and cast
+		var err error
+		movie, err = loadMovie(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+	}
+
+	now := time.Now().UTC()
+	e := events.Event{Type: eventType, MovieID: id, Movie: movie, Actor: db.ActorFromContext(ctx), Time: now}
+	if movie.Director != nil {
+		e.DirectorID = movie.Director.ID
+	}
+	//? Deletions only carry IDs
+	if eventType == events.MovieDeleted {
+		e.Movie = nil
+	}
+
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO outbox(aggregate_type, aggregate_id, event_type, payload, created_at, next_attempt_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, "movie", id, eventType, string(payload), now, now)
+	return err
+}
+
+func (s *SQLite) PendingOutbox(ctx context.Context, now time.Time, limit int) ([]*db.OutboxMessage, error) {
+	//? Messages in backoff are skipped so they cannot fill every batch and starve newer due ones
+	rows, err := s.DB.QueryContext(ctx, `
+		SELECT id, aggregate_type, aggregate_id, event_type, payload, created_at, attempts, COALESCE(last_error, ''), next_attempt_at
+		FROM outbox AS o
+		WHERE relayed_at IS NULL AND next_attempt_at <= ?
+			AND NOT EXISTS (
+				SELECT 1 FROM outbox AS earlier
+				WHERE earlier.relayed_at IS NULL AND earlier.next_attempt_at > ?
+					AND earlier.aggregate_type = o.aggregate_type AND earlier.aggregate_id = o.aggregate_id
+					AND earlier.id < o.id
+			)
+		ORDER BY id
+		LIMIT ?
+	`, now.UTC(), now.UTC(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []*db.OutboxMessage
+
+	for rows.Next() {
+		var m db.OutboxMessage
+		err := rows.Scan(&m.ID, &m.AggregateType, &m.AggregateID, &m.EventType, &m.Payload,
+			&m.CreatedAt, &m.Attempts, &m.LastError, &m.NextAttemptAt)
+		if err != nil {
+			return nil, err
+		}
+
+		messages = append(messages, &m)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+func (s *SQLite) MarkRelayed(ctx context.Context, id int64, at time.Time) error {
+	_, err := s.DB.ExecContext(ctx, "UPDATE outbox SET relayed_at = ?, last_error = NULL WHERE id = ?", at.UTC(), id)
+	return err
+}
+
+func (s *SQLite) MarkOutboxFailed(ctx context.Context, id int64, reason string, next time.Time) error {
+	_, err := s.DB.ExecContext(ctx, "UPDATE outbox SET attempts = attempts + 1, last_error = ?, next_attempt_at = ? WHERE id = ?",
+		reason, next.UTC(), id)
+	return err
+}
+
+func (s *SQLite) PurgeOutbox(ctx context.Context, before time.Time) (int64, error) {
+	res, err := s.DB.ExecContext(ctx, "DELETE FROM outbox WHERE relayed_at IS NOT NULL AND relayed_at < ?", before.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
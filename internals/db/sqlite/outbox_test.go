@@ -0,0 +1,73 @@
+package sqlite
+
+import (
+	"context"
+	"github/MahfujulSagor/movies_crud/internals/config"
+	"github/MahfujulSagor/movies_crud/internals/types"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestDB(t *testing.T) *SQLite {
+	t.Helper()
+	store, err := New(&config.Config{DBPath: filepath.Join(t.TempDir(), "movies.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func testMovie(title string) *types.Movie {
+	return &types.Movie{
+		Title:    title,
+		Rating:   7,
+		Director: &types.Director{Name: "Director of " + title, Age: 50},
+		Cast:     &types.Cast{Actor: "Actor", Actress: "Actress"},
+	}
+}
+
+func TestPendingOutboxSkipsMessagesInBackoff(t *testing.T) {
+	ctx := context.Background()
+	store := newTestDB(t)
+
+	first, err := store.CreateMovie(ctx, testMovie("First"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().UTC()
+	pending, err := store.PendingOutbox(ctx, now, 10)
+	if err != nil || len(pending) != 1 {
+		t.Fatalf("PendingOutbox = %d messages, %v; want 1", len(pending), err)
+	}
+	if err := store.MarkOutboxFailed(ctx, pending[0].ID, "sink down", now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	//? A later message for the same movie waits behind the failed one; other movies go ahead
+	if _, err := store.UpdateMovie(ctx, first, testMovie("First again")); err != nil {
+		t.Fatal(err)
+	}
+	second, err := store.CreateMovie(ctx, testMovie("Second"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pending, err = store.PendingOutbox(ctx, time.Now().UTC(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 || pending[0].AggregateID != second {
+		t.Fatalf("PendingOutbox returned %+v, want only the message for movie %d", pending, second)
+	}
+
+	//? Once the backoff has passed, the failed message comes first again
+	pending, err = store.PendingOutbox(ctx, now.Add(2*time.Hour), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 3 || pending[0].AggregateID != first {
+		t.Fatalf("PendingOutbox after backoff returned %d messages, want 3 starting with movie %d", len(pending), first)
+	}
+}
@@ -0,0 +1,153 @@
+package sqlite
+
+import (
+	"context"
+	"github/MahfujulSagor/movies_crud/internals/db"
+	"github/MahfujulSagor/movies_crud/internals/types"
+	"strings"
+)
+
+func (s *SQLite) FindMovies(ctx context.Context, filter db.MovieFilter, limit int, offset int) ([]*types.Movie, error) {
+	var where []string
+	var args []any
+
+	if filter.TitleContains != "" {
+		where = append(where, "m.title LIKE ? ESCAPE '\\'")
+		args = append(args, "%"+escapeLike(filter.TitleContains)+"%")
+	}
+	if filter.DirectorContains != "" {
+		where = append(where, "d.name LIKE ? ESCAPE '\\'")
+		args = append(args, "%"+escapeLike(filter.DirectorContains)+"%")
+	}
+	if filter.MinRating > 0 {
+		where = append(where, "m.rating >= ?")
+		args = append(args, filter.MinRating)
+	}
+	if filter.MaxRating > 0 {
+		where = append(where, "m.rating <= ?")
+		args = append(args, filter.MaxRating)
+	}
+	if filter.ReleaseYear > 0 {
+		where = append(where, "m.release_year = ?")
+		args = append(args, filter.ReleaseYear)
+	}
+	if len(filter.DirectorIDs) > 0 {
+		where = append(where, "m.director_id IN ("+placeholders(len(filter.DirectorIDs))+")")
+		for _, id := range filter.DirectorIDs {
+			args = append(args, id)
+		}
+	}
+	if len(filter.CastIDs) > 0 {
+		where = append(where, "m.cast_id IN ("+placeholders(len(filter.CastIDs))+")")
+		for _, id := range filter.CastIDs {
+			args = append(args, id)
+		}
+	}
+
+	//? Batched lookups number each movie within its director or cast, so they can be capped per key
+	var group, groupRow string
+	switch {
+	case filter.PerGroupLimit > 0 && len(filter.DirectorIDs) > 0:
+		group = "m.director_id"
+	case filter.PerGroupLimit > 0 && len(filter.CastIDs) > 0:
+		group = "m.cast_id"
+	}
+	if group != "" {
+		groupRow = ",\n\t\t\tROW_NUMBER() OVER (PARTITION BY " + group + " ORDER BY m.id) AS group_row"
+	}
+
+	query := `
+		SELECT
+			m.id, m.title, m.rating, COALESCE(m.release_year, 0), m.created_at, m.updated_at,
+			d.id, d.name, d.age,
+			c.id, c.actor, c.actress` + groupRow + `
+		FROM movies m
+		LEFT JOIN directors d ON m.director_id = d.id
+		LEFT JOIN casts c ON m.cast_id = c.id`
+	if len(where) > 0 {
+		query += "\n\t\tWHERE " + strings.Join(where, " AND ")
+	}
+	query += "\n\t\tORDER BY m.id"
+	if group != "" {
+		//? Column 1 is the movie ID; the joined tables repeat the name "id"
+		query = "SELECT * FROM (" + query + "\n\t) WHERE group_row <= ? ORDER BY 1"
+		args = append(args, filter.PerGroupLimit)
+	}
+
+	//? SQLite treats a negative LIMIT as unbounded
+	if limit <= 0 {
+		limit = -1
+	}
+	query += "\n\t\tLIMIT ? OFFSET ?"
+	args = append(args, limit, offset)
+
+	rows, err := s.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var movies []*types.Movie
+	for rows.Next() {
+		movie := &types.Movie{
+			Director: &types.Director{},
+			Cast:     &types.Cast{},
+		}
+
+		dest := []any{
+			&movie.ID, &movie.Title, &movie.Rating, &movie.ReleaseYear, &movie.CreatedAt, &movie.UpdatedAt,
+			&movie.Director.ID, &movie.Director.Name, &movie.Director.Age,
+			&movie.Cast.ID, &movie.Cast.Actor, &movie.Cast.Actress,
+		}
+		var row int
+		if group != "" {
+			dest = append(dest, &row)
+		}
+
+		err := rows.Scan(dest...)
+		if err != nil {
+			return nil, err
+		}
+
+		movies = append(movies, movie)
+	}
+
+	return movies, rows.Err()
+}
+
+func (s *SQLite) GetDirectorsByIDs(ctx context.Context, ids []int64) ([]*types.Director, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+
+	rows, err := s.DB.QueryContext(ctx, "SELECT id, name, age FROM directors WHERE id IN ("+placeholders(len(ids))+") ORDER BY id", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var directors []*types.Director
+	for rows.Next() {
+		var director types.Director
+		if err := rows.Scan(&director.ID, &director.Name, &director.Age); err != nil {
+			return nil, err
+		}
+		directors = append(directors, &director)
+	}
+
+	return directors, rows.Err()
+}
+
+// placeholders returns "?, ?, ..." for an IN clause with n values
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -1,19 +1,34 @@
 package sqlite
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
 	"github/MahfujulSagor/movies_crud/internals/config"
+	"github/MahfujulSagor/movies_crud/internals/db"
+	"github/MahfujulSagor/movies_crud/internals/events"
 	"github/MahfujulSagor/movies_crud/internals/types"
+	"strings"
+	"time"
 
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/mattn/go-sqlite3"
 )
 
 type SQLite struct {
-	DB *sql.DB
+	DB   *sql.DB
+	Path string
 }
 
 func New(cfg *config.Config) (*SQLite, error) {
-	db, err := sql.Open("sqlite3", cfg.DBPath)
+	//? Foreign keys are off by default in SQLite; enable them on every pooled connection
+	dsn := cfg.DBPath
+	if strings.Contains(dsn, "?") {
+		dsn += "&_foreign_keys=on"
+	} else {
+		dsn += "?_foreign_keys=on"
+	}
+
+	db, err := sql.Open("sqlite3", dsn)
 	if err != nil {
 		return nil, err
 	}
@@ -41,6 +56,7 @@ func New(cfg *config.Config) (*SQLite, error) {
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
 		title TEXT NOT NULL,
 		rating INTEGER NOT NULL,
+		release_year INTEGER,
 		director_id INTEGER,
 		cast_id INTEGER,
 		UNIQUE(title, director_id, cast_id),
@@ -51,93 +67,245 @@ func New(cfg *config.Config) (*SQLite, error) {
 		return nil, err
 	}
 
+	//? Columns added after the initial schema
+	if err := ensureColumn(db, "movies", "release_year", "INTEGER"); err != nil {
+		return nil, err
+	}
+	if err := ensureColumn(db, "movies", "created_at", "TIMESTAMP"); err != nil {
+		return nil, err
+	}
+	if err := ensureColumn(db, "movies", "updated_at", "TIMESTAMP"); err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS api_keys (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		prefix TEXT NOT NULL,
+		key_hash TEXT UNIQUE NOT NULL,
+		scopes TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		revoked_at TIMESTAMP
+	)`)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS users (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		username TEXT UNIQUE NOT NULL,
+		password_hash TEXT NOT NULL,
+		role TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL
+	)`)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS sessions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		token_hash TEXT UNIQUE NOT NULL,
+		created_at TIMESTAMP NOT NULL,
+		expires_at TIMESTAMP NOT NULL,
+		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+	)`)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS audit_log (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		actor TEXT NOT NULL,
+		action TEXT NOT NULL,
+		entity TEXT NOT NULL,
+		entity_id INTEGER NOT NULL,
+		created_at TIMESTAMP NOT NULL
+	)`)
+	if err != nil {
+		return nil, err
+	}
+
+	//? Backfill timestamps for movies created before they were tracked, from the audit log where possible
+	_, err = db.Exec(`UPDATE movies SET
+		created_at = COALESCE((SELECT MIN(a.created_at) FROM audit_log a WHERE a.entity = 'movie' AND a.entity_id = movies.id), CURRENT_TIMESTAMP),
+		updated_at = COALESCE((SELECT MAX(a.created_at) FROM audit_log a WHERE a.entity = 'movie' AND a.entity_id = movies.id), CURRENT_TIMESTAMP)
+		WHERE created_at IS NULL OR updated_at IS NULL`)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_movies_updated_at ON movies(updated_at)`)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_audit_log_entity ON audit_log(entity, action, created_at)`)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS webhooks (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		url TEXT NOT NULL,
+		events TEXT NOT NULL,
+		secret TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL
+	)`)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS webhook_deliveries (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		webhook_id INTEGER NOT NULL,
+		event_type TEXT NOT NULL,
+		payload TEXT NOT NULL,
+		status TEXT NOT NULL,
+		attempts INTEGER NOT NULL DEFAULT 0,
+		next_attempt_at TIMESTAMP NOT NULL,
+		last_status INTEGER,
+		last_error TEXT,
+		created_at TIMESTAMP NOT NULL,
+		delivered_at TIMESTAMP,
+		FOREIGN KEY (webhook_id) REFERENCES webhooks(id) ON DELETE CASCADE
+	)`)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(status, next_attempt_at)`)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ensureColumn(db, "webhook_deliveries", "event_id", "INTEGER"); err != nil {
+		return nil, err
+	}
+
+	//? One delivery per webhook and event, so a retried relay does not queue it twice. Redeliveries
+	//? leave event_id NULL, and NULLs never collide.
+	_, err = db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_webhook_deliveries_event ON webhook_deliveries(webhook_id, event_id)`)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS outbox (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		aggregate_type TEXT NOT NULL,
+		aggregate_id INTEGER NOT NULL,
+		event_type TEXT NOT NULL,
+		payload TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL,
+		attempts INTEGER NOT NULL DEFAULT 0,
+		last_error TEXT,
+		next_attempt_at TIMESTAMP NOT NULL,
+		relayed_at TIMESTAMP
+	)`)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_outbox_pending ON outbox(relayed_at, id)`)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS idempotency_keys (
+		key TEXT PRIMARY KEY,
+		fingerprint TEXT NOT NULL,
+		status INTEGER NOT NULL DEFAULT 0,
+		header TEXT,
+		body BLOB,
+		created_at TIMESTAMP NOT NULL,
+		expires_at TIMESTAMP NOT NULL
+	)`)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expiry ON idempotency_keys(expires_at)`)
+	if err != nil {
+		return nil, err
+	}
+
 	return &SQLite{
-		DB: db,
+		DB:   db,
+		Path: cfg.DBPath,
 	}, nil
 }
 
-func (s *SQLite) CreateMovie(movie *types.Movie) (int64, error) {
-	//? Transaction
-	tx, err := s.DB.Begin()
+// Close waits for running queries and releases the database, checkpointing the WAL
+func (s *SQLite) Close() error {
+	return s.DB.Close()
+}
+
+func (s *SQLite) CreateMovie(ctx context.Context, movie *types.Movie) (int64, error) {
+	//? Transaction; rollback is a no-op once committed
+	tx, err := s.DB.BeginTx(ctx, nil)
 	if err != nil {
 		return 0, err
 	}
-	defer func() {
-		if err != nil {
-			_ = tx.Rollback()
-		}
-	}()
+	defer func() { _ = tx.Rollback() }()
 
-	//? ----------- DIRECTOR: check if exists -----------
-	var director_id int64
-	dir_row := tx.QueryRow("SELECT id FROM directors WHERE name = ?", movie.Director.Name)
-	err = dir_row.Scan(&director_id)
+	//? ----------- DIRECTOR & CAST: reuse or create -----------
+	director_id, err := upsertDirector(ctx, tx, movie.Director)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			res, err := tx.Exec("INSERT INTO directors(name, age) VALUES (?, ?)", movie.Director.Name, movie.Director.Age)
-			if err != nil {
-				return 0, err
-			}
-			director_id, err = res.LastInsertId()
-			if err != nil {
-				return 0, err
-			}
-		} else {
-			return 0, err
-		}
+		return 0, err
 	}
 
-	//? ----------- Cast: check if exists -----------
-	var cast_id int64
-	cast_row := tx.QueryRow("SELECT id FROM casts WHERE actor = ? AND actress = ?", movie.Cast.Actor, movie.Cast.Actress)
-	err = cast_row.Scan(&cast_id)
+	cast_id, err := upsertCast(ctx, tx, movie.Cast)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			res, err := tx.Exec("INSERT INTO casts(actor, actress) VALUES (?, ?)", movie.Cast.Actor, movie.Cast.Actress)
-			if err != nil {
-				return 0, err
-			}
-			cast_id, err = res.LastInsertId()
-			if err != nil {
-				return 0, err
-			}
-		} else {
-			return 0, err
-		}
+		return 0, err
 	}
 
 	//? ----------- Movie: check if exists -----------
-	var movie_id int64
-	movie_row := tx.QueryRow("SELECT id FROM movies WHERE title = ?", movie.Title)
-	err = movie_row.Scan(&movie_id)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			res, err := tx.Exec("INSERT INTO movies(title, rating, director_id, cast_id) VALUES (?, ?, ?, ?)", movie.Title, movie.Rating, director_id, cast_id)
-			if err != nil {
-				return 0, err
-			}
-			movie_id, err = res.LastInsertId()
-			if err != nil {
-				return 0, err
-			}
-		} else {
-			return 0, nil
-		}
+	var existing_id int64
+	err = tx.QueryRowContext(ctx, "SELECT id FROM movies WHERE title = ?", movie.Title).Scan(&existing_id)
+	if err == nil {
+		return 0, fmt.Errorf("%w: movie %q already exists with ID %d", db.ErrConflict, movie.Title, existing_id)
+	}
+	if err != sql.ErrNoRows {
+		return 0, err
+	}
 
+	now := time.Now().UTC()
+	res, err := tx.ExecContext(ctx, "INSERT INTO movies(title, rating, release_year, director_id, cast_id, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		movie.Title, movie.Rating, nullableYear(movie.ReleaseYear), director_id, cast_id, now, now)
+	if err != nil {
+		return 0, translateError(err)
+	}
+	movie_id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	if err := recordAudit(ctx, tx, "create", "movie", movie_id); err != nil {
+		return 0, err
+	}
+	if err := recordMovieEvent(ctx, tx, events.MovieCreated, movie_id, nil); err != nil {
 		return 0, err
 	}
 
 	if err := tx.Commit(); err != nil {
-		return 0, err
+		return 0, translateError(err)
 	}
 
 	return movie_id, nil
 }
 
-func (s *SQLite) GetMovieByID(id int64) (*types.Movie, error) {
-	row := s.DB.QueryRow(`
+func (s *SQLite) GetMovieByID(ctx context.Context, id int64) (*types.Movie, error) {
+	return loadMovie(ctx, s.DB, id)
+}
+
+// queryer is satisfied by both *sql.DB and *sql.Tx
+type queryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func loadMovie(ctx context.Context, q queryer, id int64) (*types.Movie, error) {
+	row := q.QueryRowContext(ctx, `
 		SELECT
-			m.id, m.title, m.rating,
+			m.id, m.title, m.rating, COALESCE(m.release_year, 0), m.created_at, m.updated_at,
 			d.id, d.name, d.age,
 			c.id, c.actor, c.actress
 		FROM movies m
@@ -151,13 +319,13 @@ func (s *SQLite) GetMovieByID(id int64) (*types.Movie, error) {
 	movie.Cast = &types.Cast{}
 
 	err := row.Scan(
-		&movie.ID, &movie.Title, &movie.Rating,
+		&movie.ID, &movie.Title, &movie.Rating, &movie.ReleaseYear, &movie.CreatedAt, &movie.UpdatedAt,
 		&movie.Director.ID, &movie.Director.Name, &movie.Director.Age,
 		&movie.Cast.ID, &movie.Cast.Actor, &movie.Cast.Actress,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, nil
+			return nil, fmt.Errorf("%w: movie %d", db.ErrNotFound, id)
 		}
 
 		return nil, err
@@ -166,10 +334,10 @@ func (s *SQLite) GetMovieByID(id int64) (*types.Movie, error) {
 	return &movie, nil
 }
 
-func (s *SQLite) GetMovieList(limit int, offset int) ([]*types.Movie, error) {
-	rows, err := s.DB.Query(`
+func (s *SQLite) GetMovieList(ctx context.Context, limit int, offset int) ([]*types.Movie, error) {
+	rows, err := s.DB.QueryContext(ctx, `
 		SELECT
-			m.id, m.title, m.rating,
+			m.id, m.title, m.rating, COALESCE(m.release_year, 0), m.created_at, m.updated_at,
 			d.id, d.name, d.age,
 			c.id, c.actor, c.actress
 		FROM movies m
@@ -192,7 +360,7 @@ func (s *SQLite) GetMovieList(limit int, offset int) ([]*types.Movie, error) {
 		}
 
 		err := rows.Scan(
-			&movie.ID, &movie.Title, &movie.Rating,
+			&movie.ID, &movie.Title, &movie.Rating, &movie.ReleaseYear, &movie.CreatedAt, &movie.UpdatedAt,
 			&movie.Director.ID, &movie.Director.Name, &movie.Director.Age,
 			&movie.Cast.ID, &movie.Cast.Actor, &movie.Cast.Actress,
 		)
@@ -210,97 +378,103 @@ func (s *SQLite) GetMovieList(limit int, offset int) ([]*types.Movie, error) {
 	return movies, nil
 }
 
-func (s *SQLite) UpdateMovie(id int64, movie *types.Movie) (int64, error) {
-	tx, err := s.DB.Begin()
+func (s *SQLite) MoviesLastModified(ctx context.Context) (time.Time, error) {
+	//? Deleted movies leave no row behind, so their time comes from the audit log
+	var updated, deleted sql.NullString
+	err := s.DB.QueryRowContext(ctx, `
+		SELECT
+			(SELECT MAX(updated_at) FROM movies),
+			(SELECT MAX(created_at) FROM audit_log WHERE entity = 'movie' AND action = 'delete')
+	`).Scan(&updated, &deleted)
 	if err != nil {
-		return 0, err
+		return time.Time{}, err
 	}
-	defer func() {
+
+	var latest time.Time
+	for _, value := range []sql.NullString{updated, deleted} {
+		if !value.Valid {
+			continue
+		}
+		t, err := parseTimestamp(value.String)
 		if err != nil {
-			_ = tx.Rollback()
+			return time.Time{}, err
+		}
+		if t.After(latest) {
+			latest = t
 		}
-	}()
+	}
+	return latest, nil
+}
 
-	//? ----------- DIRECTOR: check if exists -----------
-	var director_id int64
-	dir_row := tx.QueryRow("SELECT id FROM directors WHERE name = ?", movie.Director.Name)
-	err = dir_row.Scan(&director_id)
+func (s *SQLite) UpdateMovie(ctx context.Context, id int64, movie *types.Movie) (int64, error) {
+	tx, err := s.DB.BeginTx(ctx, nil)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			res, err := tx.Exec("INSERT INTO directors(name, age) VALUES (?, ?)", movie.Director.Name, movie.Director.Age)
-			if err != nil {
-				return 0, err
-			}
-			director_id, err = res.LastInsertId()
-			if err != nil {
-				return 0, err
-			}
-		} else {
-			return 0, err
-		}
+		return 0, err
 	}
+	defer func() { _ = tx.Rollback() }()
 
-	//? ----------- Cast: check if exists -----------
-	var cast_id int64
-	cast_row := tx.QueryRow("SELECT id FROM casts WHERE actor = ? AND actress = ?", movie.Cast.Actor, movie.Cast.Actress)
-	err = cast_row.Scan(&cast_id)
+	//? ----------- DIRECTOR & CAST: reuse or create -----------
+	director_id, err := upsertDirector(ctx, tx, movie.Director)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			res, err := tx.Exec("INSERT INTO casts(actor, actress) VALUES (?, ?)", movie.Cast.Actor, movie.Cast.Actress)
-			if err != nil {
-				return 0, err
-			}
-			cast_id, err = res.LastInsertId()
-			if err != nil {
-				return 0, err
-			}
-		} else {
-			return 0, err
-		}
+		return 0, err
 	}
 
-	//? ----------- MOVIE: update the row -----------
-	res, err := tx.Exec("UPDATE movies SET title = ?, rating = ?, director_id = ?, cast_id = ? WHERE id = ?",
-		movie.Title, movie.Rating, director_id, cast_id, id)
+	cast_id, err := upsertCast(ctx, tx, movie.Cast)
 	if err != nil {
 		return 0, err
 	}
 
+	//? ----------- MOVIE: update the row -----------
+	res, err := tx.ExecContext(ctx, "UPDATE movies SET title = ?, rating = ?, release_year = ?, director_id = ?, cast_id = ?, updated_at = ? WHERE id = ?",
+		movie.Title, movie.Rating, nullableYear(movie.ReleaseYear), director_id, cast_id, time.Now().UTC(), id)
+	if err != nil {
+		return 0, translateError(err)
+	}
+
 	rowsAffected, err := res.RowsAffected()
 	if err != nil {
 		return 0, err
 	}
 
 	if rowsAffected == 0 {
-		return 0, nil
+		return 0, fmt.Errorf("%w: movie %d", db.ErrNotFound, id)
 	}
 
-	//? ----------- COMMIT -----------
-	if err = tx.Commit(); err != nil {
+	if err := recordAudit(ctx, tx, "update", "movie", id); err != nil {
+		return 0, err
+	}
+	if err := recordMovieEvent(ctx, tx, events.MovieUpdated, id, nil); err != nil {
 		return 0, err
 	}
 
+	//? ----------- COMMIT -----------
+	if err := tx.Commit(); err != nil {
+		return 0, translateError(err)
+	}
+
 	return id, nil
 }
 
-func (s *SQLite) DeleteMovieByID(id int64) (int64, error) {
+func (s *SQLite) DeleteMovieByID(ctx context.Context, id int64) (int64, error) {
 	//? Start a transaction
-	tx, err := s.DB.Begin()
+	tx, err := s.DB.BeginTx(ctx, nil)
 	if err != nil {
 		return 0, err
 	}
-	defer func() {
-		if err != nil {
-			_ = tx.Rollback()
-		}
-	}()
+	defer func() { _ = tx.Rollback() }()
 
-	//? Delete the movie row
-	res, err := tx.Exec("DELETE FROM movies WHERE id = ?", id)
+	//? Read first so the deletion event still carries the director
+	before, err := loadMovie(ctx, tx, id)
 	if err != nil {
 		return 0, err
 	}
 
+	//? Delete the movie row
+	res, err := tx.ExecContext(ctx, "DELETE FROM movies WHERE id = ?", id)
+	if err != nil {
+		return 0, translateError(err)
+	}
+
 	//? Check if any row was actually deleted
 	rowsAffected, err := res.RowsAffected()
 	if err != nil {
@@ -308,13 +482,113 @@ func (s *SQLite) DeleteMovieByID(id int64) (int64, error) {
 	}
 
 	if rowsAffected == 0 {
-		return 0, nil
+		return 0, fmt.Errorf("%w: movie %d", db.ErrNotFound, id)
+	}
+
+	if err := recordAudit(ctx, tx, "delete", "movie", id); err != nil {
+		return 0, err
+	}
+	if err := recordMovieEvent(ctx, tx, events.MovieDeleted, id, before); err != nil {
+		return 0, err
 	}
 
 	//? Commit transaction
 	if err := tx.Commit(); err != nil {
-		return 0, err
+		return 0, translateError(err)
 	}
 
 	return id, nil
 }
+
+// upsertDirector returns the ID of the director with this name, creating it if needed
+func upsertDirector(ctx context.Context, tx *sql.Tx, director *types.Director) (int64, error) {
+	var director_id int64
+	err := tx.QueryRowContext(ctx, "SELECT id FROM directors WHERE name = ?", director.Name).Scan(&director_id)
+	if err == nil {
+		return director_id, nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, err
+	}
+
+	res, err := tx.ExecContext(ctx, "INSERT INTO directors(name, age) VALUES (?, ?)", director.Name, director.Age)
+	if err != nil {
+		return 0, translateError(err)
+	}
+	director_id, err = res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return director_id, recordAudit(ctx, tx, "create", "director", director_id)
+}
+
+// upsertCast returns the ID of the cast with this actor and actress, creating it if needed
+func upsertCast(ctx context.Context, tx *sql.Tx, cast *types.Cast) (int64, error) {
+	var cast_id int64
+	err := tx.QueryRowContext(ctx, "SELECT id FROM casts WHERE actor = ? AND actress = ?", cast.Actor, cast.Actress).Scan(&cast_id)
+	if err == nil {
+		return cast_id, nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, err
+	}
+
+	res, err := tx.ExecContext(ctx, "INSERT INTO casts(actor, actress) VALUES (?, ?)", cast.Actor, cast.Actress)
+	if err != nil {
+		return 0, translateError(err)
+	}
+	cast_id, err = res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return cast_id, recordAudit(ctx, tx, "create", "cast", cast_id)
+}
+
+// recordAudit logs a write in the same transaction, attributed to the actor carried by ctx
+func recordAudit(ctx context.Context, tx *sql.Tx, action string, entity string, entityID int64) error {
+	_, err := tx.ExecContext(ctx, "INSERT INTO audit_log(actor, action, entity, entity_id, created_at) VALUES (?, ?, ?, ?, ?)",
+		db.ActorFromContext(ctx), action, entity, entityID, time.Now().UTC())
+	return err
+}
+
+// ensureColumn adds a column to databases created before it was part of the schema
+func ensureColumn(conn *sql.DB, table string, column string, definition string) error {
+	rows, err := conn.Query("SELECT name FROM pragma_table_info(?)", table)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	_, err = conn.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	return err
+}
+
+// parseTimestamp reads a time returned by an aggregate, which the driver leaves as text
+func parseTimestamp(value string) (time.Time, error) {
+	value = strings.TrimSuffix(value, "Z")
+	for _, layout := range sqlite3.SQLiteTimestampFormats {
+		if t, err := time.ParseInLocation(layout, value, time.UTC); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognised timestamp %q", value)
+}
+
+func nullableYear(year int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(year), Valid: year != 0}
+}
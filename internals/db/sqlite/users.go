@@ -0,0 +1,101 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"github/MahfujulSagor/movies_crud/internals/db"
+	"github/MahfujulSagor/movies_crud/internals/types"
+	"time"
+)
+
+func (s *SQLite) CreateUser(user *types.User, passwordHash string) (int64, error) {
+	res, err := s.DB.Exec("INSERT INTO users(username, password_hash, role, created_at) VALUES (?, ?, ?, ?)",
+		user.Username, passwordHash, user.Role, user.CreatedAt)
+	if err != nil {
+		return 0, translateError(err)
+	}
+
+	return res.LastInsertId()
+}
+
+func (s *SQLite) GetUserByUsername(username string) (*types.User, string, error) {
+	row := s.DB.QueryRow(`
+		SELECT id, username, role, created_at, password_hash
+		FROM users
+		WHERE username = ?
+	`, username)
+
+	var user types.User
+	var hash string
+	err := row.Scan(&user.ID, &user.Username, &user.Role, &user.CreatedAt, &hash)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, "", fmt.Errorf("%w: user %q", db.ErrNotFound, username)
+		}
+
+		return nil, "", err
+	}
+
+	return &user, hash, nil
+}
+
+func (s *SQLite) ListUsers() ([]*types.User, error) {
+	rows, err := s.DB.Query(`
+		SELECT id, username, role, created_at
+		FROM users
+		ORDER BY id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []*types.User
+
+	for rows.Next() {
+		var user types.User
+		if err := rows.Scan(&user.ID, &user.Username, &user.Role, &user.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		users = append(users, &user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+func (s *SQLite) CreateSession(userID int64, tokenHash string, expiresAt time.Time) error {
+	_, err := s.DB.Exec("INSERT INTO sessions(user_id, token_hash, created_at, expires_at) VALUES (?, ?, ?, ?)",
+		userID, tokenHash, time.Now().UTC(), expiresAt.UTC())
+	return translateError(err)
+}
+
+func (s *SQLite) GetUserBySession(tokenHash string) (*types.User, error) {
+	row := s.DB.QueryRow(`
+		SELECT u.id, u.username, u.role, u.created_at
+		FROM sessions s
+		JOIN users u ON s.user_id = u.id
+		WHERE s.token_hash = ? AND s.expires_at > ?
+	`, tokenHash, time.Now().UTC())
+
+	var user types.User
+	err := row.Scan(&user.ID, &user.Username, &user.Role, &user.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("%w: session", db.ErrNotFound)
+		}
+
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func (s *SQLite) DeleteSession(tokenHash string) error {
+	_, err := s.DB.Exec("DELETE FROM sessions WHERE token_hash = ?", tokenHash)
+	return err
+}
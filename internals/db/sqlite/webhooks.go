@@ -0,0 +1,194 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"github/MahfujulSagor/movies_crud/internals/db"
+	"github/MahfujulSagor/movies_crud/internals/types"
+	"strings"
+	"time"
+)
+
+func (s *SQLite) CreateWebhook(ctx context.Context, hook *types.Webhook) (int64, error) {
+	res, err := s.DB.ExecContext(ctx, "INSERT INTO webhooks(url, events, secret, created_at) VALUES (?, ?, ?, ?)",
+		hook.URL, strings.Join(hook.Events, " "), hook.Secret, hook.CreatedAt)
+	if err != nil {
+		return 0, translateError(err)
+	}
+
+	return res.LastInsertId()
+}
+
+func (s *SQLite) ListWebhooks(ctx context.Context) ([]*types.Webhook, error) {
+	return s.queryWebhooks(ctx, false, "SELECT id, url, events, secret, created_at FROM webhooks ORDER BY id")
+}
+
+func (s *SQLite) WebhooksForEvent(ctx context.Context, eventType string) ([]*types.Webhook, error) {
+	//? Events are stored space-separated, so pad both sides to match whole names
+	return s.queryWebhooks(ctx, true, `
+		SELECT id, url, events, secret, created_at
+		FROM webhooks
+		WHERE ' ' || events || ' ' LIKE '% ' || ? || ' %'
+		ORDER BY id
+	`, eventType)
+}
+
+func (s *SQLite) GetWebhook(ctx context.Context, id int64) (*types.Webhook, error) {
+	hooks, err := s.queryWebhooks(ctx, true, "SELECT id, url, events, secret, created_at FROM webhooks WHERE id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(hooks) == 0 {
+		return nil, fmt.Errorf("%w: webhook %d", db.ErrNotFound, id)
+	}
+	return hooks[0], nil
+}
+
+func (s *SQLite) DeleteWebhook(ctx context.Context, id int64) (int64, error) {
+	res, err := s.DB.ExecContext(ctx, "DELETE FROM webhooks WHERE id = ?", id)
+	if err != nil {
+		return 0, translateError(err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	if rowsAffected == 0 {
+		return 0, fmt.Errorf("%w: webhook %d", db.ErrNotFound, id)
+	}
+
+	return id, nil
+}
+
+func (s *SQLite) queryWebhooks(ctx context.Context, withSecret bool, query string, args ...any) ([]*types.Webhook, error) {
+	rows, err := s.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hooks []*types.Webhook
+
+	for rows.Next() {
+		var hook types.Webhook
+		var events string
+		if err := rows.Scan(&hook.ID, &hook.URL, &events, &hook.Secret, &hook.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		hook.Events = strings.Fields(events)
+		if !withSecret {
+			hook.Secret = ""
+		}
+
+		hooks = append(hooks, &hook)
+	}
+
+	return hooks, rows.Err()
+}
+
+func (s *SQLite) EnqueueDelivery(ctx context.Context, d *types.WebhookDelivery) (int64, error) {
+	res, err := s.DB.ExecContext(ctx, `
+		INSERT INTO webhook_deliveries(webhook_id, event_id, event_type, payload, status, attempts, next_attempt_at, created_at)
+		VALUES (?, NULLIF(?, 0), ?, ?, ?, 0, ?, ?)
+		ON CONFLICT(webhook_id, event_id) DO NOTHING
+	`, d.WebhookID, d.EventID, d.EventType, d.Payload, db.DeliveryPending, d.NextAttemptAt, d.CreatedAt)
+	if err != nil {
+		return 0, translateError(err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	//? Already queued by an earlier relay of the same event
+	if rowsAffected == 0 {
+		var id int64
+		err := s.DB.QueryRowContext(ctx, "SELECT id FROM webhook_deliveries WHERE webhook_id = ? AND event_id = ?",
+			d.WebhookID, d.EventID).Scan(&id)
+		return id, err
+	}
+
+	return res.LastInsertId()
+}
+
+const deliveryColumns = `id, webhook_id, COALESCE(event_id, 0), event_type, payload, status, attempts, next_attempt_at,
+	COALESCE(last_status, 0), COALESCE(last_error, ''), created_at, delivered_at`
+
+func (s *SQLite) DueDeliveries(ctx context.Context, now time.Time, limit int) ([]*types.WebhookDelivery, error) {
+	return s.queryDeliveries(ctx, `
+		SELECT `+deliveryColumns+`
+		FROM webhook_deliveries
+		WHERE status = ? AND next_attempt_at <= ?
+		ORDER BY next_attempt_at, id
+		LIMIT ?
+	`, db.DeliveryPending, now, limit)
+}
+
+func (s *SQLite) ListDeliveries(ctx context.Context, webhookID int64, limit int, offset int) ([]*types.WebhookDelivery, error) {
+	return s.queryDeliveries(ctx, `
+		SELECT `+deliveryColumns+`
+		FROM webhook_deliveries
+		WHERE webhook_id = ?
+		ORDER BY id DESC
+		LIMIT ? OFFSET ?
+	`, webhookID, limit, offset)
+}
+
+func (s *SQLite) GetDelivery(ctx context.Context, id int64) (*types.WebhookDelivery, error) {
+	deliveries, err := s.queryDeliveries(ctx, "SELECT "+deliveryColumns+" FROM webhook_deliveries WHERE id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(deliveries) == 0 {
+		return nil, fmt.Errorf("%w: webhook delivery %d", db.ErrNotFound, id)
+	}
+	return deliveries[0], nil
+}
+
+func (s *SQLite) UpdateDelivery(ctx context.Context, d *types.WebhookDelivery) error {
+	res, err := s.DB.ExecContext(ctx, `
+		UPDATE webhook_deliveries
+		SET status = ?, attempts = ?, next_attempt_at = ?, last_status = ?, last_error = ?, delivered_at = ?
+		WHERE id = ?
+	`, d.Status, d.Attempts, d.NextAttemptAt, d.LastStatus, d.LastError, d.DeliveredAt, d.ID)
+	if err != nil {
+		return translateError(err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("%w: webhook delivery %d", db.ErrNotFound, d.ID)
+	}
+
+	return nil
+}
+
+func (s *SQLite) queryDeliveries(ctx context.Context, query string, args ...any) ([]*types.WebhookDelivery, error) {
+	rows, err := s.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []*types.WebhookDelivery
+
+	for rows.Next() {
+		var d types.WebhookDelivery
+		err := rows.Scan(&d.ID, &d.WebhookID, &d.EventID, &d.EventType, &d.Payload, &d.Status, &d.Attempts, &d.NextAttemptAt,
+			&d.LastStatus, &d.LastError, &d.CreatedAt, &d.DeliveredAt)
+		if err != nil {
+			return nil, err
+		}
+
+		deliveries = append(deliveries, &d)
+	}
+
+	return deliveries, rows.Err()
+}
@@ -0,0 +1,177 @@
+// Package events is an in-process bus for catalogue change notifications.
+package events
+
+import (
+	"github/MahfujulSagor/movies_crud/internals/types"
+	"sync"
+	"time"
+)
+
+const (
+	MovieCreated = "movie.created"
+	MovieUpdated = "movie.updated"
+	MovieDeleted = "movie.deleted"
+)
+
+const (
+	DefaultHistory   = 1000
+	subscriberBuffer = 64
+)
+
+type Event struct {
+	ID         uint64       `json:"id"`
+	Type       string       `json:"type"`
+	MovieID    int64        `json:"movie_id"`
+	DirectorID int64        `json:"director_id,omitempty"`
+	Movie      *types.Movie `json:"movie,omitempty"`
+	Actor      string       `json:"actor"`
+	Time       time.Time    `json:"time"`
+}
+
+// Filter restricts a subscription; zero fields match every event
+type Filter struct {
+	MovieID    int64
+	DirectorID int64
+}
+
+func (f Filter) Match(e Event) bool {
+	if f.MovieID != 0 && e.MovieID != f.MovieID {
+		return false
+	}
+	if f.DirectorID != 0 && e.DirectorID != f.DirectorID {
+		return false
+	}
+	return true
+}
+
+// Bus fans events out to subscribers and keeps a bounded history so reconnecting
+// clients can resume from the last ID they saw. Events relayed from the outbox keep their
+// outbox IDs, so those stay stable across restarts; IDs the bus assigns itself restart.
+type Bus struct {
+	mu      sync.Mutex
+	nextID  uint64
+	history []Event
+	limit   int
+	subs    map[*Subscription]struct{}
+	closed  bool
+}
+
+func NewBus(history int) *Bus {
+	if history <= 0 {
+		history = DefaultHistory
+	}
+	return &Bus{limit: history, subs: map[*Subscription]struct{}{}}
+}
+
+// Publish delivers e, stamping it with the next ID unless it already has one. An event whose ID is
+// still in the history was published before and is dropped, so retried relays are not seen twice.
+// Subscribers too slow to keep up are disconnected.
+func (b *Bus) Publish(e Event) Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if e.ID == 0 {
+		b.nextID++
+		e.ID = b.nextID
+	} else {
+		if b.published(e.ID) {
+			return e
+		}
+		//? Later bus-assigned IDs must not collide with ones given by the caller
+		b.nextID = max(b.nextID, e.ID)
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+
+	b.history = append(b.history, e)
+	if len(b.history) > b.limit {
+		b.history = b.history[len(b.history)-b.limit:]
+	}
+
+	for sub := range b.subs {
+		if !sub.filter.Match(e) {
+			continue
+		}
+		select {
+		case sub.ch <- e:
+		default:
+			//? Dropping the subscriber beats silently skipping events; it resumes with Last-Event-ID
+			b.remove(sub)
+		}
+	}
+
+	return e
+}
+
+type Subscription struct {
+	ch     chan Event
+	filter Filter
+	bus    *Bus
+	// Backlog holds matching events published after the requested ID, oldest first
+	Backlog []Event
+}
+
+// Events is closed when the subscriber falls behind, Close is called or the bus is closed
+func (s *Subscription) Events() <-chan Event {
+	return s.ch
+}
+
+func (s *Subscription) Close() {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	s.bus.remove(s)
+}
+
+// Subscribe registers a listener; with after > 0 the retained events newer than after are returned as the backlog
+func (b *Bus) Subscribe(filter Filter, after uint64) *Subscription {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sub := &Subscription{ch: make(chan Event, subscriberBuffer), filter: filter, bus: b}
+	//? After Close a subscriber gets a closed channel and ends at once
+	if b.closed {
+		close(sub.ch)
+		return sub
+	}
+	if after > 0 {
+		for _, e := range b.history {
+			if e.ID > after && filter.Match(e) {
+				sub.Backlog = append(sub.Backlog, e)
+			}
+		}
+	}
+
+	b.subs[sub] = struct{}{}
+	return sub
+}
+
+// Close ends every subscription and refuses new ones, so long-lived streams finish on shutdown
+// and their clients reconnect elsewhere. Publish keeps recording history.
+func (b *Bus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for sub := range b.subs {
+		b.remove(sub)
+	}
+}
+
+// published must be called with b.mu held
+func (b *Bus) published(id uint64) bool {
+	for i := len(b.history) - 1; i >= 0; i-- {
+		if b.history[i].ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// remove must be called with b.mu held
+func (b *Bus) remove(sub *Subscription) {
+	if _, ok := b.subs[sub]; ok {
+		delete(b.subs, sub)
+		close(sub.ch)
+	}
+}
@@ -0,0 +1,69 @@
+package events
+
+import "testing"
+
+func TestPublishKeepsCallerIDs(t *testing.T) {
+	bus := NewBus(10)
+	sub := bus.Subscribe(Filter{}, 0)
+	defer sub.Close()
+
+	if got := bus.Publish(Event{ID: 42, Type: MovieCreated}).ID; got != 42 {
+		t.Fatalf("Publish ID = %d, want 42", got)
+	}
+	//? Bus-assigned IDs continue after the highest given one
+	if got := bus.Publish(Event{Type: MovieUpdated}).ID; got != 43 {
+		t.Fatalf("assigned ID = %d, want 43", got)
+	}
+
+	for _, want := range []uint64{42, 43} {
+		if e := <-sub.Events(); e.ID != want {
+			t.Fatalf("delivered ID = %d, want %d", e.ID, want)
+		}
+	}
+}
+
+func TestPublishDropsRepeatedIDs(t *testing.T) {
+	bus := NewBus(10)
+	sub := bus.Subscribe(Filter{}, 0)
+	defer sub.Close()
+
+	bus.Publish(Event{ID: 7, Type: MovieCreated})
+	bus.Publish(Event{ID: 7, Type: MovieCreated})
+	bus.Publish(Event{ID: 8, Type: MovieDeleted})
+
+	if e := <-sub.Events(); e.ID != 7 {
+		t.Fatalf("first event ID = %d, want 7", e.ID)
+	}
+	if e := <-sub.Events(); e.ID != 8 {
+		t.Fatalf("second event ID = %d, want 8, the repeat should be dropped", e.ID)
+	}
+
+	if backlog := bus.Subscribe(Filter{}, 6).Backlog; len(backlog) != 2 {
+		t.Fatalf("backlog has %d events, want 2", len(backlog))
+	}
+}
+
+func TestCloseEndsSubscriptions(t *testing.T) {
+	bus := NewBus(10)
+	sub := bus.Subscribe(Filter{}, 0)
+	bus.Publish(Event{Type: MovieCreated})
+
+	bus.Close()
+	if e, ok := <-sub.Events(); !ok || e.ID != 1 {
+		t.Fatalf("buffered event lost on close: %+v, %v", e, ok)
+	}
+	if _, ok := <-sub.Events(); ok {
+		t.Fatal("subscription still open after Close")
+	}
+	sub.Close()
+
+	//? Late subscribers end at once, while history keeps growing for a bus that outlives its streams
+	bus.Publish(Event{Type: MovieUpdated})
+	late := bus.Subscribe(Filter{}, 0)
+	if _, ok := <-late.Events(); ok {
+		t.Fatal("subscribing after Close returned an open channel")
+	}
+	if !bus.published(2) {
+		t.Fatal("Publish stopped recording history after Close")
+	}
+}
@@ -0,0 +1,117 @@
+package clientip
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// Resolver determines the originating client address, honouring X-Forwarded-For
+// only when the request arrived through a trusted proxy
+type Resolver struct {
+	trusted []netip.Prefix
+}
+
+func New(cidrs []string) (*Resolver, error) {
+	r := &Resolver{}
+	for _, c := range cidrs {
+		//? Accept bare addresses as single-host prefixes
+		if !strings.Contains(c, "/") {
+			addr, err := netip.ParseAddr(c)
+			if err != nil {
+				return nil, err
+			}
+			r.trusted = append(r.trusted, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(c)
+		if err != nil {
+			return nil, err
+		}
+		r.trusted = append(r.trusted, prefix.Masked())
+	}
+	return r, nil
+}
+
+func (r *Resolver) Trusted(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, p := range r.trusted {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP walks X-Forwarded-For from the right, skipping trusted proxies, and returns the first untrusted hop
+func (r *Resolver) ClientIP(req *http.Request) string {
+	remote := remoteAddr(req)
+	if !remote.IsValid() {
+		return req.RemoteAddr
+	}
+
+	if !r.Trusted(remote) {
+		return remote.String()
+	}
+
+	hops := forwardedFor(req)
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(hops[i])
+		if err != nil {
+			//? A malformed hop cannot be trusted or attributed; stop at the last good address
+			break
+		}
+		addr = addr.Unmap()
+		if !r.Trusted(addr) {
+			return addr.String()
+		}
+		remote = addr
+	}
+
+	return remote.String()
+}
+
+// Scheme is "https" for TLS connections and for requests a trusted proxy forwarded with
+// X-Forwarded-Proto: https, otherwise "http"
+func (r *Resolver) Scheme(req *http.Request) string {
+	if req.TLS != nil {
+		return "https"
+	}
+
+	remote := remoteAddr(req)
+	if !remote.IsValid() || !r.Trusted(remote) {
+		return "http"
+	}
+	//? A chain of proxies may append; the first value is what the client used
+	proto, _, _ := strings.Cut(req.Header.Get("X-Forwarded-Proto"), ",")
+	if strings.EqualFold(strings.TrimSpace(proto), "https") {
+		return "https"
+	}
+	return "http"
+}
+
+func remoteAddr(req *http.Request) netip.Addr {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}
+	}
+	return addr.Unmap()
+}
+
+func forwardedFor(req *http.Request) []string {
+	var hops []string
+	for _, header := range req.Header.Values("X-Forwarded-For") {
+		for _, hop := range strings.Split(header, ",") {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				hops = append(hops, hop)
+			}
+		}
+	}
+	return hops
+}
@@ -0,0 +1,83 @@
+package clientip
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	r, err := New([]string{"10.0.0.0/8", "192.168.1.10", "fd00::/8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		remote string
+		xff    []string
+		want   string
+	}{
+		{"direct client", "203.0.113.7:5000", nil, "203.0.113.7"},
+		{"untrusted peer cannot spoof", "203.0.113.7:5000", []string{"198.51.100.1"}, "203.0.113.7"},
+		{"untrusted peer inside the trusted range in XFF", "203.0.113.7:5000", []string{"10.0.0.1"}, "203.0.113.7"},
+		{"trusted proxy", "10.0.0.2:443", []string{"198.51.100.1"}, "198.51.100.1"},
+		{"trusted proxy, client-supplied hop on the left", "10.0.0.2:443", []string{"1.1.1.1, 198.51.100.1"}, "198.51.100.1"},
+		{"chain of trusted proxies", "10.0.0.2:443", []string{"198.51.100.1, 192.168.1.10, 10.3.3.3"}, "198.51.100.1"},
+		{"header split across lines", "10.0.0.2:443", []string{"1.1.1.1", "198.51.100.1, 10.3.3.3"}, "198.51.100.1"},
+		{"bare address trusts only that host", "192.168.1.11:443", []string{"198.51.100.1"}, "192.168.1.11"},
+		{"only trusted hops", "10.0.0.2:443", []string{"10.9.9.9"}, "10.9.9.9"},
+		{"trusted proxy without header", "10.0.0.2:443", nil, "10.0.0.2"},
+		{"malformed hop stops the walk", "10.0.0.2:443", []string{"198.51.100.1, garbage, 10.3.3.3"}, "10.3.3.3"},
+		{"IPv4-mapped peer", "[::ffff:10.0.0.2]:443", []string{"198.51.100.1"}, "198.51.100.1"},
+		{"IPv4-mapped hop", "10.0.0.2:443", []string{"::ffff:198.51.100.1"}, "198.51.100.1"},
+		{"IPv6 proxy", "[fd00::1]:443", []string{"2001:db8::5"}, "2001:db8::5"},
+		{"unparseable remote", "pipe", []string{"198.51.100.1"}, "pipe"},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = tc.remote
+		for _, value := range tc.xff {
+			req.Header.Add("X-Forwarded-For", value)
+		}
+		if got := r.ClientIP(req); got != tc.want {
+			t.Errorf("%s: ClientIP = %s, want %s", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestScheme(t *testing.T) {
+	r, err := New([]string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		remote string
+		proto  string
+		want   string
+	}{
+		{"plain", "203.0.113.7:5000", "", "http"},
+		{"untrusted peer cannot claim https", "203.0.113.7:5000", "https", "http"},
+		{"trusted proxy", "10.0.0.2:443", "HTTPS", "https"},
+		{"first value is the client's", "10.0.0.2:443", "http, https", "http"},
+		{"trusted proxy over http", "10.0.0.2:443", "http", "http"},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = tc.remote
+		if tc.proto != "" {
+			req.Header.Set("X-Forwarded-Proto", tc.proto)
+		}
+		if got := r.Scheme(req); got != tc.want {
+			t.Errorf("%s: Scheme = %s, want %s", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestNewRejectsBadProxies(t *testing.T) {
+	for _, cidr := range []string{"10.0.0.0/33", "not-an-ip", "10.0.0/8"} {
+		if _, err := New([]string{cidr}); err == nil {
+			t.Errorf("New accepted %q", cidr)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package compress
+
+import (
+	"bytes"
+	"compress/gzip"
+	"github/MahfujulSagor/movies_crud/internals/apperr"
+	"github/MahfujulSagor/movies_crud/internals/utils/response"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Body decodes a request body sent with Content-Encoding and caps its decoded size at limit bytes,
+// answering 413 when it is larger. The limit applies after decompression, so small compressed
+// bodies cannot expand into large ones.
+func Body(limit int64, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding")))
+		if r.ContentLength == 0 && encoding == "" {
+			next(w, r)
+			return
+		}
+
+		//? A declared length already over the limit is refused without reading the body
+		if encoding == "" && r.ContentLength > limit {
+			response.WriteProblem(w, r, tooLarge(limit))
+			return
+		}
+
+		body, err := decoder(encoding, r.Body)
+		if err != nil {
+			response.WriteProblem(w, r, err)
+			return
+		}
+		defer body.Close()
+
+		//? Buffer one byte past the limit to tell a body of exactly limit bytes from a longer one
+		data, err := io.ReadAll(io.LimitReader(body, limit+1))
+		if err != nil {
+			response.WriteProblem(w, r, apperr.BadRequest(apperr.CodeMalformedBody, "reading body: %v", err))
+			return
+		}
+		if int64(len(data)) > limit {
+			response.WriteProblem(w, r, tooLarge(limit))
+			return
+		}
+
+		//? Handlers see a plain body, as if it had been sent uncompressed
+		r.Body = io.NopCloser(bytes.NewReader(data))
+		r.ContentLength = int64(len(data))
+		r.Header.Del("Content-Encoding")
+		r.Header.Set("Content-Length", strconv.Itoa(len(data)))
+		next(w, r)
+	}
+}
+
+func decoder(encoding string, body io.ReadCloser) (io.ReadCloser, error) {
+	switch encoding {
+	case "", "identity":
+		return body, nil
+	case Gzip, "x-gzip":
+		zr, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, apperr.BadRequest(apperr.CodeMalformedBody, "malformed gzip body: %v", err)
+		}
+		return zr, nil
+	case Zstd:
+		zr, err := zstd.NewReader(body, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, apperr.BadRequest(apperr.CodeMalformedBody, "malformed zstd body: %v", err)
+		}
+		return zr.IOReadCloser(), nil
+	case Brotli:
+		return io.NopCloser(brotli.NewReader(body)), nil
+	default:
+		return nil, apperr.New(apperr.KindUnsupportedMediaType, apperr.CodeUnsupportedEncoding,
+			"unsupported Content-Encoding %q, use gzip, zstd or br", encoding)
+	}
+}
+
+func tooLarge(limit int64) error {
+	return apperr.New(apperr.KindTooLarge, apperr.CodeBodyTooLarge, "request body exceeds %d bytes", limit)
+}
@@ -0,0 +1,236 @@
+// Package compress negotiates response compression from Accept-Encoding and decodes compressed request bodies.
+package compress
+
+import (
+	"compress/gzip"
+	"fmt"
+	"github/MahfujulSagor/movies_crud/internals/config"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	Gzip   = "gzip"
+	Zstd   = "zstd"
+	Brotli = "br"
+)
+
+// encoder compresses into the writer given to Reset; Flush pushes out what was written so far
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// pools reuse encoders, which are costly to allocate, especially zstd's
+var pools = map[string]*sync.Pool{
+	Gzip: {New: func() any { return gzip.NewWriter(io.Discard) }},
+	Zstd: {New: func() any {
+		enc, _ := zstd.NewWriter(io.Discard, zstd.WithEncoderConcurrency(1))
+		return enc
+	}},
+	Brotli: {New: func() any { return brotli.NewWriterLevel(io.Discard, brotli.DefaultCompression) }},
+}
+
+// incompressible content types are already compressed or must reach the client unbuffered
+var incompressible = []string{"text/event-stream", "image/", "video/", "audio/", "application/zip", "application/gzip", "application/zstd"}
+
+// Compressor compresses responses of at least min_size bytes with the first of its algorithms the client accepts
+type Compressor struct {
+	algorithms []string
+	minSize    int
+}
+
+func New(cfg *config.Config) (*Compressor, error) {
+	for _, name := range cfg.HTTPConfig.Compression.Algorithms {
+		if _, ok := pools[name]; !ok {
+			return nil, fmt.Errorf("http.compression: unknown algorithm %q", name)
+		}
+	}
+	return &Compressor{algorithms: cfg.HTTPConfig.Compression.Algorithms, minSize: cfg.HTTPConfig.Compression.MinSize}, nil
+}
+
+func (c *Compressor) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		//? Upgraded connections such as WebSockets need the unwrapped writer to hijack
+		if r.Header.Get("Upgrade") != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Accept-Encoding")
+		encoding := c.negotiate(r.Header.Get("Accept-Encoding"))
+		if encoding == "" || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &writer{ResponseWriter: w, encoding: encoding, minSize: c.minSize}
+		defer cw.close()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// negotiate picks the algorithm with the highest q-value, preferring earlier ones on ties; "" means identity
+func (c *Compressor) negotiate(acceptEncoding string) string {
+	weights := map[string]float64{}
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		weights[name] = q
+	}
+
+	best, bestQ := "", 0.0
+	for _, name := range c.algorithms {
+		q, ok := weights[name]
+		if !ok {
+			q = weights["*"]
+		}
+		if q > bestQ {
+			best, bestQ = name, q
+		}
+	}
+	return best
+}
+
+// writer holds back the first minSize bytes, so small responses go out uncompressed,
+// and decides on compression once it has seen the status, headers and that much body
+type writer struct {
+	http.ResponseWriter
+	encoding string
+	minSize  int
+	status   int
+	buf      []byte
+	decided  bool
+	enc      encoder
+}
+
+func (w *writer) WriteHeader(status int) {
+	//? Informational responses are sent straight away and do not end the header phase
+	if w.decided || status < http.StatusOK {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *writer) Write(b []byte) (int, error) {
+	if w.decided {
+		if w.enc != nil {
+			return w.enc.Write(b)
+		}
+		return w.ResponseWriter.Write(b)
+	}
+
+	w.buf = append(w.buf, b...)
+	if len(w.buf) >= w.minSize {
+		if err := w.start(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// Flush ends buffering, so streamed responses reach the client as they are written
+func (w *writer) Flush() {
+	if !w.decided {
+		w.start(len(w.buf) > 0)
+	}
+	if w.enc != nil {
+		w.enc.Flush()
+	}
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the connection's writer
+func (w *writer) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// start sends the headers, compressing the body if compress is set and the response allows it
+func (w *writer) start(compress bool) error {
+	w.decided = true
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	h := w.Header()
+	//? net/http would otherwise sniff the compressed bytes
+	if h.Get("Content-Type") == "" && len(w.buf) > 0 {
+		h.Set("Content-Type", http.DetectContentType(w.buf))
+	}
+	if compress && w.compressible() {
+		h.Set("Content-Encoding", w.encoding)
+		h.Del("Content-Length")
+		w.enc = pools[w.encoding].Get().(encoder)
+		w.enc.Reset(w.ResponseWriter)
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	var err error
+	if w.enc != nil {
+		_, err = w.enc.Write(buf)
+	} else {
+		_, err = w.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+func (w *writer) compressible() bool {
+	if w.status == http.StatusNoContent || w.status == http.StatusNotModified {
+		return false
+	}
+	h := w.Header()
+	if h.Get("Content-Encoding") != "" {
+		return false
+	}
+	contentType := h.Get("Content-Type")
+	for _, prefix := range incompressible {
+		if strings.HasPrefix(contentType, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// close sends what is still held back, uncompressed if it never reached minSize, and returns the encoder to its pool
+func (w *writer) close() {
+	if !w.decided {
+		//? Nothing was written, so there is nothing to send
+		if w.status == 0 && len(w.buf) == 0 {
+			return
+		}
+		w.start(false)
+	}
+	if w.enc != nil {
+		w.enc.Close()
+		w.enc.Reset(io.Discard)
+		pools[w.encoding].Put(w.enc)
+		w.enc = nil
+	}
+}
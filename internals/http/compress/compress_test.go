@@ -0,0 +1,238 @@
+package compress
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"github/MahfujulSagor/movies_crud/internals/apperr"
+	"github/MahfujulSagor/movies_crud/internals/config"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+func testCompressor(t *testing.T) *Compressor {
+	t.Helper()
+	cfg := &config.Config{}
+	cfg.HTTPConfig.Compression.Algorithms = []string{Zstd, Brotli, Gzip}
+	cfg.HTTPConfig.Compression.MinSize = 100
+	c, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func decode(t *testing.T, encoding string, body []byte) string {
+	t.Helper()
+	var r io.Reader
+	switch encoding {
+	case "":
+		r = bytes.NewReader(body)
+	case Gzip:
+		zr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = zr
+	case Zstd:
+		zr, err := zstd.NewReader(bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer zr.Close()
+		r = zr
+	case Brotli:
+		r = brotli.NewReader(bytes.NewReader(body))
+	default:
+		t.Fatalf("unexpected Content-Encoding %q", encoding)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("decoding %s: %v", encoding, err)
+	}
+	return string(data)
+}
+
+func TestNegotiate(t *testing.T) {
+	c := testCompressor(t)
+	for accept, want := range map[string]string{
+		"":                               "",
+		"identity":                       "",
+		"gzip":                           Gzip,
+		"gzip, deflate, br":              Brotli,
+		"gzip, br, zstd":                 Zstd,
+		"zstd;q=0.5, br;q=0.8, gzip;q=1": Gzip,
+		"BR;q=0.9, GZIP;q=0.9":           Brotli,
+		"*":                              Zstd,
+		"zstd;q=0, *;q=0.5":              Brotli,
+		"gzip;q=0":                       "",
+		"gzip;q=abc, br":                 Brotli,
+		" gzip ; q=0.3 , zstd ; q=0.2 ":  Gzip,
+		"deflate, compress":              "",
+	} {
+		if got := c.negotiate(accept); got != want {
+			t.Errorf("negotiate(%q) = %q, want %q", accept, got, want)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	c := testCompressor(t)
+	large := strings.Repeat(`{"name": "Heat", "rating": 8}`, 20)
+
+	for _, tc := range []struct {
+		name         string
+		accept       string
+		method       string
+		contentType  string
+		preEncoded   string
+		status       int
+		body         string
+		wantEncoding string
+	}{
+		{"zstd", "gzip, zstd", http.MethodGet, "application/json", "", http.StatusOK, large, Zstd},
+		{"brotli", "br", http.MethodGet, "application/json", "", http.StatusOK, large, Brotli},
+		{"gzip", "gzip", http.MethodGet, "application/json", "", http.StatusOK, large, Gzip},
+		{"identity", "", http.MethodGet, "application/json", "", http.StatusOK, large, ""},
+		{"below min_size", "gzip", http.MethodGet, "application/json", "", http.StatusOK, `{"name": "Heat"}`, ""},
+		{"already encoded", "gzip", http.MethodGet, "application/json", Zstd, http.StatusOK, large, Zstd},
+		{"event stream", "gzip", http.MethodGet, "text/event-stream", "", http.StatusOK, large, ""},
+		{"image", "gzip", http.MethodGet, "image/png", "", http.StatusOK, large, ""},
+		{"no content", "gzip", http.MethodDelete, "", "", http.StatusNoContent, "", ""},
+		{"head", "gzip", http.MethodHead, "application/json", "", http.StatusOK, "", ""},
+	} {
+		handler := c.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if tc.contentType != "" {
+				w.Header().Set("Content-Type", tc.contentType)
+			}
+			if tc.preEncoded != "" {
+				w.Header().Set("Content-Encoding", tc.preEncoded)
+			}
+			w.WriteHeader(tc.status)
+			//? Written in two pieces, so the first alone may be below min_size
+			io.WriteString(w, tc.body[:len(tc.body)/2])
+			io.WriteString(w, tc.body[len(tc.body)/2:])
+		}))
+
+		r := httptest.NewRequest(tc.method, "/api/v1/movies", nil)
+		if tc.accept != "" {
+			r.Header.Set("Accept-Encoding", tc.accept)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, r)
+
+		encoding := rec.Header().Get("Content-Encoding")
+		if rec.Code != tc.status || encoding != tc.wantEncoding {
+			t.Errorf("%s: got %d with Content-Encoding %q, want %d %q", tc.name, rec.Code, encoding, tc.status, tc.wantEncoding)
+			continue
+		}
+		if rec.Header().Get("Vary") != "Accept-Encoding" {
+			t.Errorf("%s: Vary %q, want Accept-Encoding", tc.name, rec.Header().Values("Vary"))
+		}
+		if tc.preEncoded == "" && tc.body != "" {
+			if got := decode(t, encoding, rec.Body.Bytes()); got != tc.body {
+				t.Errorf("%s: body does not round-trip", tc.name)
+			}
+		}
+		if encoding != "" && rec.Header().Get("Content-Length") != "" {
+			t.Errorf("%s: compressed response kept Content-Length", tc.name)
+		}
+	}
+}
+
+func TestHandlerFlushesStreams(t *testing.T) {
+	c := testCompressor(t)
+	flushed := make(chan string, 1)
+
+	handler := c.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		io.WriteString(w, `{"id": 1}`+"\n")
+		//? Below min_size, but Flush must still push it to the client
+		http.NewResponseController(w).Flush()
+		flushed <- w.Header().Get("Content-Encoding")
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/movies/export", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, r)
+
+	if encoding := <-flushed; encoding != Gzip || !rec.Flushed {
+		t.Fatalf("flush sent encoding %q and flushed %v, want a flushed gzip stream", encoding, rec.Flushed)
+	}
+	if got := decode(t, Gzip, rec.Body.Bytes()); got != `{"id": 1}`+"\n" {
+		t.Fatalf("stream decoded to %q", got)
+	}
+}
+
+func TestNewRejectsUnknownAlgorithms(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.HTTPConfig.Compression.Algorithms = []string{Gzip, "deflate"}
+	if _, err := New(cfg); err == nil {
+		t.Fatal("deflate was accepted")
+	}
+}
+
+func gzipped(t *testing.T, s string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	io.WriteString(zw, s)
+	zw.Close()
+	return buf.Bytes()
+}
+
+func TestBody(t *testing.T) {
+	const limit = 64
+	small := `{"name": "Heat"}`
+
+	for _, tc := range []struct {
+		name     string
+		encoding string
+		body     []byte
+		status   int
+		code     string
+	}{
+		{"plain", "", []byte(small), http.StatusOK, ""},
+		{"gzip", "gzip", gzipped(t, small), http.StatusOK, ""},
+		{"exactly the limit", "", bytes.Repeat([]byte("a"), limit), http.StatusOK, ""},
+		{"plain over the limit", "", bytes.Repeat([]byte("a"), limit+1), http.StatusRequestEntityTooLarge, apperr.CodeBodyTooLarge},
+		//? Compresses far below the limit but expands past it
+		{"gzip bomb", "gzip", gzipped(t, strings.Repeat("a", 10*limit)), http.StatusRequestEntityTooLarge, apperr.CodeBodyTooLarge},
+		{"malformed gzip", "gzip", []byte(small), http.StatusBadRequest, apperr.CodeMalformedBody},
+		{"unknown encoding", "deflate", []byte(small), http.StatusUnsupportedMediaType, apperr.CodeUnsupportedEncoding},
+	} {
+		var seen string
+		handler := Body(limit, func(w http.ResponseWriter, r *http.Request) {
+			data, _ := io.ReadAll(r.Body)
+			seen = string(data)
+			if r.Header.Get("Content-Encoding") != "" || r.ContentLength != int64(len(data)) {
+				t.Errorf("%s: handler saw Content-Encoding %q and length %d for %d bytes", tc.name, r.Header.Get("Content-Encoding"), r.ContentLength, len(data))
+			}
+		})
+
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/movies", bytes.NewReader(tc.body))
+		if tc.encoding != "" {
+			r.Header.Set("Content-Encoding", tc.encoding)
+		}
+		rec := httptest.NewRecorder()
+		handler(rec, r)
+
+		var problem struct {
+			Code string `json:"code"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &problem)
+		if rec.Code != tc.status || problem.Code != tc.code {
+			t.Errorf("%s: got %d %q, want %d %q", tc.name, rec.Code, problem.Code, tc.status, tc.code)
+		}
+		if tc.status == http.StatusOK && tc.encoding == "gzip" && seen != small {
+			t.Errorf("%s: handler read %q", tc.name, seen)
+		}
+	}
+}
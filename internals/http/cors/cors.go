@@ -0,0 +1,158 @@
+// Package cors lets browser apps on other origins call the API, answering preflight requests itself.
+package cors
+
+import (
+	"errors"
+	"fmt"
+	"github/MahfujulSagor/movies_crud/internals/config"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+type CORS struct {
+	origins          []string
+	anyOrigin        bool
+	methods          map[string]bool
+	headers          map[string]bool
+	anyHeader        bool
+	allowMethods     string
+	allowHeaders     string
+	exposeHeaders    string
+	allowCredentials bool
+	maxAge           string
+}
+
+func New(cfg *config.Config) (*CORS, error) {
+	cc := cfg.HTTPConfig.CORS
+	c := &CORS{
+		methods:          map[string]bool{},
+		headers:          map[string]bool{},
+		allowMethods:     strings.Join(cc.AllowedMethods, ", "),
+		allowHeaders:     strings.Join(cc.AllowedHeaders, ", "),
+		exposeHeaders:    strings.Join(cc.ExposedHeaders, ", "),
+		allowCredentials: cc.AllowCredentials,
+		maxAge:           strconv.Itoa(int(cc.MaxAge.Seconds())),
+	}
+
+	for _, origin := range cc.AllowedOrigins {
+		if origin == "*" {
+			c.anyOrigin = true
+			continue
+		}
+		u, err := url.Parse(strings.Replace(origin, "*.", "", 1))
+		if err != nil || u.Scheme == "" || u.Host == "" || u.Path != "" {
+			return nil, fmt.Errorf("http.cors: invalid origin %q, want scheme://host[:port]", origin)
+		}
+		c.origins = append(c.origins, strings.ToLower(origin))
+	}
+	//? Browsers refuse credentials with a wildcard, and reflecting any origin instead would let every site act as the user
+	if c.anyOrigin && c.allowCredentials {
+		return nil, errors.New("http.cors: allow_credentials cannot be combined with the \"*\" origin")
+	}
+
+	for _, method := range cc.AllowedMethods {
+		c.methods[strings.ToUpper(method)] = true
+	}
+	for _, header := range cc.AllowedHeaders {
+		if header == "*" {
+			c.anyHeader = true
+		}
+		c.headers[http.CanonicalHeaderKey(header)] = true
+	}
+
+	return c, nil
+}
+
+// Handler adds CORS headers for allowed origins and answers preflight requests with 204
+func (c *CORS) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+		h := w.Header()
+		h.Add("Vary", "Origin")
+		if preflight {
+			h.Add("Vary", "Access-Control-Request-Method")
+			h.Add("Vary", "Access-Control-Request-Headers")
+		}
+
+		if origin == "" || !c.allowedOrigin(origin) {
+			if preflight {
+				//? Without CORS headers the browser blocks the actual request
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if preflight {
+			if !c.methods[strings.ToUpper(r.Header.Get("Access-Control-Request-Method"))] || !c.allowedHeaders(r.Header.Get("Access-Control-Request-Headers")) {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			c.allowOrigin(h, origin)
+			h.Set("Access-Control-Allow-Methods", c.allowMethods)
+			if c.anyHeader {
+				h.Set("Access-Control-Allow-Headers", r.Header.Get("Access-Control-Request-Headers"))
+			} else if c.allowHeaders != "" {
+				h.Set("Access-Control-Allow-Headers", c.allowHeaders)
+			}
+			h.Set("Access-Control-Max-Age", c.maxAge)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		c.allowOrigin(h, origin)
+		if c.exposeHeaders != "" {
+			h.Set("Access-Control-Expose-Headers", c.exposeHeaders)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (c *CORS) allowOrigin(h http.Header, origin string) {
+	if c.anyOrigin {
+		h.Set("Access-Control-Allow-Origin", "*")
+		return
+	}
+	h.Set("Access-Control-Allow-Origin", origin)
+	if c.allowCredentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+func (c *CORS) allowedOrigin(origin string) bool {
+	if c.anyOrigin {
+		return true
+	}
+
+	origin = strings.ToLower(origin)
+	for _, allowed := range c.origins {
+		if allowed == origin {
+			return true
+		}
+		//? "https://*.example.com" matches any subdomain, but not example.com itself
+		if scheme, domain, ok := strings.Cut(allowed, "://*."); ok {
+			if rest, ok := strings.CutPrefix(origin, scheme+"://"); ok && strings.HasSuffix(rest, "."+domain) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// allowedHeaders reports whether every header in a preflight's Access-Control-Request-Headers is allowed
+func (c *CORS) allowedHeaders(requested string) bool {
+	if c.anyHeader {
+		return true
+	}
+	for _, header := range strings.Split(requested, ",") {
+		if header = strings.TrimSpace(header); header != "" && !c.headers[http.CanonicalHeaderKey(header)] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,164 @@
+package cors
+
+import (
+	"github/MahfujulSagor/movies_crud/internals/config"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+func testCORS(t *testing.T, origins []string, credentials bool, headers ...string) *CORS {
+	t.Helper()
+	cfg := &config.Config{}
+	cfg.HTTPConfig.CORS = config.CORSConfig{
+		AllowedOrigins:   origins,
+		AllowedMethods:   []string{"GET", "POST", "DELETE"},
+		AllowedHeaders:   append([]string{"Authorization", "Content-Type", "Idempotency-Key"}, headers...),
+		ExposedHeaders:   []string{"Location", "Retry-After"},
+		AllowCredentials: credentials,
+		MaxAge:           10 * time.Minute,
+	}
+	c, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// serve sends r through c and reports whether the wrapped handler ran
+func serve(c *CORS, r *http.Request) (*httptest.ResponseRecorder, bool) {
+	ran := false
+	rec := httptest.NewRecorder()
+	c.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ran = true
+		w.WriteHeader(http.StatusOK)
+	})).ServeHTTP(rec, r)
+	return rec, ran
+}
+
+func preflight(origin, method, headers string) *http.Request {
+	r := httptest.NewRequest(http.MethodOptions, "/api/v1/movies", nil)
+	r.Header.Set("Origin", origin)
+	r.Header.Set("Access-Control-Request-Method", method)
+	if headers != "" {
+		r.Header.Set("Access-Control-Request-Headers", headers)
+	}
+	return r
+}
+
+func TestPreflight(t *testing.T) {
+	c := testCORS(t, []string{"https://admin.example.com", "https://*.apps.example.com"}, false)
+
+	for _, tc := range []struct {
+		name, origin, method, headers string
+		allowed                       bool
+	}{
+		{"exact origin", "https://admin.example.com", "POST", "content-type, idempotency-key", true},
+		{"origin case", "HTTPS://Admin.Example.com", "delete", "", true},
+		{"subdomain wildcard", "https://team.apps.example.com", "GET", "Authorization", true},
+		{"wildcard excludes the bare domain", "https://apps.example.com", "GET", "", false},
+		{"lookalike domain", "https://evilapps.example.com", "GET", "", false},
+		{"other scheme", "http://admin.example.com", "GET", "", false},
+		{"other origin", "https://evil.example", "GET", "", false},
+		{"method not allowed", "https://admin.example.com", "PATCH", "", false},
+		{"header not allowed", "https://admin.example.com", "POST", "Content-Type, X-Debug", false},
+	} {
+		rec, ran := serve(c, preflight(tc.origin, tc.method, tc.headers))
+		h := rec.Header()
+
+		if ran || rec.Code != http.StatusNoContent {
+			t.Errorf("%s: preflight reached the handler or answered %d", tc.name, rec.Code)
+		}
+		if got := h.Get("Access-Control-Allow-Origin"); (got == tc.origin) != tc.allowed || !tc.allowed && got != "" {
+			t.Errorf("%s: Access-Control-Allow-Origin %q, allowed %v", tc.name, got, tc.allowed)
+		}
+		if tc.allowed && (h.Get("Access-Control-Allow-Methods") != "GET, POST, DELETE" ||
+			h.Get("Access-Control-Allow-Headers") != "Authorization, Content-Type, Idempotency-Key" || h.Get("Access-Control-Max-Age") != "600") {
+			t.Errorf("%s: preflight headers %v", tc.name, h)
+		}
+		//? Caches must key preflights on everything that decided the answer, allowed or not
+		if want := []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"}; !slices.Equal(h.Values("Vary"), want) {
+			t.Errorf("%s: Vary %q, want %q", tc.name, h.Values("Vary"), want)
+		}
+	}
+}
+
+func TestActualRequests(t *testing.T) {
+	c := testCORS(t, []string{"https://admin.example.com"}, false)
+
+	for _, tc := range []struct {
+		name, origin string
+		allowed      bool
+	}{
+		{"allowed origin", "https://admin.example.com", true},
+		{"other origin", "https://evil.example", false},
+		{"same origin", "", false},
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/movies", nil)
+		if tc.origin != "" {
+			r.Header.Set("Origin", tc.origin)
+		}
+		rec, ran := serve(c, r)
+		h := rec.Header()
+
+		//? The request always runs; only the browser decides whether the page may read the answer
+		if !ran {
+			t.Errorf("%s: handler did not run", tc.name)
+		}
+		if got := h.Get("Access-Control-Allow-Origin"); (got != "") != tc.allowed {
+			t.Errorf("%s: Access-Control-Allow-Origin %q", tc.name, got)
+		}
+		if got := h.Get("Access-Control-Expose-Headers"); (got == "Location, Retry-After") != tc.allowed {
+			t.Errorf("%s: Access-Control-Expose-Headers %q", tc.name, got)
+		}
+		if h.Get("Access-Control-Allow-Credentials") != "" {
+			t.Errorf("%s: credentials allowed without allow_credentials", tc.name)
+		}
+		if !slices.Equal(h.Values("Vary"), []string{"Origin"}) {
+			t.Errorf("%s: Vary %q, want Origin", tc.name, h.Values("Vary"))
+		}
+	}
+
+	//? A plain OPTIONS is not a preflight and reaches the router
+	r := httptest.NewRequest(http.MethodOptions, "/api/v1/movies", nil)
+	r.Header.Set("Origin", "https://admin.example.com")
+	if _, ran := serve(c, r); !ran {
+		t.Error("OPTIONS without Access-Control-Request-Method was answered as a preflight")
+	}
+}
+
+func TestCredentialsAndWildcards(t *testing.T) {
+	c := testCORS(t, []string{"https://admin.example.com"}, true)
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/movies", nil)
+	r.Header.Set("Origin", "https://admin.example.com")
+	rec, _ := serve(c, r)
+	if rec.Header().Get("Access-Control-Allow-Origin") != "https://admin.example.com" || rec.Header().Get("Access-Control-Allow-Credentials") != "true" {
+		t.Errorf("credentialed origin got %v", rec.Header())
+	}
+
+	//? "*" is sent literally rather than reflecting the caller's origin
+	c = testCORS(t, []string{"*"}, false, "*")
+	rec, _ = serve(c, preflight("https://anyone.example", "POST", "X-Custom, Content-Type"))
+	if rec.Header().Get("Access-Control-Allow-Origin") != "*" || rec.Header().Get("Access-Control-Allow-Headers") != "X-Custom, Content-Type" {
+		t.Errorf("wildcard preflight got %v", rec.Header())
+	}
+}
+
+func TestNewRejectsBadConfigs(t *testing.T) {
+	for _, tc := range []struct {
+		origins     []string
+		credentials bool
+	}{
+		{[]string{"*"}, true},
+		{[]string{"admin.example.com"}, false},
+		{[]string{"https://admin.example.com/app"}, false},
+	} {
+		cfg := &config.Config{}
+		cfg.HTTPConfig.CORS = config.CORSConfig{AllowedOrigins: tc.origins, AllowCredentials: tc.credentials}
+		if _, err := New(cfg); err == nil {
+			t.Errorf("New accepted origins %q with credentials %v", tc.origins, tc.credentials)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package apikeys
+
+import (
+	"fmt"
+	"github/MahfujulSagor/movies_crud/internals/apperr"
+	"github/MahfujulSagor/movies_crud/internals/auth"
+	"github/MahfujulSagor/movies_crud/internals/db"
+	"github/MahfujulSagor/movies_crud/internals/http/negotiate"
+	"github/MahfujulSagor/movies_crud/internals/logger"
+	"github/MahfujulSagor/movies_crud/internals/types"
+	"github/MahfujulSagor/movies_crud/internals/utils/response"
+	"github/MahfujulSagor/movies_crud/internals/validation"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type CreatedKey struct {
+	*types.APIKey
+	Key string `json:"key"`
+}
+
+func New(store db.APIKeyStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info.Println("Create API key handler called")
+
+		//? Decode JSON into APIKey struct; other formats get 415
+		var key types.APIKey
+		if err := negotiate.DecodeJSON(r, &key); err != nil {
+			response.WriteProblem(w, r, err)
+			logger.Error.Println("Error decoding API key:", err)
+			return
+		}
+		defer r.Body.Close()
+
+		//? Request validation
+		if err := validation.Request(r, key); err != nil {
+			response.WriteProblem(w, r, err)
+			logger.Error.Println("Validation error:", err)
+			return
+		}
+
+		if err := auth.ValidateScopes(key.Scopes); err != nil {
+			response.WriteProblem(w, r, apperr.Validation([]apperr.FieldError{{Field: "scopes", Rule: "scope", Message: err.Error()}}))
+			logger.Error.Println("Invalid scopes:", err)
+			return
+		}
+
+		created, err := Create(store, key.Name, key.Scopes)
+		if err != nil {
+			response.WriteProblem(w, r, apperr.FromDB(err, apperr.NotFound(apperr.CodeNotFound, "referenced record not found")))
+			logger.Error.Println("Failed to create API key:", err)
+			return
+		}
+
+		logger.Info.Println("API key created with ID:", created.ID)
+
+		//? The plaintext key is only ever returned here
+		response.WriteJson(w, http.StatusCreated, created)
+	}
+}
+
+func List(store db.APIKeyStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info.Println("List API keys handler called")
+
+		keys, err := store.ListAPIKeys()
+		if err != nil {
+			response.WriteProblem(w, r, apperr.Internal(err))
+			logger.Error.Println("Error listing API keys:", err)
+			return
+		}
+
+		if len(keys) == 0 {
+			response.WriteJson(w, http.StatusOK, []types.APIKey{})
+			return
+		}
+
+		response.WriteJson(w, http.StatusOK, keys)
+	}
+}
+
+func Revoke(store db.APIKeyStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info.Println("Revoke API key handler called")
+
+		//? Parse id from URL
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			response.WriteProblem(w, r, apperr.BadRequest(apperr.CodeInvalidID, "invalid ID %q", r.PathValue("id")))
+			logger.Error.Println("Error parsing ID:", err)
+			return
+		}
+
+		revoked_id, err := store.RevokeAPIKey(id)
+		if err != nil {
+			response.WriteProblem(w, r, apperr.FromDB(err, apperr.NotFound(apperr.CodeAPIKeyNotFound, "API key %d not found or already revoked", id)))
+			logger.Error.Println("Failed to revoke API key:", err)
+			return
+		}
+
+		response.WriteJson(w, http.StatusOK, response.Message{
+			Success: response.StatusOK,
+			Message: fmt.Sprintf("API key revoked with ID %d", revoked_id),
+		})
+	}
+}
+
+// Create generates and stores a new key; shared by the HTTP handler and the admin CLI
+func Create(store db.APIKeyStore, name string, scopes []string) (*CreatedKey, error) {
+	plaintext, prefix, err := auth.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	key := &types.APIKey{
+		Name:      name,
+		Prefix:    prefix,
+		Scopes:    scopes,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	id, err := store.CreateAPIKey(key, auth.HashKey(plaintext))
+	if err != nil {
+		return nil, err
+	}
+	key.ID = id
+
+	return &CreatedKey{APIKey: key, Key: plaintext}, nil
+}
@@ -0,0 +1,111 @@
+package apikeys
+
+import (
+	"encoding/json"
+	"github/MahfujulSagor/movies_crud/internals/apperr"
+	"github/MahfujulSagor/movies_crud/internals/auth"
+	"github/MahfujulSagor/movies_crud/internals/config"
+	"github/MahfujulSagor/movies_crud/internals/db/sqlite"
+	"github/MahfujulSagor/movies_crud/internals/logger"
+	"github/MahfujulSagor/movies_crud/internals/utils/response"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func init() {
+	logger.Info = log.New(io.Discard, "", 0)
+	logger.Error = log.New(io.Discard, "", 0)
+}
+
+func newStore(t *testing.T) *sqlite.SQLite {
+	t.Helper()
+	store, err := sqlite.New(&config.Config{DBPath: filepath.Join(t.TempDir(), "movies.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestNewReturnsTheKeyOnce(t *testing.T) {
+	store := newStore(t)
+
+	rec := httptest.NewRecorder()
+	New(store)(rec, httptest.NewRequest(http.MethodPost, "/api/v1/admin/api-keys",
+		strings.NewReader(`{"name": "ci", "scopes": ["movies:read"]}`)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+
+	var created CreatedKey
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(created.Key, created.Prefix) {
+		t.Fatalf("key %q does not start with its prefix %q", created.Key, created.Prefix)
+	}
+
+	//? Only the hash is stored, and listings never carry the key
+	stored, err := store.GetAPIKeyByHash(auth.HashKey(created.Key))
+	if err != nil || stored.ID != created.ID {
+		t.Fatalf("key not found by its hash: %v", err)
+	}
+	rec = httptest.NewRecorder()
+	List(store)(rec, httptest.NewRequest(http.MethodGet, "/api/v1/admin/api-keys", nil))
+	if strings.Contains(rec.Body.String(), created.Key) || !strings.Contains(rec.Body.String(), created.Prefix) {
+		t.Fatalf("listing %s should show the prefix but not the key", rec.Body)
+	}
+}
+
+func TestNewRejectsInvalidKeys(t *testing.T) {
+	for body, rule := range map[string]string{
+		`{"name": "ci", "scopes": ["movies:*"]}`: "scope",
+		`{"name": "ci", "scopes": []}`:           "min",
+		`{"scopes": ["movies:read"]}`:            "required",
+	} {
+		rec := httptest.NewRecorder()
+		New(newStore(t))(rec, httptest.NewRequest(http.MethodPost, "/api/v1/admin/api-keys", strings.NewReader(body)))
+
+		var problem response.Problem
+		json.Unmarshal(rec.Body.Bytes(), &problem)
+		if rec.Code != http.StatusBadRequest || problem.Code != apperr.CodeValidationFailed || len(problem.Errors) != 1 || problem.Errors[0].Rule != rule {
+			t.Errorf("%s: got %d %+v, want a %q validation error", body, rec.Code, problem, rule)
+		}
+	}
+}
+
+func TestRevoke(t *testing.T) {
+	store := newStore(t)
+	created, err := Create(store, "ci", []string{auth.ScopeMoviesRead})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	revoke := func(id string) (int, string) {
+		r := httptest.NewRequest(http.MethodDelete, "/api/v1/admin/api-keys/"+id, nil)
+		r.SetPathValue("id", id)
+		rec := httptest.NewRecorder()
+		Revoke(store)(rec, r)
+
+		var problem response.Problem
+		json.Unmarshal(rec.Body.Bytes(), &problem)
+		return rec.Code, problem.Code
+	}
+
+	id := strconv.FormatInt(created.ID, 10)
+	if status, _ := revoke(id); status != http.StatusOK {
+		t.Fatalf("revoking returned %d", status)
+	}
+	if status, code := revoke(id); status != http.StatusNotFound || code != apperr.CodeAPIKeyNotFound {
+		t.Fatalf("revoking twice returned %d %s, want 404 %s", status, code, apperr.CodeAPIKeyNotFound)
+	}
+	if status, code := revoke("abc"); status != http.StatusBadRequest || code != apperr.CodeInvalidID {
+		t.Fatalf("revoking a bad ID returned %d %s", status, code)
+	}
+}
@@ -0,0 +1,176 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"github/MahfujulSagor/movies_crud/internals/apperr"
+	"github/MahfujulSagor/movies_crud/internals/events"
+	"github/MahfujulSagor/movies_crud/internals/logger"
+	"github/MahfujulSagor/movies_crud/internals/utils/response"
+	"net/http"
+	"strconv"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+const heartbeat = 15 * time.Second
+
+// Stream serves the change feed as Server-Sent Events, resuming after Last-Event-ID
+func Stream(bus *events.Bus) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info.Println("Event stream handler called")
+
+		filter, after, err := parseQuery(r)
+		if err != nil {
+			response.WriteProblem(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+
+		rc := http.NewResponseController(w)
+		keepOpen(rc)
+
+		sub := bus.Subscribe(filter, after)
+		defer sub.Close()
+
+		for _, e := range sub.Backlog {
+			writeEvent(w, e)
+		}
+		if err := rc.Flush(); err != nil {
+			logger.Error.Println("Event stream does not support flushing:", err)
+			return
+		}
+
+		ticker := time.NewTicker(heartbeat)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case e, ok := <-sub.Events():
+				if !ok {
+					//? Dropped for falling behind or shutting down; the client reconnects with Last-Event-ID
+					return
+				}
+				writeEvent(w, e)
+			case <-ticker.C:
+				fmt.Fprint(w, ": ping\n\n")
+			}
+
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, e events.Event) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		logger.Error.Println("Error encoding event:", err)
+		return
+	}
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+}
+
+// WebSocket streams the same feed as JSON text frames; resume with ?last_event_id=
+func WebSocket(bus *events.Bus) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info.Println("Event websocket handler called")
+
+		filter, after, err := parseQuery(r)
+		if err != nil {
+			response.WriteProblem(w, r, err)
+			return
+		}
+
+		server := websocket.Server{
+			//? Callers are already authenticated, so any Origin is acceptable
+			Handshake: func(*websocket.Config, *http.Request) error { return nil },
+			Handler: func(ws *websocket.Conn) {
+				defer ws.Close()
+
+				sub := bus.Subscribe(filter, after)
+				defer sub.Close()
+
+				//? Reads only detect the peer closing; clients send nothing meaningful
+				closed := make(chan struct{})
+				go func() {
+					var discard []byte
+					for websocket.Message.Receive(ws, &discard) == nil {
+					}
+					close(closed)
+				}()
+
+				for _, e := range sub.Backlog {
+					if websocket.JSON.Send(ws, e) != nil {
+						return
+					}
+				}
+
+				for {
+					select {
+					case <-closed:
+						return
+					case <-r.Context().Done():
+						return
+					case e, ok := <-sub.Events():
+						if !ok || websocket.JSON.Send(ws, e) != nil {
+							return
+						}
+					}
+				}
+			},
+		}
+
+		//? The hijacked connection keeps whatever deadlines the server set
+		keepOpen(http.NewResponseController(w))
+		server.ServeHTTP(w, r)
+	}
+}
+
+// keepOpen lifts the server's read and write timeouts, which would otherwise cut long-lived streams off
+func keepOpen(rc *http.ResponseController) {
+	if err := rc.SetReadDeadline(time.Time{}); err != nil {
+		logger.Error.Println("Error clearing stream read deadline:", err)
+	}
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		logger.Error.Println("Error clearing stream write deadline:", err)
+	}
+}
+
+// parseQuery reads the movie_id/director_id filters and the resume point
+func parseQuery(r *http.Request) (events.Filter, uint64, error) {
+	var filter events.Filter
+	query := r.URL.Query()
+
+	for name, dst := range map[string]*int64{"movie_id": &filter.MovieID, "director_id": &filter.DirectorID} {
+		if v := query.Get(name); v != "" {
+			id, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || id <= 0 {
+				return filter, 0, apperr.BadRequest(apperr.CodeInvalidQuery, "invalid %s value %q", name, v)
+			}
+			*dst = id
+		}
+	}
+
+	last := r.Header.Get("Last-Event-ID")
+	if last == "" {
+		last = query.Get("last_event_id")
+	}
+	var after uint64
+	if last != "" {
+		var err error
+		if after, err = strconv.ParseUint(last, 10, 64); err != nil {
+			return filter, 0, apperr.BadRequest(apperr.CodeInvalidQuery, "invalid Last-Event-ID %q", last)
+		}
+	}
+
+	return filter, after, nil
+}
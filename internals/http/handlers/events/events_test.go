@@ -0,0 +1,180 @@
+package events
+
+import (
+	"bufio"
+	"encoding/json"
+	"github/MahfujulSagor/movies_crud/internals/apperr"
+	"github/MahfujulSagor/movies_crud/internals/events"
+	"github/MahfujulSagor/movies_crud/internals/logger"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+func init() {
+	logger.Info = log.New(io.Discard, "", 0)
+	logger.Error = log.New(io.Discard, "", 0)
+}
+
+// readEvent reads one SSE frame, skipping heartbeats, and returns its id, type and decoded data
+func readEvent(t *testing.T, r *bufio.Reader) (id, typ string, e events.Event) {
+	t.Helper()
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("stream ended: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "" && id != "":
+			return id, typ, e
+		case strings.HasPrefix(line, "id: "):
+			id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			typ = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &e); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestStreamResumesAndFilters(t *testing.T) {
+	bus := events.NewBus(10)
+	server := httptest.NewServer(Stream(bus))
+	defer server.Close()
+
+	bus.Publish(events.Event{Type: events.MovieCreated, MovieID: 1})
+	bus.Publish(events.Event{Type: events.MovieCreated, MovieID: 2})
+	bus.Publish(events.Event{Type: events.MovieUpdated, MovieID: 1})
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"?movie_id=1", nil)
+	req.Header.Set("Last-Event-ID", "1")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type %q", ct)
+	}
+	body := bufio.NewReader(resp.Body)
+
+	//? Only movie 1's update is newer than event 1 and matches the filter
+	if id, typ, e := readEvent(t, body); id != "3" || typ != events.MovieUpdated || e.MovieID != 1 {
+		t.Fatalf("backlog event %s %s %+v", id, typ, e)
+	}
+
+	//? The subscription exists once headers arrive, so live events follow the backlog
+	bus.Publish(events.Event{Type: events.MovieDeleted, MovieID: 2})
+	bus.Publish(events.Event{Type: events.MovieDeleted, MovieID: 1})
+	if id, typ, _ := readEvent(t, body); id != "5" || typ != events.MovieDeleted {
+		t.Fatalf("live event %s %s, want 5 %s", id, typ, events.MovieDeleted)
+	}
+
+	//? Closing the bus ends the stream so the client reconnects
+	bus.Close()
+	done := make(chan error, 1)
+	go func() {
+		_, err := io.Copy(io.Discard, body)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("stream ended with %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream stayed open after the bus closed")
+	}
+}
+
+func TestStreamRejectsBadQueries(t *testing.T) {
+	for _, tc := range []struct {
+		target, lastEventID string
+	}{
+		{"/?movie_id=abc", ""},
+		{"/?director_id=0", ""},
+		{"/", "soon"},
+		{"/?last_event_id=-1", ""},
+	} {
+		r := httptest.NewRequest(http.MethodGet, tc.target, nil)
+		if tc.lastEventID != "" {
+			r.Header.Set("Last-Event-ID", tc.lastEventID)
+		}
+		rec := httptest.NewRecorder()
+		Stream(events.NewBus(10))(rec, r)
+
+		var problem struct {
+			Code string `json:"code"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &problem)
+		if rec.Code != http.StatusBadRequest || problem.Code != apperr.CodeInvalidQuery {
+			t.Errorf("%s (Last-Event-ID %q): got %d %q", tc.target, tc.lastEventID, rec.Code, problem.Code)
+		}
+	}
+}
+
+func TestWebSocketResumesAndStreams(t *testing.T) {
+	bus := events.NewBus(10)
+	server := httptest.NewServer(WebSocket(bus))
+	defer server.Close()
+
+	bus.Publish(events.Event{Type: events.MovieCreated, MovieID: 1, DirectorID: 9})
+	bus.Publish(events.Event{Type: events.MovieUpdated, MovieID: 1, DirectorID: 9})
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/?director_id=9&last_event_id=1"
+	ws, err := websocket.Dial(url, "", server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+	ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	var e events.Event
+	if err := websocket.JSON.Receive(ws, &e); err != nil || e.ID != 2 || e.Type != events.MovieUpdated {
+		t.Fatalf("backlog frame %+v, %v", e, err)
+	}
+
+	//? The backlog arrives after subscribing, so this is delivered live
+	bus.Publish(events.Event{Type: events.MovieCreated, MovieID: 3, DirectorID: 4})
+	bus.Publish(events.Event{Type: events.MovieDeleted, MovieID: 1, DirectorID: 9})
+	if err := websocket.JSON.Receive(ws, &e); err != nil || e.ID != 4 || e.Type != events.MovieDeleted {
+		t.Fatalf("live frame %+v, %v", e, err)
+	}
+
+	bus.Close()
+	if err := websocket.JSON.Receive(ws, &e); err != io.EOF {
+		t.Fatalf("closing the bus left the socket open: %v", err)
+	}
+}
+
+func TestStreamOutlivesServerTimeouts(t *testing.T) {
+	bus := events.NewBus(10)
+	server := httptest.NewUnstartedServer(Stream(bus))
+	server.Config.ReadTimeout = 50 * time.Millisecond
+	server.Config.WriteTimeout = 50 * time.Millisecond
+	server.Start()
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	//? Well past both deadlines, which the stream lifts for itself
+	time.Sleep(200 * time.Millisecond)
+	bus.Publish(events.Event{Type: events.MovieCreated, MovieID: 1})
+	if id, _, _ := readEvent(t, bufio.NewReader(resp.Body)); id != "1" {
+		t.Fatalf("got event %s, want 1", id)
+	}
+}
@@ -0,0 +1,121 @@
+package graphql
+
+import (
+	"github/MahfujulSagor/movies_crud/internals/apperr"
+	"strings"
+
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+const (
+	// maxDepth allows e.g. movies { director { movies { cast { actor } } } }
+	maxDepth = 5
+	// maxFields bounds the fields selected in total, fragments expanded, so aliases cannot multiply a query
+	maxFields = 200
+)
+
+// cost is how deep a selection nests and how many fields it selects
+type cost struct {
+	depth  int
+	fields int
+}
+
+// costs measures selections, expanding each named fragment once
+type costs struct {
+	fragments map[string]*ast.FragmentDefinition
+	measured  map[string]cost
+	visiting  map[string]bool
+}
+
+// checkComplexity rejects queries that nest deeper than maxDepth or select more than maxFields.
+// Queries that do not parse are left for graphql-go to report.
+func checkComplexity(query string) *apperr.Error {
+	doc, err := parser.Parse(parser.ParseParams{Source: query})
+	if err != nil {
+		return nil
+	}
+
+	c := &costs{fragments: map[string]*ast.FragmentDefinition{}, measured: map[string]cost{}, visiting: map[string]bool{}}
+	for _, def := range doc.Definitions {
+		if fragment, ok := def.(*ast.FragmentDefinition); ok {
+			c.fragments[fragment.Name.Value] = fragment
+		}
+	}
+
+	for _, def := range doc.Definitions {
+		op, ok := def.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		total, err := c.selectionSet(op.SelectionSet)
+		if err != nil {
+			return err
+		}
+		if total.depth > maxDepth {
+			return apperr.BadRequest(apperr.CodeQueryTooComplex, "query depth %d exceeds the limit of %d", total.depth, maxDepth)
+		}
+		if total.fields > maxFields {
+			return apperr.BadRequest(apperr.CodeQueryTooComplex, "query selects more than %d fields", maxFields)
+		}
+	}
+	return nil
+}
+
+func (c *costs) selectionSet(set *ast.SelectionSet) (cost, *apperr.Error) {
+	var total cost
+	if set == nil {
+		return total, nil
+	}
+
+	for _, selection := range set.Selections {
+		var sub cost
+		var err *apperr.Error
+
+		switch s := selection.(type) {
+		case *ast.Field:
+			//? Introspection only reads the schema, and GraphiQL's own query nests deeply
+			if !strings.HasPrefix(s.Name.Value, "__") {
+				sub, err = c.selectionSet(s.SelectionSet)
+			}
+			sub.depth++
+			sub.fields++
+		case *ast.InlineFragment:
+			sub, err = c.selectionSet(s.SelectionSet)
+		case *ast.FragmentSpread:
+			sub, err = c.fragment(s.Name.Value)
+		}
+		if err != nil {
+			return total, err
+		}
+
+		total.depth = max(total.depth, sub.depth)
+		//? Saturate, since fragments spreading each other can describe exponentially many fields
+		total.fields = min(total.fields+sub.fields, maxFields+1)
+	}
+	return total, nil
+}
+
+func (c *costs) fragment(name string) (cost, *apperr.Error) {
+	if measured, ok := c.measured[name]; ok {
+		return measured, nil
+	}
+	fragment, ok := c.fragments[name]
+	if !ok {
+		//? Unknown fragments fail validation later
+		return cost{}, nil
+	}
+	if c.visiting[name] {
+		return cost{}, apperr.BadRequest(apperr.CodeBadRequest, "fragment %q spreads itself", name)
+	}
+
+	c.visiting[name] = true
+	measured, err := c.selectionSet(fragment.SelectionSet)
+	c.visiting[name] = false
+	if err != nil {
+		return cost{}, err
+	}
+
+	c.measured[name] = measured
+	return measured, nil
+}
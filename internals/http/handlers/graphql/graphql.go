@@ -0,0 +1,185 @@
+package graphql
+
+import (
+	"context"
+	_ "embed"
+	"github/MahfujulSagor/movies_crud/internals/apperr"
+	"github/MahfujulSagor/movies_crud/internals/db"
+	"github/MahfujulSagor/movies_crud/internals/http/negotiate"
+	"github/MahfujulSagor/movies_crud/internals/logger"
+	"github/MahfujulSagor/movies_crud/internals/types"
+	"github/MahfujulSagor/movies_crud/internals/utils/response"
+	"github/MahfujulSagor/movies_crud/internals/validation"
+	"net/http"
+
+	gql "github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+)
+
+//go:embed graphiql.html
+var playground []byte
+
+// Request is a GraphQL-over-HTTP POST body
+type Request struct {
+	Query         string         `json:"query" validate:"required"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+}
+
+// Result is the response envelope; errors carry the stable API code in extensions.code
+type Result struct {
+	Data   any     `json:"data"`
+	Errors []Error `json:"errors,omitempty"`
+}
+
+type Error struct {
+	Message    string         `json:"message"`
+	Path       []any          `json:"path,omitempty"`
+	Extensions map[string]any `json:"extensions,omitempty"`
+}
+
+type loaders struct {
+	directors        *loader[*types.Director]
+	moviesByDirector *loader[[]*types.Movie]
+	moviesByCast     *loader[[]*types.Movie]
+}
+
+type loadersKey struct{}
+type localeKey struct{}
+
+// newLoaders is created per request so batched results are never shared between callers. Nested
+// movie lists are capped at maxLimit per director or cast, like top-level lists.
+func newLoaders(store Store) *loaders {
+	return &loaders{
+		directors: newLoader(func(ctx context.Context, ids []int64) (map[int64]*types.Director, error) {
+			directors, err := store.GetDirectorsByIDs(ctx, ids)
+			if err != nil {
+				return nil, err
+			}
+			byID := make(map[int64]*types.Director, len(directors))
+			for _, d := range directors {
+				byID[d.ID] = d
+			}
+			return byID, nil
+		}),
+		moviesByDirector: newLoader(func(ctx context.Context, ids []int64) (map[int64][]*types.Movie, error) {
+			movies, err := store.FindMovies(ctx, db.MovieFilter{DirectorIDs: ids, PerGroupLimit: maxLimit}, 0, 0)
+			return groupBy(ids, movies, func(m *types.Movie) int64 { return m.Director.ID }), err
+		}),
+		moviesByCast: newLoader(func(ctx context.Context, ids []int64) (map[int64][]*types.Movie, error) {
+			movies, err := store.FindMovies(ctx, db.MovieFilter{CastIDs: ids, PerGroupLimit: maxLimit}, 0, 0)
+			return groupBy(ids, movies, func(m *types.Movie) int64 { return m.Cast.ID }), err
+		}),
+	}
+}
+
+// groupBy buckets movies by key, giving every requested key at least an empty list
+func groupBy(keys []int64, movies []*types.Movie, key func(*types.Movie) int64) map[int64][]*types.Movie {
+	grouped := make(map[int64][]*types.Movie, len(keys))
+	for _, k := range keys {
+		grouped[k] = []*types.Movie{}
+	}
+	for _, m := range movies {
+		grouped[key(m)] = append(grouped[key(m)], m)
+	}
+	return grouped
+}
+
+func loadersFrom(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
+
+func localeFrom(ctx context.Context) string {
+	locale, _ := ctx.Value(localeKey{}).(string)
+	return locale
+}
+
+func Handler(schema gql.Schema, store Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info.Println("GraphQL handler called")
+
+		var req Request
+		if err := negotiate.DecodeJSON(r, &req); err != nil {
+			response.WriteProblem(w, r, err)
+			logger.Error.Println("Error decoding GraphQL request:", err)
+			return
+		}
+		defer r.Body.Close()
+
+		if err := validation.Request(r, req); err != nil {
+			response.WriteProblem(w, r, err)
+			return
+		}
+
+		//? Refuse deep or wide queries before any resolver runs
+		if err := checkComplexity(req.Query); err != nil {
+			response.WriteJson(w, http.StatusOK, Result{Errors: []Error{{
+				Message:    err.Detail,
+				Extensions: map[string]any{"code": err.Code},
+			}}})
+			logger.Error.Println("GraphQL query rejected:", err)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), loadersKey{}, newLoaders(store))
+		ctx = context.WithValue(ctx, localeKey{}, validation.Locale(r))
+
+		result := gql.Do(gql.Params{
+			Schema:         schema,
+			RequestString:  req.Query,
+			OperationName:  req.OperationName,
+			VariableValues: req.Variables,
+			Context:        ctx,
+		})
+
+		out := Result{Data: result.Data}
+		for _, err := range result.Errors {
+			out.Errors = append(out.Errors, formatError(err))
+		}
+
+		response.WriteJson(w, http.StatusOK, out)
+	}
+}
+
+// playgroundCSP lets GraphiQL load React and its own assets from unpkg; the default page policy
+// allows nothing from other origins
+const playgroundCSP = "default-src 'self'; script-src 'self' 'unsafe-inline' https://unpkg.com; " +
+	"style-src 'self' 'unsafe-inline' https://unpkg.com; font-src 'self' data: https://unpkg.com; img-src 'self' data:; frame-ancestors 'none'"
+
+// Playground serves GraphiQL; it is only mounted in development
+func Playground() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Content-Security-Policy", playgroundCSP)
+		w.Write(playground)
+	}
+}
+
+// formatError maps resolver errors through apperr so GraphQL and REST clients see the same codes
+func formatError(err gqlerrors.FormattedError) Error {
+	out := Error{Message: err.Message, Path: err.Path}
+
+	var cause error
+	if original, ok := err.OriginalError().(*gqlerrors.Error); ok {
+		cause = original.OriginalError
+	}
+	if cause == nil {
+		//? Syntax and schema validation errors
+		out.Extensions = map[string]any{"code": apperr.CodeBadRequest}
+		return out
+	}
+
+	appErr := apperr.From(cause)
+	//? Internal errors and storage conflicts reach the client as a fixed message, so log the cause
+	if appErr.Kind == apperr.KindInternal || appErr.Kind == apperr.KindConflict {
+		logger.Error.Println("GraphQL resolver error:", cause)
+	}
+
+	out.Message = appErr.Detail
+	out.Extensions = map[string]any{"code": appErr.Code}
+	if len(appErr.Fields) > 0 {
+		out.Extensions["fields"] = appErr.Fields
+	}
+
+	return out
+}
@@ -0,0 +1,163 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github/MahfujulSagor/movies_crud/internals/apperr"
+	"github/MahfujulSagor/movies_crud/internals/config"
+	"github/MahfujulSagor/movies_crud/internals/db/sqlite"
+	"github/MahfujulSagor/movies_crud/internals/logger"
+	"github/MahfujulSagor/movies_crud/internals/types"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func init() {
+	logger.Info = log.New(io.Discard, "", 0)
+	logger.Error = log.New(io.Discard, "", 0)
+}
+
+func TestCheckComplexity(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		query string
+		ok    bool
+	}{
+		{"nested up to the limit", `{ movies { director { movies { cast { actor } } } } }`, true},
+		{"too deep", `{ movies { director { movies { cast { movies { id } } } } } }`, false},
+		{"too deep through fragments", `
+			{ movies { ...withDirector } }
+			fragment withDirector on Movie { director { movies { ...withCast } } }
+			fragment withCast on Movie { cast { movies { id } } }`, false},
+		{"too many aliases", "{ " + aliases(120) + " }", false},
+		{"fragments doubling", doublingFragments(20), false},
+		{"introspection", `{ __schema { types { fields { type { ofType { ofType { ofType { name } } } } } } } }`, true},
+		{"syntax error is left to graphql-go", `{ movies { `, true},
+	} {
+		err := checkComplexity(tc.query)
+		if tc.ok && err != nil {
+			t.Errorf("%s: rejected: %v", tc.name, err)
+		}
+		if !tc.ok && (err == nil || err.Code != apperr.CodeQueryTooComplex) {
+			t.Errorf("%s: got %v, want %s", tc.name, err, apperr.CodeQueryTooComplex)
+		}
+	}
+}
+
+// aliases selects movies n times under different names
+func aliases(n int) string {
+	var b strings.Builder
+	for i := range n {
+		fmt.Fprintf(&b, "a%d: movies { id } ", i)
+	}
+	return b.String()
+}
+
+// doublingFragments nests n fragments that each spread the next one twice, 2^n fields when expanded
+func doublingFragments(n int) string {
+	var b strings.Builder
+	b.WriteString("{ movies { ...f0 } }\n")
+	for i := range n {
+		fmt.Fprintf(&b, "fragment f%d on Movie { a: id ...f%d ...f%d }\n", i, i+1, i+1)
+	}
+	fmt.Fprintf(&b, "fragment f%d on Movie { id }\n", n)
+	return b.String()
+}
+
+func query(t *testing.T, handler http.Handler, q string) Result {
+	t.Helper()
+	body, _ := json.Marshal(Request{Query: q})
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body))))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+
+	var result Result
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestNestedMoviesAreCappedPerDirector(t *testing.T) {
+	store, err := sqlite.New(&config.Config{DBPath: filepath.Join(t.TempDir(), "movies.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	counts := map[string]int{"Prolific": maxLimit + 5, "Occasional": 3}
+	ids := map[string]int64{}
+	for director, n := range counts {
+		for i := range n {
+			movie := &types.Movie{
+				Title:    fmt.Sprintf("%s %d", director, i),
+				Rating:   5,
+				Director: &types.Director{Name: director, Age: 60},
+				Cast:     &types.Cast{Actor: "Actor", Actress: "Actress"},
+			}
+			id, err := store.CreateMovie(context.Background(), movie)
+			if err != nil {
+				t.Fatal(err)
+			}
+			created, err := store.GetMovieByID(context.Background(), id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids[director] = created.Director.ID
+		}
+	}
+
+	schema, err := NewSchema(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := query(t, Handler(schema, store), fmt.Sprintf(
+		`{ prolific: director(id: %d) { movies { id } } occasional: director(id: %d) { movies { id } } }`,
+		ids["Prolific"], ids["Occasional"]))
+	if len(result.Errors) > 0 {
+		t.Fatal(result.Errors)
+	}
+
+	data := result.Data.(map[string]any)
+	for alias, want := range map[string]int{"prolific": maxLimit, "occasional": 3} {
+		movies := data[alias].(map[string]any)["movies"].([]any)
+		if len(movies) != want {
+			t.Errorf("%s director has %d movies, want %d", alias, len(movies), want)
+		}
+	}
+
+	//? The shared cast appears in every movie, so it is capped too
+	result = query(t, Handler(schema, store), `{ movies(limit: 1) { cast { movies { id } } } }`)
+	if len(result.Errors) > 0 {
+		t.Fatal(result.Errors)
+	}
+	cast := result.Data.(map[string]any)["movies"].([]any)[0].(map[string]any)["cast"].(map[string]any)
+	if n := len(cast["movies"].([]any)); n != maxLimit {
+		t.Errorf("cast has %d movies, want %d", n, maxLimit)
+	}
+}
+
+func TestHandlerRejectsDeepQueries(t *testing.T) {
+	store, err := sqlite.New(&config.Config{DBPath: filepath.Join(t.TempDir(), "movies.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	schema, err := NewSchema(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := query(t, Handler(schema, store), `{ movies { director { movies { director { movies { id } } } } } }`)
+	if result.Data != nil || len(result.Errors) != 1 || result.Errors[0].Extensions["code"] != apperr.CodeQueryTooComplex {
+		t.Fatalf("deep query returned %+v, want only a %s error", result, apperr.CodeQueryTooComplex)
+	}
+}
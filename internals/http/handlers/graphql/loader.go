@@ -0,0 +1,90 @@
+package graphql
+
+import (
+	"context"
+	"sync"
+)
+
+// loader batches lookups made while resolving one level of a query into a single fetch.
+// Load registers the key and returns a thunk; graphql-go runs thunks only after every
+// sibling resolver has been called, so the first thunk fetches all pending keys at once.
+type loader[V any] struct {
+	fetch func(ctx context.Context, keys []int64) (map[int64]V, error)
+
+	mu      sync.Mutex
+	pending []int64
+	loaded  map[int64]bool
+	results map[int64]V
+	errs    map[int64]error
+}
+
+func newLoader[V any](fetch func(ctx context.Context, keys []int64) (map[int64]V, error)) *loader[V] {
+	return &loader[V]{
+		fetch:   fetch,
+		loaded:  map[int64]bool{},
+		results: map[int64]V{},
+		errs:    map[int64]error{},
+	}
+}
+
+func (l *loader[V]) Load(ctx context.Context, key int64) func() (any, error) {
+	l.mu.Lock()
+	if !l.loaded[key] {
+		l.pending = append(l.pending, key)
+	}
+	l.mu.Unlock()
+
+	return func() (any, error) {
+		l.dispatch(ctx)
+
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if err := l.errs[key]; err != nil {
+			return nil, err
+		}
+		//? Missing keys resolve to null rather than a typed nil inside an interface
+		value, ok := l.results[key]
+		if !ok {
+			return nil, nil
+		}
+		return value, nil
+	}
+}
+
+func (l *loader[V]) dispatch(ctx context.Context) {
+	l.mu.Lock()
+	keys := unique(l.pending)
+	l.pending = nil
+	l.mu.Unlock()
+
+	if len(keys) == 0 {
+		return
+	}
+
+	results, err := l.fetch(ctx, keys)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, key := range keys {
+		l.loaded[key] = true
+		if err != nil {
+			l.errs[key] = err
+			continue
+		}
+		if value, ok := results[key]; ok {
+			l.results[key] = value
+		}
+	}
+}
+
+func unique(keys []int64) []int64 {
+	seen := make(map[int64]bool, len(keys))
+	out := keys[:0]
+	for _, k := range keys {
+		if !seen[k] {
+			seen[k] = true
+			out = append(out, k)
+		}
+	}
+	return out
+}
@@ -0,0 +1,294 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+	"github/MahfujulSagor/movies_crud/internals/apperr"
+	"github/MahfujulSagor/movies_crud/internals/auth"
+	"github/MahfujulSagor/movies_crud/internals/db"
+	"github/MahfujulSagor/movies_crud/internals/types"
+	"github/MahfujulSagor/movies_crud/internals/validation"
+
+	gql "github.com/graphql-go/graphql"
+)
+
+const (
+	defaultLimit = 10
+	maxLimit     = 50
+)
+
+type Store interface {
+	db.DB
+	db.MovieFinder
+}
+
+// NewSchema builds the schema; resolvers take their batching loaders and locale from the request context
+func NewSchema(store Store) (gql.Schema, error) {
+	movieType := gql.NewObject(gql.ObjectConfig{
+		Name: "Movie",
+		Fields: gql.Fields{
+			"id":          &gql.Field{Type: gql.NewNonNull(gql.Int), Resolve: movieField(func(m *types.Movie) any { return m.ID })},
+			"title":       &gql.Field{Type: gql.NewNonNull(gql.String), Resolve: movieField(func(m *types.Movie) any { return m.Title })},
+			"rating":      &gql.Field{Type: gql.NewNonNull(gql.Int), Resolve: movieField(func(m *types.Movie) any { return m.Rating })},
+			"releaseYear": &gql.Field{Type: gql.Int, Resolve: movieField(func(m *types.Movie) any { return nullableInt(m.ReleaseYear) })},
+			"createdAt":   &gql.Field{Type: gql.NewNonNull(gql.DateTime), Resolve: movieField(func(m *types.Movie) any { return m.CreatedAt })},
+			"updatedAt":   &gql.Field{Type: gql.NewNonNull(gql.DateTime), Resolve: movieField(func(m *types.Movie) any { return m.UpdatedAt })},
+		},
+	})
+
+	directorType := gql.NewObject(gql.ObjectConfig{
+		Name: "Director",
+		Fields: gql.Fields{
+			"id":   &gql.Field{Type: gql.NewNonNull(gql.Int), Resolve: directorField(func(d *types.Director) any { return d.ID })},
+			"name": &gql.Field{Type: gql.NewNonNull(gql.String), Resolve: directorField(func(d *types.Director) any { return d.Name })},
+			"age":  &gql.Field{Type: gql.NewNonNull(gql.Int), Resolve: directorField(func(d *types.Director) any { return d.Age })},
+			"movies": &gql.Field{
+				Type:        gql.NewNonNull(gql.NewList(gql.NewNonNull(movieType))),
+				Description: "The first 50 movies by this director, batched across all directors in the query",
+				Resolve: func(p gql.ResolveParams) (any, error) {
+					director := p.Source.(*types.Director)
+					return loadersFrom(p.Context).moviesByDirector.Load(p.Context, director.ID), nil
+				},
+			},
+		},
+	})
+
+	castType := gql.NewObject(gql.ObjectConfig{
+		Name: "Cast",
+		Fields: gql.Fields{
+			"id":      &gql.Field{Type: gql.NewNonNull(gql.Int), Resolve: castField(func(c *types.Cast) any { return c.ID })},
+			"actor":   &gql.Field{Type: gql.NewNonNull(gql.String), Resolve: castField(func(c *types.Cast) any { return c.Actor })},
+			"actress": &gql.Field{Type: gql.NewNonNull(gql.String), Resolve: castField(func(c *types.Cast) any { return c.Actress })},
+			"movies": &gql.Field{
+				Type:        gql.NewNonNull(gql.NewList(gql.NewNonNull(movieType))),
+				Description: "The first 50 movies with this cast, batched across all casts in the query",
+				Resolve: func(p gql.ResolveParams) (any, error) {
+					cast := p.Source.(*types.Cast)
+					return loadersFrom(p.Context).moviesByCast.Load(p.Context, cast.ID), nil
+				},
+			},
+		},
+	})
+
+	movieType.AddFieldConfig("director", &gql.Field{Type: gql.NewNonNull(directorType), Resolve: movieField(func(m *types.Movie) any { return m.Director })})
+	movieType.AddFieldConfig("cast", &gql.Field{Type: gql.NewNonNull(castType), Resolve: movieField(func(m *types.Movie) any { return m.Cast })})
+
+	filterType := gql.NewInputObject(gql.InputObjectConfig{
+		Name: "MovieFilter",
+		Fields: gql.InputObjectConfigFieldMap{
+			"titleContains":    &gql.InputObjectFieldConfig{Type: gql.String},
+			"directorContains": &gql.InputObjectFieldConfig{Type: gql.String},
+			"minRating":        &gql.InputObjectFieldConfig{Type: gql.Int},
+			"maxRating":        &gql.InputObjectFieldConfig{Type: gql.Int},
+			"releaseYear":      &gql.InputObjectFieldConfig{Type: gql.Int},
+		},
+	})
+
+	movieInput := gql.NewInputObject(gql.InputObjectConfig{
+		Name: "MovieInput",
+		Fields: gql.InputObjectConfigFieldMap{
+			"title":       &gql.InputObjectFieldConfig{Type: gql.NewNonNull(gql.String)},
+			"rating":      &gql.InputObjectFieldConfig{Type: gql.NewNonNull(gql.Int)},
+			"releaseYear": &gql.InputObjectFieldConfig{Type: gql.Int},
+			"director": &gql.InputObjectFieldConfig{Type: gql.NewNonNull(gql.NewInputObject(gql.InputObjectConfig{
+				Name: "DirectorInput",
+				Fields: gql.InputObjectConfigFieldMap{
+					"name": &gql.InputObjectFieldConfig{Type: gql.NewNonNull(gql.String)},
+					"age":  &gql.InputObjectFieldConfig{Type: gql.NewNonNull(gql.Int)},
+				},
+			}))},
+			"cast": &gql.InputObjectFieldConfig{Type: gql.NewNonNull(gql.NewInputObject(gql.InputObjectConfig{
+				Name: "CastInput",
+				Fields: gql.InputObjectConfigFieldMap{
+					"actor":   &gql.InputObjectFieldConfig{Type: gql.NewNonNull(gql.String)},
+					"actress": &gql.InputObjectFieldConfig{Type: gql.NewNonNull(gql.String)},
+				},
+			}))},
+		},
+	})
+
+	idArg := gql.FieldConfigArgument{"id": &gql.ArgumentConfig{Type: gql.NewNonNull(gql.Int)}}
+
+	query := gql.NewObject(gql.ObjectConfig{
+		Name: "Query",
+		Fields: gql.Fields{
+			"movie": &gql.Field{
+				Type: movieType,
+				Args: idArg,
+				Resolve: func(p gql.ResolveParams) (any, error) {
+					//? A missing movie resolves to null, as GraphQL clients expect
+					movie, err := store.GetMovieByID(p.Context, int64(p.Args["id"].(int)))
+					if errors.Is(err, db.ErrNotFound) {
+						return nil, nil
+					}
+					if err != nil {
+						return nil, apperr.Internal(err)
+					}
+					return movie, nil
+				},
+			},
+			"movies": &gql.Field{
+				Type: gql.NewNonNull(gql.NewList(gql.NewNonNull(movieType))),
+				Args: gql.FieldConfigArgument{
+					"filter": &gql.ArgumentConfig{Type: filterType},
+					"limit":  &gql.ArgumentConfig{Type: gql.Int, DefaultValue: defaultLimit, Description: "Capped at 50"},
+					"offset": &gql.ArgumentConfig{Type: gql.Int, DefaultValue: 0},
+				},
+				Resolve: func(p gql.ResolveParams) (any, error) {
+					limit, offset := p.Args["limit"].(int), p.Args["offset"].(int)
+					if limit <= 0 || offset < 0 {
+						return nil, apperr.BadRequest(apperr.CodeInvalidQuery, "limit must be positive and offset non-negative")
+					}
+					movies, err := store.FindMovies(p.Context, movieFilter(p.Args["filter"]), min(limit, maxLimit), offset)
+					if err != nil {
+						return nil, apperr.Internal(err)
+					}
+					return movies, nil
+				},
+			},
+			"director": &gql.Field{
+				Type: directorType,
+				Args: idArg,
+				Resolve: func(p gql.ResolveParams) (any, error) {
+					return loadersFrom(p.Context).directors.Load(p.Context, int64(p.Args["id"].(int))), nil
+				},
+			},
+		},
+	})
+
+	mutation := gql.NewObject(gql.ObjectConfig{
+		Name: "Mutation",
+		Fields: gql.Fields{
+			"createMovie": &gql.Field{
+				Type: gql.NewNonNull(movieType),
+				Args: gql.FieldConfigArgument{"input": &gql.ArgumentConfig{Type: gql.NewNonNull(movieInput)}},
+				Resolve: func(p gql.ResolveParams) (any, error) {
+					if err := requireScope(p.Context, auth.ScopeMoviesWrite); err != nil {
+						return nil, err
+					}
+
+					movie, err := validMovie(p.Context, p.Args["input"])
+					if err != nil {
+						return nil, err
+					}
+
+					id, err := store.CreateMovie(p.Context, movie)
+					if err != nil {
+						return nil, apperr.FromDB(err, apperr.NotFound(apperr.CodeNotFound, "referenced record not found"))
+					}
+					return store.GetMovieByID(p.Context, id)
+				},
+			},
+			"updateMovie": &gql.Field{
+				Type: gql.NewNonNull(movieType),
+				Args: gql.FieldConfigArgument{
+					"id":    &gql.ArgumentConfig{Type: gql.NewNonNull(gql.Int)},
+					"input": &gql.ArgumentConfig{Type: gql.NewNonNull(movieInput)},
+				},
+				Resolve: func(p gql.ResolveParams) (any, error) {
+					if err := requireScope(p.Context, auth.ScopeMoviesWrite); err != nil {
+						return nil, err
+					}
+
+					movie, err := validMovie(p.Context, p.Args["input"])
+					if err != nil {
+						return nil, err
+					}
+
+					id := int64(p.Args["id"].(int))
+					if _, err := store.UpdateMovie(p.Context, id, movie); err != nil {
+						return nil, apperr.FromDB(err, movieNotFound(id))
+					}
+					return store.GetMovieByID(p.Context, id)
+				},
+			},
+			"deleteMovie": &gql.Field{
+				Type:        gql.NewNonNull(gql.Int),
+				Description: "Deletes the movie and returns its ID",
+				Args:        idArg,
+				Resolve: func(p gql.ResolveParams) (any, error) {
+					if err := requireScope(p.Context, auth.ScopeMoviesDelete); err != nil {
+						return nil, err
+					}
+
+					id := int64(p.Args["id"].(int))
+					if _, err := store.DeleteMovieByID(p.Context, id); err != nil {
+						return nil, apperr.FromDB(err, movieNotFound(id))
+					}
+					return id, nil
+				},
+			},
+		},
+	})
+
+	return gql.NewSchema(gql.SchemaConfig{Query: query, Mutation: mutation})
+}
+
+func movieField(get func(*types.Movie) any) gql.FieldResolveFn {
+	return func(p gql.ResolveParams) (any, error) {
+		return get(p.Source.(*types.Movie)), nil
+	}
+}
+
+func directorField(get func(*types.Director) any) gql.FieldResolveFn {
+	return func(p gql.ResolveParams) (any, error) {
+		return get(p.Source.(*types.Director)), nil
+	}
+}
+
+func castField(get func(*types.Cast) any) gql.FieldResolveFn {
+	return func(p gql.ResolveParams) (any, error) {
+		return get(p.Source.(*types.Cast)), nil
+	}
+}
+
+func movieFilter(arg any) db.MovieFilter {
+	values, _ := arg.(map[string]any)
+
+	var filter db.MovieFilter
+	filter.TitleContains, _ = values["titleContains"].(string)
+	filter.DirectorContains, _ = values["directorContains"].(string)
+	filter.MinRating, _ = values["minRating"].(int)
+	filter.MaxRating, _ = values["maxRating"].(int)
+	filter.ReleaseYear, _ = values["releaseYear"].(int)
+	return filter
+}
+
+// validMovie converts a MovieInput and applies the same validation rules as the REST API
+func validMovie(ctx context.Context, arg any) (*types.Movie, error) {
+	values := arg.(map[string]any)
+	director := values["director"].(map[string]any)
+	cast := values["cast"].(map[string]any)
+
+	movie := &types.Movie{
+		Title:    values["title"].(string),
+		Rating:   values["rating"].(int),
+		Director: &types.Director{Name: director["name"].(string), Age: director["age"].(int)},
+		Cast:     &types.Cast{Actor: cast["actor"].(string), Actress: cast["actress"].(string)},
+	}
+	movie.ReleaseYear, _ = values["releaseYear"].(int)
+
+	if err := validation.Struct(*movie, localeFrom(ctx)); err != nil {
+		return nil, err
+	}
+	return movie, nil
+}
+
+// requireScope guards mutations; the endpoint itself only demands movies:read
+func requireScope(ctx context.Context, scope string) error {
+	if identity, ok := auth.FromContext(ctx); ok && identity.HasScope(scope) {
+		return nil
+	}
+	return apperr.Forbidden("missing required scope %q", scope)
+}
+
+func movieNotFound(id int64) *apperr.Error {
+	return apperr.NotFound(apperr.CodeMovieNotFound, "movie %d not found", id)
+}
+
+func nullableInt(n int) any {
+	if n == 0 {
+		return nil
+	}
+	return n
+}
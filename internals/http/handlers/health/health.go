@@ -0,0 +1,160 @@
+package health
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"github/MahfujulSagor/movies_crud/internals/apperr"
+	"github/MahfujulSagor/movies_crud/internals/build"
+	"github/MahfujulSagor/movies_crud/internals/cache"
+	"github/MahfujulSagor/movies_crud/internals/db"
+	"github/MahfujulSagor/movies_crud/internals/logger"
+	"github/MahfujulSagor/movies_crud/internals/utils/response"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const checkTimeout = 2 * time.Second
+
+// State tracks process lifecycle information shared by the health handlers
+type State struct {
+	started      time.Time
+	shuttingDown atomic.Bool
+}
+
+func NewState() *State {
+	return &State{started: time.Now()}
+}
+
+// SetShuttingDown makes readiness fail so load balancers stop routing traffic
+func (s *State) SetShuttingDown() {
+	s.shuttingDown.Store(true)
+}
+
+func (s *State) ShuttingDown() bool {
+	return s.shuttingDown.Load()
+}
+
+func (s *State) Uptime() time.Duration {
+	return time.Since(s.started)
+}
+
+type Check struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type Report struct {
+	Status string           `json:"status"`
+	Checks map[string]Check `json:"checks,omitempty"`
+}
+
+type Details struct {
+	Report
+	Uptime   string       `json:"uptime"`
+	Database *db.Stats    `json:"database,omitempty"`
+	Cache    *cache.Stats `json:"cache,omitempty"`
+	Build    build.Info   `json:"build"`
+}
+
+func Liveness() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		response.WriteJson(w, http.StatusOK, Report{Status: response.StatusOK})
+	}
+}
+
+func Readiness(checker db.HealthChecker, state *State) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report := readiness(r.Context(), checker, state)
+
+		status := http.StatusOK
+		if report.Status != response.StatusOK {
+			status = http.StatusServiceUnavailable
+			logger.Error.Println("Readiness check failed:", report.Checks)
+		}
+
+		//? The probe is public, so failures are reported by status only; the reasons are logged above
+		//? and shown by /health/details
+		for name, check := range report.Checks {
+			check.Error = ""
+			report.Checks[name] = check
+		}
+
+		response.WriteJson(w, status, report)
+	}
+}
+
+// Detailed reports the full health picture; cached is nil when the read cache is disabled
+func Detailed(checker db.HealthChecker, state *State, token string, cached *cache.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		//? Details expose internals, so they are disabled unless a token is configured
+		if token == "" || !validToken(r, token) {
+			response.WriteProblem(w, r, apperr.Unauthorized(apperr.CodeUnauthorized, "a valid health token is required"))
+			logger.Error.Println("Unauthorized health details request from", r.RemoteAddr)
+			return
+		}
+
+		details := Details{
+			Report: readiness(r.Context(), checker, state),
+			Uptime: state.Uptime().Round(time.Second).String(),
+			Build:  build.Get(),
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), checkTimeout)
+		defer cancel()
+		stats, err := checker.Stats(ctx)
+		if err != nil {
+			details.Checks["stats"] = Check{Status: response.StatusError, Error: err.Error()}
+			logger.Error.Println("Error reading database stats:", err)
+		}
+		details.Database = stats
+
+		if cached != nil {
+			cacheStats := cached.CacheStats()
+			details.Cache = &cacheStats
+		}
+
+		response.WriteJson(w, http.StatusOK, details)
+	}
+}
+
+func readiness(ctx context.Context, checker db.HealthChecker, state *State) Report {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	report := Report{
+		Status: response.StatusOK,
+		Checks: map[string]Check{},
+	}
+
+	record := func(name string, err error) {
+		if err != nil {
+			report.Status = response.StatusError
+			report.Checks[name] = Check{Status: response.StatusError, Error: err.Error()}
+			return
+		}
+		report.Checks[name] = Check{Status: response.StatusOK}
+	}
+
+	record("database", checker.Ping(ctx))
+	record("migrations", checker.SchemaReady(ctx))
+
+	var shutdownErr error
+	if state.ShuttingDown() {
+		shutdownErr = fmt.Errorf("server is shutting down")
+	}
+	record("shutdown", shutdownErr)
+
+	return report
+}
+
+func validToken(r *http.Request, token string) bool {
+	auth := r.Header.Get("Authorization")
+	given, ok := strings.CutPrefix(auth, "Bearer ")
+	if !ok {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1
+}
@@ -0,0 +1,61 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github/MahfujulSagor/movies_crud/internals/db"
+	"github/MahfujulSagor/movies_crud/internals/logger"
+	"github/MahfujulSagor/movies_crud/internals/utils/response"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func init() {
+	logger.Info = log.New(io.Discard, "", 0)
+	logger.Error = log.New(io.Discard, "", 0)
+}
+
+// brokenDB fails every check with driver-style error text
+type brokenDB struct{}
+
+func (brokenDB) Ping(ctx context.Context) error {
+	return errors.New("unable to open database file: /var/lib/movies/movies.db")
+}
+
+func (brokenDB) SchemaReady(ctx context.Context) error {
+	return errors.New("no such table: schema_migrations")
+}
+
+func (brokenDB) Stats(ctx context.Context) (*db.Stats, error) {
+	return nil, errors.New("stats unavailable")
+}
+
+func TestReadinessHidesFailureDetails(t *testing.T) {
+	state := NewState()
+	state.SetShuttingDown()
+
+	rec := httptest.NewRecorder()
+	Readiness(brokenDB{}, state)(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status %d, want 503", rec.Code)
+	}
+	if body := rec.Body.String(); strings.Contains(body, "movies.db") || strings.Contains(body, "schema_migrations") {
+		t.Fatalf("readiness exposes error details: %s", body)
+	}
+
+	var report Report
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"database", "migrations", "shutdown"} {
+		if report.Checks[name].Status != response.StatusError {
+			t.Errorf("check %s is %q, want %q", name, report.Checks[name].Status, response.StatusError)
+		}
+	}
+}
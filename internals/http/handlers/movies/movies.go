@@ -1,51 +1,43 @@
 package movies
 
 import (
-	"encoding/json"
-	"errors"
 	"fmt"
+	"github/MahfujulSagor/movies_crud/internals/apperr"
 	"github/MahfujulSagor/movies_crud/internals/db"
+	"github/MahfujulSagor/movies_crud/internals/http/httpcache"
+	"github/MahfujulSagor/movies_crud/internals/http/negotiate"
 	"github/MahfujulSagor/movies_crud/internals/logger"
 	"github/MahfujulSagor/movies_crud/internals/types"
 	"github/MahfujulSagor/movies_crud/internals/utils/response"
-	"io"
+	"github/MahfujulSagor/movies_crud/internals/validation"
 	"net/http"
 	"strconv"
-
-	"github.com/go-playground/validator"
 )
 
 func New(db db.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		logger.Info.Println("Root handler has been called")
 
-		//? Decode JSON into Movie struct
+		//? Decode the body in whichever format Content-Type names
 		var movie types.Movie
-		err := json.NewDecoder(r.Body).Decode(&movie)
-		if errors.Is(err, io.EOF) {
-			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(fmt.Errorf("empty body")))
-			logger.Error.Println("Empty body:", err)
-			return
-		}
-
-		if err != nil {
-			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+		if err := negotiate.Decode(r, &movie); err != nil {
+			response.WriteProblem(w, r, err)
 			logger.Error.Println("Error decoding movie:", err)
 			return
 		}
 		defer r.Body.Close()
 
 		//? Request validation
-		if err := validator.New().Struct(movie); err != nil {
-			response.WriteJson(w, http.StatusBadRequest, response.ValidationError(err.(validator.ValidationErrors)))
+		if err := validation.Request(r, movie); err != nil {
+			response.WriteProblem(w, r, err)
 			logger.Error.Println("Validation error:", err)
 			return
 		}
 
 		//* Create movie in database
-		id, err := db.CreateMovie(&movie)
+		id, err := db.CreateMovie(r.Context(), &movie)
 		if err != nil {
-			response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
+			response.WriteProblem(w, r, apperr.FromDB(err, apperr.NotFound(apperr.CodeNotFound, "referenced record not found")))
 			logger.Error.Println("Failed to create movie:", err)
 			return
 		}
@@ -53,9 +45,10 @@ func New(db db.DB) http.HandlerFunc {
 		logger.Info.Println("Movie created with ID:", id)
 
 		//? Send response
-		response.WriteJson(w, http.StatusCreated, map[string]string{
-			"success": "OK",
-			"message": fmt.Sprintf("Movie created with ID: %d", id),
+		w.Header().Set("Location", fmt.Sprintf("/api/v1/movies/%d", id))
+		response.Write(w, r, http.StatusCreated, response.Message{
+			Success: response.StatusOK,
+			Message: fmt.Sprintf("Movie created with ID: %d", id),
 		})
 	}
 }
@@ -67,7 +60,7 @@ func GetByID(db db.DB) http.HandlerFunc {
 		//? Get ID string from pathvalue
 		idStr := r.PathValue("id")
 		if idStr == "" {
-			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(fmt.Errorf("missing movie ID in URL")))
+			response.WriteProblem(w, r, apperr.BadRequest(apperr.CodeInvalidID, "missing movie ID in URL"))
 			logger.Error.Println("Missing movie ID in URL:")
 			return
 		}
@@ -75,27 +68,25 @@ func GetByID(db db.DB) http.HandlerFunc {
 		//? Parse idStr into int64
 		id, err := strconv.ParseInt(idStr, 10, 64)
 		if err != nil {
-			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(fmt.Errorf("invalid ID")))
+			response.WriteProblem(w, r, apperr.BadRequest(apperr.CodeInvalidID, "invalid ID %q", idStr))
 			logger.Error.Println("Error parsing ID into int64:", err)
 			return
 		}
 
 		//* Retrieve movie from database
-		movie, err := db.GetMovieByID(id)
+		movie, err := db.GetMovieByID(r.Context(), id)
 		if err != nil {
-			response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
+			response.WriteProblem(w, r, apperr.FromDB(err, movieNotFound(id)))
 			logger.Error.Println("Error retrieving movie:", err)
 			return
 		}
 
-		if movie == nil {
-			response.WriteJson(w, http.StatusNotFound, response.GeneralError(fmt.Errorf("movie not found")))
-			logger.Error.Println("Movie not found")
+		if httpcache.NotModified(w, r, movie.UpdatedAt) {
 			return
 		}
 
 		//? Send response
-		response.WriteJson(w, http.StatusOK, movie)
+		response.Write(w, r, http.StatusOK, movie)
 	}
 }
 
@@ -119,13 +110,13 @@ func GetList(db db.DB) http.HandlerFunc {
 		//? Convert limit and offset to integers
 		limit, err := strconv.Atoi(limitStr)
 		if err != nil || limit <= 0 {
-			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(fmt.Errorf("invalid limit value")))
+			response.WriteProblem(w, r, apperr.BadRequest(apperr.CodeInvalidQuery, "invalid limit value %q", limitStr))
 			logger.Error.Println("Invalid limit value:", err)
 			return
 		}
 		offset, err := strconv.Atoi(offsetStr)
 		if err != nil || offset < 0 {
-			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(fmt.Errorf("invalid offset value")))
+			response.WriteProblem(w, r, apperr.BadRequest(apperr.CodeInvalidQuery, "invalid offset value %q", offsetStr))
 			logger.Error.Println("Invalid offset value:", err)
 			return
 		}
@@ -136,23 +127,34 @@ func GetList(db db.DB) http.HandlerFunc {
 			limit = maxLimit
 		}
 
+		//? Answer conditional requests before running the page query
+		modified, err := db.MoviesLastModified(r.Context())
+		if err != nil {
+			response.WriteProblem(w, r, apperr.Internal(err))
+			logger.Error.Println("Error reading catalogue modification time:", err)
+			return
+		}
+		if httpcache.NotModified(w, r, modified) {
+			return
+		}
+
 		//* Retrieve movie list from database
-		movies, err := db.GetMovieList(limit, offset)
+		movies, err := db.GetMovieList(r.Context(), limit, offset)
 		if err != nil {
-			response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
+			response.WriteProblem(w, r, apperr.Internal(err))
 			logger.Error.Println("Error retrieving students:", err)
 			return
 		}
 
 		//? Handle empty results gracefully
 		if len(movies) == 0 {
-			response.WriteJson(w, http.StatusOK, []types.Movie{})
+			response.Write(w, r, http.StatusOK, []types.Movie{})
 			logger.Info.Println("Movie list is empty")
 			return
 		}
 
 		//? Send response
-		response.WriteJson(w, http.StatusOK, movies)
+		response.Write(w, r, http.StatusOK, movies)
 	}
 }
 
@@ -163,7 +165,7 @@ func Update(db db.DB) http.HandlerFunc {
 		//? Get id from URL
 		idStr := r.PathValue("id")
 		if idStr == "" {
-			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(fmt.Errorf("missing ID")))
+			response.WriteProblem(w, r, apperr.BadRequest(apperr.CodeInvalidID, "missing movie ID in URL"))
 			logger.Error.Println("Missing ID in URL")
 			return
 		}
@@ -171,59 +173,38 @@ func Update(db db.DB) http.HandlerFunc {
 		//? Parse idStr into int64
 		id, err := strconv.ParseInt(idStr, 10, 64)
 		if err != nil {
-			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(fmt.Errorf("invalid ID")))
+			response.WriteProblem(w, r, apperr.BadRequest(apperr.CodeInvalidID, "invalid ID %q", idStr))
 			logger.Error.Println("Error parsing ID:", err)
 			return
 		}
 
-		//? Check if movie exists
-		m, err := db.GetMovieByID(id)
-		if err != nil || m == nil {
-			response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(fmt.Errorf("movie does not exist")))
-			logger.Error.Println("Movie doen not exist", err)
-			return
-		}
-
-		//? Decode JSON
+		//? Decode the body in whichever format Content-Type names
 		var movie types.Movie
-		err = json.NewDecoder(r.Body).Decode(&movie)
-		if errors.Is(err, io.EOF) {
-			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(fmt.Errorf("empty body")))
-			logger.Error.Println("Empty body:", err)
-			return
-		}
-
-		if err != nil {
-			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+		if err := negotiate.Decode(r, &movie); err != nil {
+			response.WriteProblem(w, r, err)
 			logger.Error.Println("Error decoding movie:", err)
 			return
 		}
 		defer r.Body.Close()
 
 		//? Request validation
-		if err := validator.New().Struct(movie); err != nil {
-			response.WriteJson(w, http.StatusBadRequest, response.ValidationError(err.(validator.ValidationErrors)))
+		if err := validation.Request(r, movie); err != nil {
+			response.WriteProblem(w, r, err)
 			logger.Error.Println("Validation error:", err)
 			return
 		}
 
 		//* Update movie
-		updated_movie_id, err := db.UpdateMovie(id, &movie)
+		updated_movie_id, err := db.UpdateMovie(r.Context(), id, &movie)
 		if err != nil {
-			response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
+			response.WriteProblem(w, r, apperr.FromDB(err, movieNotFound(id)))
 			logger.Error.Println("Failed to update movie:", err)
 			return
 		}
 
-		if updated_movie_id == 0 {
-			response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(fmt.Errorf("movie not found")))
-			logger.Error.Println("Movie to update not found")
-			return
-		}
-
-		response.WriteJson(w, http.StatusOK, map[string]string{
-			"success": "OK",
-			"message": fmt.Sprintf("Movie updated with ID %d", updated_movie_id),
+		response.Write(w, r, http.StatusOK, response.Message{
+			Success: response.StatusOK,
+			Message: fmt.Sprintf("Movie updated with ID %d", updated_movie_id),
 		})
 	}
 }
@@ -235,7 +216,7 @@ func DeleteByID(db db.DB) http.HandlerFunc {
 		//? Get id string from URL
 		idStr := r.PathValue("id")
 		if idStr == "" {
-			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(fmt.Errorf("missing ID")))
+			response.WriteProblem(w, r, apperr.BadRequest(apperr.CodeInvalidID, "missing movie ID in URL"))
 			logger.Error.Println("Missing ID in URL")
 			return
 		}
@@ -243,27 +224,26 @@ func DeleteByID(db db.DB) http.HandlerFunc {
 		//? Parse idStr into int64
 		id, err := strconv.ParseInt(idStr, 10, 64)
 		if err != nil {
-			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(fmt.Errorf("invalid ID")))
+			response.WriteProblem(w, r, apperr.BadRequest(apperr.CodeInvalidID, "invalid ID %q", idStr))
 			logger.Error.Println("Invalid ID:", err)
 			return
 		}
 
 		//* Delete movie from database
-		deleted_movie_id, err := db.DeleteMovieByID(id)
+		deleted_movie_id, err := db.DeleteMovieByID(r.Context(), id)
 		if err != nil {
-			response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
+			response.WriteProblem(w, r, apperr.FromDB(err, movieNotFound(id)))
 			logger.Error.Println("Failed to delete movie:", err)
-		}
-
-		if deleted_movie_id == 0 {
-			response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(fmt.Errorf("movie not found")))
-			logger.Error.Println("Movie to delete not found")
 			return
 		}
 
-		response.WriteJson(w, http.StatusOK, map[string]string{
-			"success": "OK",
-			"message": fmt.Sprintf("Movie deleted with ID %d", deleted_movie_id),
+		response.Write(w, r, http.StatusOK, response.Message{
+			Success: response.StatusOK,
+			Message: fmt.Sprintf("Movie deleted with ID %d", deleted_movie_id),
 		})
 	}
 }
+
+func movieNotFound(id int64) *apperr.Error {
+	return apperr.NotFound(apperr.CodeMovieNotFound, "movie %d not found", id)
+}
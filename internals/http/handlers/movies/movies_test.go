@@ -0,0 +1,198 @@
+package movies
+
+import (
+	"context"
+	"encoding/json"
+	"github/MahfujulSagor/movies_crud/internals/apperr"
+	"github/MahfujulSagor/movies_crud/internals/config"
+	"github/MahfujulSagor/movies_crud/internals/db/sqlite"
+	"github/MahfujulSagor/movies_crud/internals/logger"
+	"github/MahfujulSagor/movies_crud/internals/types"
+	"github/MahfujulSagor/movies_crud/internals/utils/response"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func init() {
+	logger.Info = log.New(io.Discard, "", 0)
+	logger.Error = log.New(io.Discard, "", 0)
+}
+
+func newStore(t *testing.T) *sqlite.SQLite {
+	t.Helper()
+	store, err := sqlite.New(&config.Config{DBPath: filepath.Join(t.TempDir(), "movies.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func testMovie(title string) *types.Movie {
+	return &types.Movie{
+		Title:    title,
+		Rating:   7,
+		Director: &types.Director{Name: "Director of " + title, Age: 50},
+		Cast:     &types.Cast{Actor: "Actor", Actress: "Actress"},
+	}
+}
+
+// get calls handler with the {id} path value and headers set
+func get(handler http.HandlerFunc, target string, id string, headers map[string]string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	if id != "" {
+		r.SetPathValue("id", id)
+	}
+	for name, value := range headers {
+		r.Header.Set(name, value)
+	}
+	rec := httptest.NewRecorder()
+	handler(rec, r)
+	return rec
+}
+
+func problemCode(rec *httptest.ResponseRecorder) string {
+	var problem response.Problem
+	json.Unmarshal(rec.Body.Bytes(), &problem)
+	return problem.Code
+}
+
+func TestConditionalGets(t *testing.T) {
+	store := newStore(t)
+	id, err := store.CreateMovie(context.Background(), testMovie("Heat"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	idStr := strconv.FormatInt(id, 10)
+
+	for _, tc := range []struct {
+		name    string
+		handler http.HandlerFunc
+		target  string
+		id      string
+	}{
+		{"movie", GetByID(store), "/api/v1/movies/" + idStr, idStr},
+		{"list", GetList(store), "/api/v1/movies?limit=5", ""},
+	} {
+		rec := get(tc.handler, tc.target, tc.id, nil)
+		lastModified := rec.Header().Get("Last-Modified")
+		if rec.Code != http.StatusOK || lastModified == "" {
+			t.Fatalf("%s: first GET answered %d with Last-Modified %q", tc.name, rec.Code, lastModified)
+		}
+		stamp, _ := http.ParseTime(lastModified)
+
+		for _, cond := range []struct {
+			name    string
+			headers map[string]string
+			status  int
+		}{
+			{"current copy", map[string]string{"If-Modified-Since": lastModified}, http.StatusNotModified},
+			{"stale copy", map[string]string{"If-Modified-Since": stamp.Add(-time.Second).Format(http.TimeFormat)}, http.StatusOK},
+			//? An entity tag we never issued cannot match, and it overrides the date
+			{"If-None-Match", map[string]string{"If-Modified-Since": lastModified, "If-None-Match": `"v1"`}, http.StatusOK},
+		} {
+			rec := get(tc.handler, tc.target, tc.id, cond.headers)
+			if rec.Code != cond.status {
+				t.Errorf("%s, %s: status %d, want %d", tc.name, cond.name, rec.Code, cond.status)
+			}
+			if cond.status == http.StatusNotModified && rec.Body.Len() != 0 {
+				t.Errorf("%s, %s: 304 carried a body %q", tc.name, cond.name, rec.Body)
+			}
+		}
+	}
+}
+
+func TestListPages(t *testing.T) {
+	store := newStore(t)
+	rec := get(GetList(store), "/api/v1/movies", "", nil)
+	if rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != "[]" {
+		t.Fatalf("empty catalogue answered %d %s", rec.Code, rec.Body)
+	}
+
+	for _, title := range []string{"Heat", "Ronin", "Collateral"} {
+		if _, err := store.CreateMovie(context.Background(), testMovie(title)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rec = get(GetList(store), "/api/v1/movies?limit=2&offset=1", "", nil)
+	var page []types.Movie
+	json.Unmarshal(rec.Body.Bytes(), &page)
+	if len(page) != 2 || page[0].Title != "Ronin" || page[0].CreatedAt.IsZero() || page[0].UpdatedAt.IsZero() {
+		t.Fatalf("page %+v, want Ronin and Collateral with timestamps", page)
+	}
+}
+
+func TestBadRequests(t *testing.T) {
+	store := newStore(t)
+
+	for _, tc := range []struct {
+		name    string
+		handler http.HandlerFunc
+		target  string
+		id      string
+		status  int
+		code    string
+	}{
+		{"bad ID", GetByID(store), "/api/v1/movies/abc", "abc", http.StatusBadRequest, apperr.CodeInvalidID},
+		{"missing movie", GetByID(store), "/api/v1/movies/9", "9", http.StatusNotFound, apperr.CodeMovieNotFound},
+		{"bad limit", GetList(store), "/api/v1/movies?limit=0", "", http.StatusBadRequest, apperr.CodeInvalidQuery},
+		{"bad offset", GetList(store), "/api/v1/movies?offset=-1", "", http.StatusBadRequest, apperr.CodeInvalidQuery},
+	} {
+		rec := get(tc.handler, tc.target, tc.id, nil)
+		if rec.Code != tc.status || problemCode(rec) != tc.code {
+			t.Errorf("%s: got %d %s, want %d %s", tc.name, rec.Code, problemCode(rec), tc.status, tc.code)
+		}
+		if rec.Header().Get("Last-Modified") != "" {
+			t.Errorf("%s: error carried Last-Modified", tc.name)
+		}
+	}
+}
+
+func TestWrites(t *testing.T) {
+	store := newStore(t)
+	body := `{"name": "Heat", "rating": 8, "director": {"name": "Michael Mann", "age": 80}, "cast": {"actor": "Al Pacino", "actress": "Diane Venora"}}`
+
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/movies", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	New(store)(rec, r)
+	if rec.Code != http.StatusCreated || rec.Header().Get("Location") != "/api/v1/movies/1" {
+		t.Fatalf("create answered %d at %q: %s", rec.Code, rec.Header().Get("Location"), rec.Body)
+	}
+
+	//? The same title again is a conflict
+	r = httptest.NewRequest(http.MethodPost, "/api/v1/movies", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	rec = httptest.NewRecorder()
+	New(store)(rec, r)
+	if rec.Code != http.StatusConflict || problemCode(rec) != apperr.CodeConflict {
+		t.Fatalf("duplicate answered %d %s", rec.Code, problemCode(rec))
+	}
+
+	r = httptest.NewRequest(http.MethodPut, "/api/v1/movies/2", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	r.SetPathValue("id", "2")
+	rec = httptest.NewRecorder()
+	Update(store)(rec, r)
+	if rec.Code != http.StatusNotFound || problemCode(rec) != apperr.CodeMovieNotFound {
+		t.Fatalf("updating a missing movie answered %d %s", rec.Code, problemCode(rec))
+	}
+
+	for _, want := range []int{http.StatusOK, http.StatusNotFound} {
+		r = httptest.NewRequest(http.MethodDelete, "/api/v1/movies/1", nil)
+		r.SetPathValue("id", "1")
+		rec = httptest.NewRecorder()
+		DeleteByID(store)(rec, r)
+		if rec.Code != want {
+			t.Fatalf("delete answered %d, want %d", rec.Code, want)
+		}
+	}
+}
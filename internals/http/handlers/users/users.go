@@ -0,0 +1,209 @@
+package users
+
+import (
+	"errors"
+	"github/MahfujulSagor/movies_crud/internals/apperr"
+	"github/MahfujulSagor/movies_crud/internals/auth"
+	"github/MahfujulSagor/movies_crud/internals/db"
+	"github/MahfujulSagor/movies_crud/internals/http/negotiate"
+	"github/MahfujulSagor/movies_crud/internals/logger"
+	"github/MahfujulSagor/movies_crud/internals/types"
+	"github/MahfujulSagor/movies_crud/internals/utils/response"
+	"github/MahfujulSagor/movies_crud/internals/validation"
+	"net/http"
+	"time"
+)
+
+type NewUser struct {
+	Username string `json:"username" validate:"required,no_leading_space"`
+	Role     string `json:"role" validate:"required,oneof=viewer editor admin"`
+	Password string `json:"password" validate:"required,min=8"`
+}
+
+type Credentials struct {
+	Username string `json:"username" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+type Session struct {
+	Token     string      `json:"token"`
+	ExpiresAt time.Time   `json:"expires_at"`
+	User      *types.User `json:"user"`
+}
+
+func New(store db.UserStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info.Println("Create user handler called")
+
+		//? Decode JSON into user struct; other formats get 415
+		var user NewUser
+		if err := negotiate.DecodeJSON(r, &user); err != nil {
+			response.WriteProblem(w, r, err)
+			logger.Error.Println("Error decoding user:", err)
+			return
+		}
+		defer r.Body.Close()
+
+		//? Request validation
+		if err := validation.Request(r, user); err != nil {
+			response.WriteProblem(w, r, err)
+			logger.Error.Println("Validation error:", err)
+			return
+		}
+
+		created, err := Create(store, user.Username, user.Password, user.Role)
+		if err != nil {
+			response.WriteProblem(w, r, apperr.FromDB(err, apperr.NotFound(apperr.CodeNotFound, "referenced record not found")))
+			logger.Error.Println("Failed to create user:", err)
+			return
+		}
+
+		logger.Info.Println("User created with ID:", created.ID)
+
+		response.WriteJson(w, http.StatusCreated, created)
+	}
+}
+
+func List(store db.UserStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info.Println("List users handler called")
+
+		users, err := store.ListUsers()
+		if err != nil {
+			response.WriteProblem(w, r, apperr.Internal(err))
+			logger.Error.Println("Error listing users:", err)
+			return
+		}
+
+		if len(users) == 0 {
+			response.WriteJson(w, http.StatusOK, []types.User{})
+			return
+		}
+
+		response.WriteJson(w, http.StatusOK, users)
+	}
+}
+
+func Login(store db.UserStore, ttl time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info.Println("Login handler called")
+
+		//? Decode JSON credentials; other formats get 415
+		var creds Credentials
+		if err := negotiate.DecodeJSON(r, &creds); err != nil {
+			response.WriteProblem(w, r, err)
+			logger.Error.Println("Error decoding credentials:", err)
+			return
+		}
+		defer r.Body.Close()
+
+		if err := validation.Request(r, creds); err != nil {
+			response.WriteProblem(w, r, err)
+			logger.Error.Println("Validation error:", err)
+			return
+		}
+
+		//* Verify credentials
+		user, err := Authenticate(store, creds.Username, creds.Password)
+		if err != nil {
+			response.WriteProblem(w, r, apperr.Internal(err))
+			logger.Error.Println("Error verifying credentials:", err)
+			return
+		}
+
+		if user == nil {
+			response.WriteProblem(w, r, apperr.Unauthorized(apperr.CodeInvalidCredentials, "invalid username or password"))
+			logger.Error.Println("Failed login for username:", creds.Username)
+			return
+		}
+
+		//* Issue session
+		token, err := auth.GenerateSessionToken()
+		if err != nil {
+			response.WriteProblem(w, r, apperr.Internal(err))
+			logger.Error.Println("Error generating session token:", err)
+			return
+		}
+
+		expiresAt := time.Now().Add(ttl).UTC()
+		if err := store.CreateSession(user.ID, auth.HashKey(token), expiresAt); err != nil {
+			response.WriteProblem(w, r, apperr.Internal(err))
+			logger.Error.Println("Error storing session:", err)
+			return
+		}
+
+		logger.Info.Println("User logged in:", user.Username)
+
+		response.WriteJson(w, http.StatusOK, Session{
+			Token:     token,
+			ExpiresAt: expiresAt,
+			User:      user,
+		})
+	}
+}
+
+func Logout(store db.UserStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info.Println("Logout handler called")
+
+		//? Parse the header as the authenticator does, so any token that logs in can log out
+		token, ok := auth.BearerToken(r.Header.Get("Authorization"))
+		if !ok {
+			response.WriteProblem(w, r, apperr.Unauthorized(apperr.CodeUnauthorized, "missing session token"))
+			return
+		}
+
+		if err := store.DeleteSession(auth.HashKey(token)); err != nil {
+			response.WriteProblem(w, r, apperr.Internal(err))
+			logger.Error.Println("Error deleting session:", err)
+			return
+		}
+
+		response.WriteJson(w, http.StatusOK, response.Message{
+			Success: response.StatusOK,
+			Message: "Logged out",
+		})
+	}
+}
+
+// Create hashes the password and stores a new user; shared by the HTTP handler and the admin CLI
+func Create(store db.UserStore, username string, password string, role string) (*types.User, error) {
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &types.User{
+		Username:  username,
+		Role:      role,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	id, err := store.CreateUser(user, hash)
+	if err != nil {
+		return nil, err
+	}
+	user.ID = id
+
+	return user, nil
+}
+
+// Authenticate returns the user for valid credentials and nil for unknown users or wrong passwords
+func Authenticate(store db.UserStore, username string, password string) (*types.User, error) {
+	user, hash, err := store.GetUserByUsername(username)
+	if errors.Is(err, db.ErrNotFound) {
+		//? Still hash for unknown users so response time does not reveal which usernames exist
+		_, _ = auth.VerifyPassword(password, auth.DummyHash)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ok, err := auth.VerifyPassword(password, hash)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	return user, nil
+}
@@ -0,0 +1,146 @@
+package users
+
+import (
+	"encoding/json"
+	"github/MahfujulSagor/movies_crud/internals/apperr"
+	"github/MahfujulSagor/movies_crud/internals/auth"
+	"github/MahfujulSagor/movies_crud/internals/config"
+	"github/MahfujulSagor/movies_crud/internals/db/sqlite"
+	"github/MahfujulSagor/movies_crud/internals/logger"
+	"github/MahfujulSagor/movies_crud/internals/utils/response"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func init() {
+	logger.Info = log.New(io.Discard, "", 0)
+	logger.Error = log.New(io.Discard, "", 0)
+}
+
+func newStore(t *testing.T) *sqlite.SQLite {
+	t.Helper()
+	store, err := sqlite.New(&config.Config{DBPath: filepath.Join(t.TempDir(), "movies.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func login(t *testing.T, store *sqlite.SQLite, body string) (*httptest.ResponseRecorder, Session) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	Login(store, time.Hour)(rec, httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", strings.NewReader(body)))
+
+	var session Session
+	if rec.Code == http.StatusOK {
+		if err := json.Unmarshal(rec.Body.Bytes(), &session); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return rec, session
+}
+
+func TestLoginAndLogout(t *testing.T) {
+	store := newStore(t)
+	if _, err := Create(store, "ana", "correct horse", auth.RoleEditor); err != nil {
+		t.Fatal(err)
+	}
+	a := &auth.Authenticator{Keys: store, Users: store}
+
+	rec, session := login(t, store, `{"username": "ana", "password": "correct horse"}`)
+	if rec.Code != http.StatusOK || session.User == nil || session.User.Username != "ana" || session.ExpiresAt.Before(time.Now()) {
+		t.Fatalf("login returned %d %s", rec.Code, rec.Body)
+	}
+
+	//? The scheme is case-insensitive for both authentication and logout
+	authorization := "bearer " + session.Token
+	identity, err := a.Check("", authorization, auth.ScopeMoviesWrite)
+	if err != nil {
+		t.Fatal("session did not authenticate:", err)
+	}
+	if identity.Method != "session" || !slices.Equal(identity.Roles, []string{auth.RoleEditor}) || identity.HasScope(auth.ScopeMoviesDelete) {
+		t.Fatalf("session identity %+v", identity)
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/auth/logout", nil)
+	r.Header.Set("Authorization", authorization)
+	rec = httptest.NewRecorder()
+	Logout(store)(rec, r)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("logout returned %d %s", rec.Code, rec.Body)
+	}
+
+	if _, err := a.Check("", authorization, auth.ScopeMoviesRead); err == nil {
+		t.Fatal("session still authenticates after logout")
+	}
+}
+
+func TestLoginRejectsBadCredentials(t *testing.T) {
+	store := newStore(t)
+	if _, err := Create(store, "ana", "correct horse", auth.RoleViewer); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, body := range []string{
+		`{"username": "ana", "password": "wrong horse"}`,
+		`{"username": "bob", "password": "correct horse"}`,
+	} {
+		rec, _ := login(t, store, body)
+
+		var problem response.Problem
+		json.Unmarshal(rec.Body.Bytes(), &problem)
+		if rec.Code != http.StatusUnauthorized || problem.Code != apperr.CodeInvalidCredentials {
+			t.Errorf("%s: got %d %s", body, rec.Code, problem.Code)
+		}
+		//? Unknown users and wrong passwords must look the same
+		if problem.Detail != "invalid username or password" {
+			t.Errorf("%s: detail %q", body, problem.Detail)
+		}
+	}
+}
+
+func TestLogoutRequiresToken(t *testing.T) {
+	for _, header := range []string{"", "Basic abc", "Bearer "} {
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/auth/logout", nil)
+		r.Header.Set("Authorization", header)
+		rec := httptest.NewRecorder()
+		Logout(newStore(t))(rec, r)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("Authorization %q: got %d, want 401", header, rec.Code)
+		}
+	}
+}
+
+func TestNewStoresHashedPassword(t *testing.T) {
+	store := newStore(t)
+
+	rec := httptest.NewRecorder()
+	New(store)(rec, httptest.NewRequest(http.MethodPost, "/api/v1/admin/users",
+		strings.NewReader(`{"username": "ana", "role": "admin", "password": "correct horse"}`)))
+	if rec.Code != http.StatusCreated || strings.Contains(rec.Body.String(), "correct horse") {
+		t.Fatalf("create returned %d %s", rec.Code, rec.Body)
+	}
+
+	_, hash, err := store.GetUserByUsername("ana")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := auth.VerifyPassword("correct horse", hash); !ok || err != nil {
+		t.Fatalf("stored hash %q does not verify: %v", hash, err)
+	}
+
+	rec = httptest.NewRecorder()
+	New(store)(rec, httptest.NewRequest(http.MethodPost, "/api/v1/admin/users",
+		strings.NewReader(`{"username": "bob", "role": "owner", "password": "correct horse"}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("unknown role returned %d", rec.Code)
+	}
+}
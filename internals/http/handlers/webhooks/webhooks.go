@@ -0,0 +1,235 @@
+package webhooks
+
+import (
+	"fmt"
+	"github/MahfujulSagor/movies_crud/internals/apperr"
+	"github/MahfujulSagor/movies_crud/internals/db"
+	"github/MahfujulSagor/movies_crud/internals/http/negotiate"
+	"github/MahfujulSagor/movies_crud/internals/logger"
+	"github/MahfujulSagor/movies_crud/internals/types"
+	"github/MahfujulSagor/movies_crud/internals/utils/response"
+	"github/MahfujulSagor/movies_crud/internals/validation"
+	"github/MahfujulSagor/movies_crud/internals/webhooks"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// New subscribes a URL to events. Unless allowPrivate is set, URLs resolving to internal addresses are refused.
+func New(store db.WebhookStore, allowPrivate bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info.Println("Create webhook handler called")
+
+		//? Decode JSON into Webhook struct; other formats get 415
+		var hook types.Webhook
+		if err := negotiate.DecodeJSON(r, &hook); err != nil {
+			response.WriteProblem(w, r, err)
+			logger.Error.Println("Error decoding webhook:", err)
+			return
+		}
+		defer r.Body.Close()
+
+		//? Request validation
+		if err := validation.Request(r, hook); err != nil {
+			response.WriteProblem(w, r, err)
+			logger.Error.Println("Validation error:", err)
+			return
+		}
+
+		if u, err := url.Parse(hook.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			response.WriteProblem(w, r, apperr.Validation([]apperr.FieldError{{Field: "url", Rule: "url", Message: "url must be an http or https URL"}}))
+			return
+		}
+
+		//? Webhooks must not become a way to reach internal services or cloud metadata from the server
+		if !allowPrivate {
+			if err := webhooks.CheckURL(r.Context(), hook.URL); err != nil {
+				response.WriteProblem(w, r, apperr.Validation([]apperr.FieldError{{Field: "url", Rule: "public_url",
+					Message: "url must resolve to public addresses only, not loopback, private or link-local ones"}}))
+				logger.Error.Println("Rejected webhook URL:", err)
+				return
+			}
+		}
+
+		//? Generate a secret unless the subscriber supplied their own
+		if hook.Secret == "" {
+			secret, err := webhooks.GenerateSecret()
+			if err != nil {
+				response.WriteProblem(w, r, apperr.Internal(err))
+				logger.Error.Println("Error generating webhook secret:", err)
+				return
+			}
+			hook.Secret = secret
+		}
+		hook.CreatedAt = time.Now().UTC()
+
+		id, err := store.CreateWebhook(r.Context(), &hook)
+		if err != nil {
+			response.WriteProblem(w, r, apperr.FromDB(err, apperr.NotFound(apperr.CodeNotFound, "referenced record not found")))
+			logger.Error.Println("Failed to create webhook:", err)
+			return
+		}
+		hook.ID = id
+
+		logger.Info.Println("Webhook created with ID:", id)
+
+		//? The secret is only ever returned here
+		response.WriteJson(w, http.StatusCreated, hook)
+	}
+}
+
+func List(store db.WebhookStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info.Println("List webhooks handler called")
+
+		hooks, err := store.ListWebhooks(r.Context())
+		if err != nil {
+			response.WriteProblem(w, r, apperr.Internal(err))
+			logger.Error.Println("Error listing webhooks:", err)
+			return
+		}
+
+		if len(hooks) == 0 {
+			response.WriteJson(w, http.StatusOK, []types.Webhook{})
+			return
+		}
+
+		response.WriteJson(w, http.StatusOK, hooks)
+	}
+}
+
+func Delete(store db.WebhookStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info.Println("Delete webhook handler called")
+
+		id, ok := pathID(w, r)
+		if !ok {
+			return
+		}
+
+		deleted_id, err := store.DeleteWebhook(r.Context(), id)
+		if err != nil {
+			response.WriteProblem(w, r, apperr.FromDB(err, webhookNotFound(id)))
+			logger.Error.Println("Failed to delete webhook:", err)
+			return
+		}
+
+		response.WriteJson(w, http.StatusOK, response.Message{
+			Success: response.StatusOK,
+			Message: fmt.Sprintf("Webhook deleted with ID %d", deleted_id),
+		})
+	}
+}
+
+// Deliveries lists a webhook's delivery history, newest first
+func Deliveries(store db.WebhookStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info.Println("List webhook deliveries handler called")
+
+		id, ok := pathID(w, r)
+		if !ok {
+			return
+		}
+
+		if _, err := store.GetWebhook(r.Context(), id); err != nil {
+			response.WriteProblem(w, r, apperr.FromDB(err, webhookNotFound(id)))
+			logger.Error.Println("Error loading webhook:", err)
+			return
+		}
+
+		limit, offset, err := page(r)
+		if err != nil {
+			response.WriteProblem(w, r, err)
+			return
+		}
+
+		deliveries, err := store.ListDeliveries(r.Context(), id, limit, offset)
+		if err != nil {
+			response.WriteProblem(w, r, apperr.Internal(err))
+			logger.Error.Println("Error listing webhook deliveries:", err)
+			return
+		}
+
+		if len(deliveries) == 0 {
+			response.WriteJson(w, http.StatusOK, []types.WebhookDelivery{})
+			return
+		}
+
+		response.WriteJson(w, http.StatusOK, deliveries)
+	}
+}
+
+// Redeliver queues a fresh copy of a delivery for immediate sending; the original stays in the history
+func Redeliver(store db.WebhookStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Info.Println("Redeliver webhook handler called")
+
+		id, ok := pathID(w, r)
+		if !ok {
+			return
+		}
+
+		original, err := store.GetDelivery(r.Context(), id)
+		if err != nil {
+			response.WriteProblem(w, r, apperr.FromDB(err, apperr.NotFound(apperr.CodeDeliveryNotFound, "webhook delivery %d not found", id)))
+			logger.Error.Println("Error loading webhook delivery:", err)
+			return
+		}
+
+		now := time.Now().UTC()
+		redelivery := &types.WebhookDelivery{
+			WebhookID:     original.WebhookID,
+			EventType:     original.EventType,
+			Payload:       original.Payload,
+			Status:        db.DeliveryPending,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+		}
+
+		redelivery.ID, err = store.EnqueueDelivery(r.Context(), redelivery)
+		if err != nil {
+			response.WriteProblem(w, r, apperr.FromDB(err, webhookNotFound(original.WebhookID)))
+			logger.Error.Println("Failed to enqueue redelivery:", err)
+			return
+		}
+
+		response.WriteJson(w, http.StatusAccepted, redelivery)
+	}
+}
+
+func pathID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		response.WriteProblem(w, r, apperr.BadRequest(apperr.CodeInvalidID, "invalid ID %q", r.PathValue("id")))
+		logger.Error.Println("Error parsing ID:", err)
+		return 0, false
+	}
+	return id, true
+}
+
+func page(r *http.Request) (int, int, error) {
+	limit, offset := 20, 0
+	query := r.URL.Query()
+
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return 0, 0, apperr.BadRequest(apperr.CodeInvalidQuery, "invalid limit value %q", v)
+		}
+		limit = min(n, 100)
+	}
+	if v := query.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return 0, 0, apperr.BadRequest(apperr.CodeInvalidQuery, "invalid offset value %q", v)
+		}
+		offset = n
+	}
+
+	return limit, offset, nil
+}
+
+func webhookNotFound(id int64) *apperr.Error {
+	return apperr.NotFound(apperr.CodeWebhookNotFound, "webhook %d not found", id)
+}
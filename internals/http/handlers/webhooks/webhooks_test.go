@@ -0,0 +1,162 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"github/MahfujulSagor/movies_crud/internals/apperr"
+	"github/MahfujulSagor/movies_crud/internals/config"
+	"github/MahfujulSagor/movies_crud/internals/db"
+	"github/MahfujulSagor/movies_crud/internals/db/sqlite"
+	"github/MahfujulSagor/movies_crud/internals/logger"
+	"github/MahfujulSagor/movies_crud/internals/types"
+	"github/MahfujulSagor/movies_crud/internals/utils/response"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func init() {
+	logger.Info = log.New(io.Discard, "", 0)
+	logger.Error = log.New(io.Discard, "", 0)
+}
+
+func newStore(t *testing.T) *sqlite.SQLite {
+	t.Helper()
+	store, err := sqlite.New(&config.Config{DBPath: filepath.Join(t.TempDir(), "movies.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+// withID sends r to handler with the {id} path value set and returns the recorder
+func withID(handler http.HandlerFunc, r *http.Request, id string) *httptest.ResponseRecorder {
+	r.SetPathValue("id", id)
+	rec := httptest.NewRecorder()
+	handler(rec, r)
+	return rec
+}
+
+func problemCode(rec *httptest.ResponseRecorder) string {
+	var problem response.Problem
+	json.Unmarshal(rec.Body.Bytes(), &problem)
+	return problem.Code
+}
+
+func TestNew(t *testing.T) {
+	store := newStore(t)
+
+	rec := httptest.NewRecorder()
+	New(store, true)(rec, httptest.NewRequest(http.MethodPost, "/api/v1/admin/webhooks",
+		strings.NewReader(`{"url": "http://127.0.0.1:9000/hook", "events": ["movie.created"]}`)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	var created types.Webhook
+	json.Unmarshal(rec.Body.Bytes(), &created)
+	if !strings.HasPrefix(created.Secret, "whsec_") || created.ID == 0 {
+		t.Fatalf("created webhook %+v has no generated secret", created)
+	}
+
+	//? The secret is only shown on creation
+	rec = httptest.NewRecorder()
+	List(store)(rec, httptest.NewRequest(http.MethodGet, "/api/v1/admin/webhooks", nil))
+	if strings.Contains(rec.Body.String(), created.Secret) {
+		t.Fatalf("listing %s contains the secret", rec.Body)
+	}
+
+	for body, rule := range map[string]string{
+		`{"url": "http://127.0.0.1:9000/hook", "events": ["movie.created"]}`: "public_url",
+		`{"url": "ftp://example.com/hook", "events": ["movie.created"]}`:     "url",
+		`{"url": "https://example.com/hook", "events": ["movie.renamed"]}`:   "oneof",
+	} {
+		rec := httptest.NewRecorder()
+		New(store, false)(rec, httptest.NewRequest(http.MethodPost, "/api/v1/admin/webhooks", strings.NewReader(body)))
+
+		var problem response.Problem
+		json.Unmarshal(rec.Body.Bytes(), &problem)
+		if rec.Code != http.StatusBadRequest || len(problem.Errors) != 1 || problem.Errors[0].Rule != rule {
+			t.Errorf("%s: got %d %+v, want a %q validation error", body, rec.Code, problem, rule)
+		}
+	}
+}
+
+func TestRedeliver(t *testing.T) {
+	store := newStore(t)
+	ctx := context.Background()
+
+	hook := &types.Webhook{URL: "https://example.com/hook", Events: []string{"movie.created"}, Secret: "whsec_test_secret", CreatedAt: time.Now().UTC()}
+	hookID, err := store.CreateWebhook(ctx, hook)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().UTC()
+	originalID, err := store.EnqueueDelivery(ctx, &types.WebhookDelivery{
+		WebhookID: hookID, EventID: 42, EventType: "movie.created", Payload: `{"id":42}`, NextAttemptAt: now, CreatedAt: now,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	original, _ := store.GetDelivery(ctx, originalID)
+	original.Status, original.Attempts, original.LastError = db.DeliveryDead, 8, "receiver responded 500"
+	if err := store.UpdateDelivery(ctx, original); err != nil {
+		t.Fatal(err)
+	}
+
+	//? A dead delivery can be sent again, more than once; each copy is a new pending delivery
+	for range 2 {
+		rec := withID(Redeliver(store), httptest.NewRequest(http.MethodPost, "/", nil), strconv.FormatInt(originalID, 10))
+		if rec.Code != http.StatusAccepted {
+			t.Fatalf("redeliver returned %d: %s", rec.Code, rec.Body)
+		}
+
+		var redelivery types.WebhookDelivery
+		json.Unmarshal(rec.Body.Bytes(), &redelivery)
+		stored, err := store.GetDelivery(ctx, redelivery.ID)
+		if err != nil || redelivery.ID == originalID {
+			t.Fatalf("redelivery %d not stored as a new delivery: %v", redelivery.ID, err)
+		}
+		if stored.Status != db.DeliveryPending || stored.Attempts != 0 || stored.Payload != original.Payload || stored.WebhookID != hookID {
+			t.Fatalf("redelivery %+v is not a fresh copy of %+v", stored, original)
+		}
+	}
+
+	if kept, _ := store.GetDelivery(ctx, originalID); kept.Status != db.DeliveryDead || kept.Attempts != 8 {
+		t.Fatalf("the original became %s with %d attempts", kept.Status, kept.Attempts)
+	}
+
+	rec := withID(Deliveries(store), httptest.NewRequest(http.MethodGet, "/", nil), strconv.FormatInt(hookID, 10))
+	var history []types.WebhookDelivery
+	json.Unmarshal(rec.Body.Bytes(), &history)
+	if len(history) != 3 || history[2].ID != originalID {
+		t.Fatalf("delivery log has %d entries, want the original and two redeliveries, newest first", len(history))
+	}
+
+	for _, tc := range []struct {
+		id     string
+		status int
+		code   string
+	}{
+		{"999", http.StatusNotFound, apperr.CodeDeliveryNotFound},
+		{"abc", http.StatusBadRequest, apperr.CodeInvalidID},
+	} {
+		rec := withID(Redeliver(store), httptest.NewRequest(http.MethodPost, "/", nil), tc.id)
+		if rec.Code != tc.status || problemCode(rec) != tc.code {
+			t.Errorf("redelivering %s returned %d %s, want %d %s", tc.id, rec.Code, problemCode(rec), tc.status, tc.code)
+		}
+	}
+}
+
+func TestDeliveriesOfUnknownWebhook(t *testing.T) {
+	rec := withID(Deliveries(newStore(t)), httptest.NewRequest(http.MethodGet, "/", nil), "7")
+	if rec.Code != http.StatusNotFound || problemCode(rec) != apperr.CodeWebhookNotFound {
+		t.Fatalf("got %d %s, want 404 %s", rec.Code, problemCode(rec), apperr.CodeWebhookNotFound)
+	}
+}
@@ -0,0 +1,59 @@
+// Package httpcache sets caching headers and answers conditional GETs.
+package httpcache
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// NoStore is the policy of routes that do not declare one
+const NoStore = "no-store"
+
+// Headers sets Cache-Control and Vary before next runs, so 304s carry them too. Problem responses
+// replace the policy with no-store, so errors are never cached.
+func Headers(policy string, vary []string, next http.HandlerFunc) http.HandlerFunc {
+	if policy == "" {
+		policy = NoStore
+	}
+	varyValue := strings.Join(vary, ", ")
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", policy)
+		if varyValue != "" {
+			//? Added rather than set, so later layers such as compression can append theirs
+			w.Header().Add("Vary", varyValue)
+		}
+		next(w, r)
+	}
+}
+
+// NotModified sets Last-Modified and, when the client's copy from If-Modified-Since is still current,
+// writes 304 and returns true. A zero modified time disables both. If-None-Match takes precedence as
+// RFC 9110 requires; no ETags are issued, so only "*" matches.
+func NotModified(w http.ResponseWriter, r *http.Request, modified time.Time) bool {
+	if modified.IsZero() {
+		return false
+	}
+	//? HTTP dates have one-second resolution
+	modified = modified.UTC().Truncate(time.Second)
+	w.Header().Set("Last-Modified", modified.Format(http.TimeFormat))
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	if match, ok := r.Header["If-None-Match"]; ok {
+		if strings.TrimSpace(strings.Join(match, ",")) != "*" {
+			return false
+		}
+		w.WriteHeader(http.StatusNotModified)
+		return true
+	}
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil || modified.After(since) {
+		return false
+	}
+
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
@@ -0,0 +1,74 @@
+package httpcache
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestHeaders(t *testing.T) {
+	for _, tc := range []struct {
+		policy   string
+		vary     []string
+		want     string
+		wantVary []string
+	}{
+		{"", nil, NoStore, []string{"Accept-Encoding"}},
+		{"public, max-age=60", []string{"Accept", "Accept-Language"}, "public, max-age=60", []string{"Accept, Accept-Language", "Accept-Encoding"}},
+	} {
+		rec := httptest.NewRecorder()
+		Headers(tc.policy, tc.vary, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+			w.WriteHeader(http.StatusNotModified)
+		})(rec, httptest.NewRequest(http.MethodGet, "/api/v1/movies", nil))
+
+		if got := rec.Header().Get("Cache-Control"); got != tc.want {
+			t.Errorf("policy %q: Cache-Control %q, want %q", tc.policy, got, tc.want)
+		}
+		//? Later layers append to Vary rather than replacing it
+		if vary := rec.Header().Values("Vary"); !slices.Equal(vary, tc.wantVary) {
+			t.Errorf("policy %q: Vary %q, want %q", tc.policy, vary, tc.wantVary)
+		}
+	}
+}
+
+func TestNotModified(t *testing.T) {
+	modified := time.Date(2026, 3, 1, 12, 0, 0, 500_000_000, time.UTC)
+	current := modified.Truncate(time.Second).Format(http.TimeFormat)
+	older := modified.Add(-time.Hour).Format(http.TimeFormat)
+
+	for _, tc := range []struct {
+		name     string
+		method   string
+		headers  map[string]string
+		modified time.Time
+		want     bool
+	}{
+		{"no validator", http.MethodGet, nil, modified, false},
+		//? The sub-second part is dropped, or a copy from the same second would look stale
+		{"same second", http.MethodGet, map[string]string{"If-Modified-Since": current}, modified, true},
+		{"later copy", http.MethodHead, map[string]string{"If-Modified-Since": modified.Add(time.Hour).Format(http.TimeFormat)}, modified, true},
+		{"stale copy", http.MethodGet, map[string]string{"If-Modified-Since": older}, modified, false},
+		{"bad date", http.MethodGet, map[string]string{"If-Modified-Since": "yesterday"}, modified, false},
+		{"not a read", http.MethodPost, map[string]string{"If-Modified-Since": current}, modified, false},
+		{"unknown time", http.MethodGet, map[string]string{"If-Modified-Since": current}, time.Time{}, false},
+		{"If-None-Match wins", http.MethodGet, map[string]string{"If-Modified-Since": current, "If-None-Match": `"abc"`}, modified, false},
+		{"If-None-Match star", http.MethodGet, map[string]string{"If-Modified-Since": older, "If-None-Match": "*"}, modified, true},
+	} {
+		r := httptest.NewRequest(tc.method, "/api/v1/movies/1", nil)
+		for name, value := range tc.headers {
+			r.Header.Set(name, value)
+		}
+		rec := httptest.NewRecorder()
+
+		got := NotModified(rec, r, tc.modified)
+		if got != tc.want || got != (rec.Code == http.StatusNotModified) {
+			t.Errorf("%s: NotModified = %v with status %d, want %v", tc.name, got, rec.Code, tc.want)
+		}
+		if lastModified := rec.Header().Get("Last-Modified"); tc.modified.IsZero() != (lastModified == "") || !tc.modified.IsZero() && lastModified != current {
+			t.Errorf("%s: Last-Modified %q", tc.name, lastModified)
+		}
+	}
+}
@@ -0,0 +1,283 @@
+package negotiate
+
+import (
+	"encoding"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"gopkg.in/yaml.v3"
+)
+
+func encodeJSON(w io.Writer, v any) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+func decodeJSON(r io.Reader, v any) error {
+	return json.NewDecoder(r).Decode(v)
+}
+
+// encodeXML names the root after the value's type, so a movie is <movie> and a list of them <movies>
+func encodeXML(w io.Writer, v any) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice {
+		if err := enc.EncodeElement(v, start(elementName(rv.Type()))); err != nil {
+			return err
+		}
+		return enc.Close()
+	}
+
+	item := elementName(rv.Type().Elem())
+	list := start(item + "s")
+	if err := enc.EncodeToken(list); err != nil {
+		return err
+	}
+	for i := 0; i < rv.Len(); i++ {
+		if err := enc.EncodeElement(rv.Index(i).Interface(), start(item)); err != nil {
+			return err
+		}
+	}
+	if err := enc.EncodeToken(list.End()); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+func decodeXML(r io.Reader, v any) error {
+	return xml.NewDecoder(r).Decode(v)
+}
+
+// encodeCSV writes one row per element with a header of JSON field names; nested structs
+// are flattened with their field name as prefix, e.g. director_name
+func encodeCSV(w io.Writer, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice {
+		return fmt.Errorf("csv can only encode lists, not %s", rv.Type())
+	}
+	elem := rv.Type().Elem()
+	for elem.Kind() == reflect.Pointer {
+		elem = elem.Elem()
+	}
+	if elem.Kind() != reflect.Struct {
+		return fmt.Errorf("csv can only encode lists of objects, not %s", rv.Type())
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader(elem, "")); err != nil {
+		return err
+	}
+	for i := 0; i < rv.Len(); i++ {
+		if err := cw.Write(csvRow(rv.Index(i), elem, nil)); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// encodeYAML converts through JSON so field names and order match the JSON representation
+func encodeYAML(w io.Writer, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	//? JSON is valid YAML, so it parses into a node tree that keeps key order
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	blockStyle(&doc)
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// decodeYAML converts through JSON so the JSON field names apply
+func decodeYAML(r io.Reader, v any) error {
+	var doc any
+	if err := yaml.NewDecoder(r).Decode(&doc); err != nil {
+		return err
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func encodeMsgPack(w io.Writer, v any) error {
+	enc := msgpack.NewEncoder(w)
+	enc.SetCustomStructTag("json")
+	return enc.Encode(v)
+}
+
+func decodeMsgPack(r io.Reader, v any) error {
+	dec := msgpack.NewDecoder(r)
+	dec.SetCustomStructTag("json")
+	return dec.Decode(v)
+}
+
+func start(name string) xml.StartElement {
+	return xml.StartElement{Name: xml.Name{Local: name}}
+}
+
+// elementName turns a type name such as WebhookDelivery into webhook_delivery
+func elementName(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	var b strings.Builder
+	for i, r := range t.Name() {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	if b.Len() == 0 {
+		return "item"
+	}
+	return b.String()
+}
+
+// blockStyle drops the flow and quoting styles inherited from JSON; the encoder still quotes
+// strings that would otherwise read as another type
+func blockStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, child := range n.Content {
+		blockStyle(child)
+	}
+}
+
+var textMarshaler = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+// csvField is a struct field that becomes one column or, for nested structs, a group of them
+type csvField struct {
+	index     int
+	name      string
+	nested    bool
+	omitEmpty bool
+}
+
+func csvFields(t reflect.Type) []csvField {
+	var fields []csvField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		ft := f.Type
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		nested := ft.Kind() == reflect.Struct && !reflect.PointerTo(ft).Implements(textMarshaler)
+		omitEmpty := strings.Contains(","+opts+",", ",omitempty,")
+		fields = append(fields, csvField{index: i, name: name, nested: nested, omitEmpty: omitEmpty})
+	}
+	return fields
+}
+
+func csvHeader(t reflect.Type, prefix string) []string {
+	var header []string
+	for _, f := range csvFields(t) {
+		if f.nested {
+			header = append(header, csvHeader(indirect(t.Field(f.index).Type), prefix+f.name+"_")...)
+			continue
+		}
+		header = append(header, prefix+f.name)
+	}
+	return header
+}
+
+// csvRow appends the cells of v, which has type t; a nil nested struct leaves its columns empty
+func csvRow(v reflect.Value, t reflect.Type, row []string) []string {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return append(row, make([]string, len(csvHeader(t, "")))...)
+		}
+		v = v.Elem()
+	}
+
+	for _, f := range csvFields(t) {
+		field := v.Field(f.index)
+		if f.nested {
+			row = csvRow(field, indirect(field.Type()), row)
+			continue
+		}
+		//? Leave the cell empty where JSON would omit the field
+		if f.omitEmpty && field.IsZero() {
+			row = append(row, "")
+			continue
+		}
+		row = append(row, csvCell(field))
+	}
+	return row
+}
+
+func csvCell(v reflect.Value) string {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+
+	switch value := v.Interface().(type) {
+	case time.Time:
+		if value.IsZero() {
+			return ""
+		}
+		return value.Format(time.RFC3339)
+	case encoding.TextMarshaler:
+		text, err := value.MarshalText()
+		if err != nil {
+			return ""
+		}
+		return string(text)
+	}
+
+	if v.Kind() == reflect.Slice || v.Kind() == reflect.Array {
+		cells := make([]string, v.Len())
+		for i := range cells {
+			cells[i] = csvCell(v.Index(i))
+		}
+		return strings.Join(cells, ";")
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+func indirect(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
@@ -0,0 +1,232 @@
+// Package negotiate picks response formats from the Accept header and decodes request bodies by Content-Type.
+package negotiate
+
+import (
+	"bytes"
+	"context"
+	"github/MahfujulSagor/movies_crud/internals/apperr"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	JSON    = "application/json"
+	XML     = "application/xml"
+	CSV     = "text/csv"
+	YAML    = "application/yaml"
+	MsgPack = "application/msgpack"
+)
+
+// Codec encodes and decodes one media type
+type Codec struct {
+	MediaType string
+	// Aliases are other media types clients commonly send for the same format
+	Aliases []string
+	Encode  func(w io.Writer, v any) error
+	// Decode is nil for formats that cannot carry a request body
+	Decode func(r io.Reader, v any) error
+}
+
+// codecs are in order of preference, so JSON wins whenever the client has no preference
+var codecs = []*Codec{
+	{MediaType: JSON, Encode: encodeJSON, Decode: decodeJSON},
+	{MediaType: XML, Aliases: []string{"text/xml"}, Encode: encodeXML, Decode: decodeXML},
+	{MediaType: CSV, Encode: encodeCSV},
+	{MediaType: YAML, Aliases: []string{"application/x-yaml", "text/yaml"}, Encode: encodeYAML, Decode: decodeYAML},
+	{MediaType: MsgPack, Aliases: []string{"application/x-msgpack", "application/vnd.msgpack"}, Encode: encodeMsgPack, Decode: decodeMsgPack},
+}
+
+// Formats returns the media types a response can be sent in; CSV only suits lists
+func Formats(list bool) []string {
+	var types []string
+	for _, c := range codecs {
+		if c.MediaType == CSV && !list {
+			continue
+		}
+		types = append(types, c.MediaType)
+	}
+	return types
+}
+
+// Decodable returns the media types a request body can be sent in
+func Decodable() []string {
+	var types []string
+	for _, c := range codecs {
+		if c.Decode != nil {
+			types = append(types, c.MediaType)
+		}
+	}
+	return types
+}
+
+// Select returns the offered codec the Accept header ranks highest, preferring earlier offers on ties.
+// A missing Accept header selects the first offer.
+func Select(accept string, offered []string) (*Codec, bool) {
+	ranges := parseAccept(accept)
+
+	var best *Codec
+	bestQ := 0.0
+	for _, mediaType := range offered {
+		c := lookup(mediaType)
+		if c == nil {
+			continue
+		}
+		if len(ranges) == 0 {
+			return c, true
+		}
+		if q := c.quality(ranges); q > bestQ {
+			best, bestQ = c, q
+		}
+	}
+	return best, best != nil
+}
+
+// BodyType is the media type Decode reads the body as: JSON when Content-Type is missing, and the
+// format's own media type for an alias or one with parameters. Unknown types are returned as sent.
+func BodyType(r *http.Request) string {
+	ct := r.Header.Get("Content-Type")
+	if ct == "" {
+		return JSON
+	}
+	mediaType, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return ct
+	}
+	if c := lookup(mediaType); c != nil {
+		return c.MediaType
+	}
+	return mediaType
+}
+
+// Decode reads the request body into v in the format named by Content-Type, JSON when it is missing.
+// Failures are *apperr.Error values ready to be written as problems.
+func Decode(r *http.Request, v any) error {
+	return decode(r, v, Decodable())
+}
+
+// DecodeJSON is Decode for endpoints whose bodies are only documented as JSON; other formats get 415
+func DecodeJSON(r *http.Request, v any) error {
+	return decode(r, v, []string{JSON})
+}
+
+func decode(r *http.Request, v any, accepted []string) error {
+	mediaType := JSON
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		parsed, _, err := mime.ParseMediaType(ct)
+		if err != nil {
+			return unsupported(ct, accepted)
+		}
+		mediaType = parsed
+	}
+
+	c := lookup(mediaType)
+	if c == nil || c.Decode == nil || !slices.Contains(accepted, c.MediaType) {
+		return unsupported(mediaType, accepted)
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return apperr.BadRequest(apperr.CodeMalformedBody, "reading body: %v", err)
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		return apperr.BadRequest(apperr.CodeEmptyBody, "empty body")
+	}
+
+	if err := c.Decode(bytes.NewReader(body), v); err != nil {
+		return apperr.BadRequest(apperr.CodeMalformedBody, "malformed %s body: %v", c.MediaType, err)
+	}
+	return nil
+}
+
+type codecKey struct{}
+
+// WithCodec records the codec selected for the request
+func WithCodec(ctx context.Context, c *Codec) context.Context {
+	return context.WithValue(ctx, codecKey{}, c)
+}
+
+// FromContext returns the codec selected for the request, JSON when none was
+func FromContext(ctx context.Context) *Codec {
+	if c, ok := ctx.Value(codecKey{}).(*Codec); ok {
+		return c
+	}
+	return codecs[0]
+}
+
+func unsupported(mediaType string, accepted []string) error {
+	return apperr.New(apperr.KindUnsupportedMediaType, apperr.CodeUnsupportedMediaType,
+		"unsupported Content-Type %q, use one of %s", mediaType, strings.Join(accepted, ", "))
+}
+
+func lookup(mediaType string) *Codec {
+	mediaType = strings.ToLower(mediaType)
+	for _, c := range codecs {
+		if c.MediaType == mediaType {
+			return c
+		}
+		for _, alias := range c.Aliases {
+			if alias == mediaType {
+				return c
+			}
+		}
+	}
+	return nil
+}
+
+// mediaRange is one element of an Accept header such as "text/*;q=0.5"
+type mediaRange struct {
+	typ, subtype string
+	q            float64
+}
+
+func parseAccept(accept string) []mediaRange {
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		typ, subtype, ok := strings.Cut(mediaType, "/")
+		if !ok {
+			continue
+		}
+
+		q := 1.0
+		if value, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(value, 64); err != nil || q < 0 || q > 1 {
+				continue
+			}
+		}
+		ranges = append(ranges, mediaRange{typ: typ, subtype: subtype, q: q})
+	}
+	return ranges
+}
+
+// quality is the q-value of the most specific range matching c, zero when none does
+func (c *Codec) quality(ranges []mediaRange) float64 {
+	q, specificity := 0.0, -1
+	for _, name := range append([]string{c.MediaType}, c.Aliases...) {
+		typ, subtype, _ := strings.Cut(name, "/")
+		for _, r := range ranges {
+			var s int
+			switch {
+			case r.typ == typ && r.subtype == subtype:
+				s = 2
+			case r.typ == typ && r.subtype == "*":
+				s = 1
+			case r.typ == "*" && r.subtype == "*":
+				s = 0
+			default:
+				continue
+			}
+			if s > specificity {
+				q, specificity = r.q, s
+			}
+		}
+	}
+	return q
+}
@@ -0,0 +1,54 @@
+package negotiate
+
+import (
+	"errors"
+	"github/MahfujulSagor/movies_crud/internals/apperr"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type hook struct {
+	URL string `json:"url"`
+}
+
+func request(contentType, body string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	if contentType != "" {
+		r.Header.Set("Content-Type", contentType)
+	}
+	return r
+}
+
+func TestDecodeJSONRefusesOtherFormats(t *testing.T) {
+	for _, contentType := range []string{XML, YAML, MsgPack, "text/plain", "not a media type"} {
+		var v hook
+		err := DecodeJSON(request(contentType, `{"url": "https://example.com"}`), &v)
+
+		var appErr *apperr.Error
+		if !errors.As(err, &appErr) || appErr.Code != apperr.CodeUnsupportedMediaType {
+			t.Errorf("%s body returned %v, want %s", contentType, err, apperr.CodeUnsupportedMediaType)
+		}
+	}
+
+	for _, contentType := range []string{"", JSON, "application/json; charset=utf-8"} {
+		var v hook
+		if err := DecodeJSON(request(contentType, `{"url": "https://example.com"}`), &v); err != nil || v.URL != "https://example.com" {
+			t.Errorf("JSON body with Content-Type %q decoded to %+v, %v", contentType, v, err)
+		}
+	}
+}
+
+func TestDecodeReadsEveryDecodableFormat(t *testing.T) {
+	for contentType, body := range map[string]string{
+		JSON: `{"url": "https://example.com"}`,
+		YAML: "url: https://example.com\n",
+		XML:  `<hook><URL>https://example.com</URL></hook>`,
+	} {
+		var v hook
+		if err := Decode(request(contentType, body), &v); err != nil || v.URL != "https://example.com" {
+			t.Errorf("%s body decoded to %+v, %v", contentType, v, err)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+// Package secure sets browser hardening headers on every response.
+package secure
+
+import (
+	"github/MahfujulSagor/movies_crud/internals/config"
+	"github/MahfujulSagor/movies_crud/internals/http/clientip"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// apiCSP forbids everything, since API responses are data and never rendered as documents
+const apiCSP = "default-src 'none'; frame-ancestors 'none'"
+
+type Headers struct {
+	resolver *clientip.Resolver
+	hsts     string
+	pageCSP  string
+}
+
+// New uses resolver to tell HTTPS requests, including those forwarded by trusted proxies, from plain ones
+func New(cfg *config.Config, resolver *clientip.Resolver) *Headers {
+	sc := cfg.HTTPConfig.Security
+
+	var hsts string
+	if sc.HSTSMaxAge > 0 {
+		hsts = "max-age=" + strconv.Itoa(int(sc.HSTSMaxAge.Seconds()))
+		if sc.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+	}
+
+	return &Headers{resolver: resolver, hsts: hsts, pageCSP: sc.PageCSP}
+}
+
+func (s *Headers) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		h.Set("X-Content-Type-Options", "nosniff")
+		h.Set("X-Frame-Options", "DENY")
+		h.Set("Referrer-Policy", "no-referrer")
+		//? Browsers ignore HSTS over plain HTTP, and sending it there would only mislead
+		if s.hsts != "" && s.resolver.Scheme(r) == "https" {
+			h.Set("Strict-Transport-Security", s.hsts)
+		}
+
+		//? Upgraded connections such as WebSockets need the unwrapped writer to hijack
+		if r.Header.Get("Upgrade") != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(&writer{ResponseWriter: w, pageCSP: s.pageCSP}, r)
+	})
+}
+
+// writer picks the Content-Security-Policy once the handler has set the Content-Type
+type writer struct {
+	http.ResponseWriter
+	pageCSP     string
+	wroteHeader bool
+}
+
+func (w *writer) WriteHeader(status int) {
+	if !w.wroteHeader && status >= http.StatusOK {
+		w.wroteHeader = true
+		w.setCSP()
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *writer) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush sends the headers first if need be, so streamed responses get their policy too
+func (w *writer) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the connection's writer
+func (w *writer) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *writer) setCSP() {
+	h := w.Header()
+	if h.Get("Content-Security-Policy") != "" {
+		return
+	}
+	if strings.HasPrefix(h.Get("Content-Type"), "text/html") && w.pageCSP != "" {
+		h.Set("Content-Security-Policy", w.pageCSP)
+		return
+	}
+	h.Set("Content-Security-Policy", apiCSP)
+}
+
+// RedirectHTTPS answers every request with a permanent redirect to the same URL on the HTTPS port
+func RedirectHTTPS(port int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = r.Host
+		}
+		if port != 443 {
+			host = net.JoinHostPort(host, strconv.Itoa(port))
+		}
+
+		//? 308 keeps the method and body, so a POST is not silently turned into a GET
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+	})
+}
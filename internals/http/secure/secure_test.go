@@ -0,0 +1,131 @@
+package secure
+
+import (
+	"crypto/tls"
+	"github/MahfujulSagor/movies_crud/internals/config"
+	"github/MahfujulSagor/movies_crud/internals/http/clientip"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const pageCSP = "default-src 'self'; frame-ancestors 'none'"
+
+func testHeaders(t *testing.T) *Headers {
+	t.Helper()
+	resolver, err := clientip.New([]string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{}
+	cfg.HTTPConfig.Security = config.SecurityConfig{HSTSMaxAge: 24 * time.Hour, HSTSIncludeSubdomains: true, PageCSP: pageCSP}
+	return New(cfg, resolver)
+}
+
+func TestContentSecurityPolicyPerResponse(t *testing.T) {
+	s := testHeaders(t)
+
+	for _, tc := range []struct {
+		name    string
+		handler http.HandlerFunc
+		want    string
+	}{
+		{"JSON", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{}`))
+		}, apiCSP},
+		{"HTML page", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.WriteHeader(http.StatusOK)
+		}, pageCSP},
+		{"route's own policy", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html")
+			w.Header().Set("Content-Security-Policy", "default-src 'self' https://unpkg.com")
+			w.Write([]byte("<html>"))
+		}, "default-src 'self' https://unpkg.com"},
+		{"streamed", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			http.NewResponseController(w).Flush()
+		}, apiCSP},
+	} {
+		rec := httptest.NewRecorder()
+		s.Handler(tc.handler).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/docs", nil))
+
+		if got := rec.Header().Get("Content-Security-Policy"); got != tc.want {
+			t.Errorf("%s: Content-Security-Policy %q, want %q", tc.name, got, tc.want)
+		}
+		for name, want := range map[string]string{"X-Content-Type-Options": "nosniff", "X-Frame-Options": "DENY", "Referrer-Policy": "no-referrer"} {
+			if got := rec.Header().Get(name); got != want {
+				t.Errorf("%s: %s %q, want %q", tc.name, name, got, want)
+			}
+		}
+	}
+}
+
+func TestStrictTransportSecurityOnlyOverHTTPS(t *testing.T) {
+	s := testHeaders(t)
+
+	for _, tc := range []struct {
+		name       string
+		remoteAddr string
+		proto      string
+		tls        bool
+		want       bool
+	}{
+		{"TLS", "203.0.113.7:4000", "", true, true},
+		{"trusted proxy", "10.0.0.2:4000", "https", false, true},
+		{"untrusted proxy", "203.0.113.7:4000", "https", false, false},
+		{"plain HTTP", "10.0.0.2:4000", "", false, false},
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/movies", nil)
+		r.RemoteAddr = tc.remoteAddr
+		if tc.proto != "" {
+			r.Header.Set("X-Forwarded-Proto", tc.proto)
+		}
+		if tc.tls {
+			r.TLS = &tls.ConnectionState{}
+		}
+		rec := httptest.NewRecorder()
+		s.Handler(http.NotFoundHandler()).ServeHTTP(rec, r)
+
+		got := rec.Header().Get("Strict-Transport-Security")
+		if tc.want && got != "max-age=86400; includeSubDomains" || !tc.want && got != "" {
+			t.Errorf("%s: Strict-Transport-Security %q", tc.name, got)
+		}
+	}
+}
+
+func TestUpgradesKeepTheConnectionsWriter(t *testing.T) {
+	s := testHeaders(t)
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/events/ws", nil)
+	r.Header.Set("Upgrade", "websocket")
+	rec := httptest.NewRecorder()
+
+	s.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if w != http.ResponseWriter(rec) {
+			t.Error("the upgrade handler got a wrapped writer it cannot hijack")
+		}
+	})).ServeHTTP(rec, r)
+}
+
+func TestRedirectHTTPS(t *testing.T) {
+	for _, tc := range []struct {
+		port       int
+		host, want string
+	}{
+		{443, "movies.example.com", "https://movies.example.com/api/v1/movies?limit=5"},
+		{443, "movies.example.com:80", "https://movies.example.com/api/v1/movies?limit=5"},
+		{8443, "movies.example.com:8080", "https://movies.example.com:8443/api/v1/movies?limit=5"},
+	} {
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/movies?limit=5", nil)
+		r.Host = tc.host
+		rec := httptest.NewRecorder()
+		RedirectHTTPS(tc.port).ServeHTTP(rec, r)
+
+		//? 308 so the client repeats the POST rather than turning it into a GET
+		if rec.Code != http.StatusPermanentRedirect || rec.Header().Get("Location") != tc.want {
+			t.Errorf("port %d, host %s: got %d to %q, want 308 to %q", tc.port, tc.host, rec.Code, rec.Header().Get("Location"), tc.want)
+		}
+	}
+}
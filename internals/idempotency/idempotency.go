@@ -0,0 +1,181 @@
+// Package idempotency makes retried writes safe: a request carrying an Idempotency-Key is
+// executed once and its response replayed to every retry with the same key, body and formats.
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github/MahfujulSagor/movies_crud/internals/apperr"
+	"github/MahfujulSagor/movies_crud/internals/config"
+	"github/MahfujulSagor/movies_crud/internals/db"
+	"github/MahfujulSagor/movies_crud/internals/http/negotiate"
+	"github/MahfujulSagor/movies_crud/internals/logger"
+	"github/MahfujulSagor/movies_crud/internals/utils/response"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	Header = "Idempotency-Key"
+	// ReplayedHeader is set on responses served from the store instead of the handler
+	ReplayedHeader = "Idempotent-Replayed"
+
+	maxKeyLength = 255
+)
+
+// replayedHeaders are the response headers stored alongside the body
+var replayedHeaders = []string{"Content-Type", "Location"}
+
+type Guard struct {
+	Store db.IdempotencyStore
+	TTL   time.Duration
+}
+
+func New(cfg *config.Config, store db.IdempotencyStore) *Guard {
+	return &Guard{Store: store, TTL: cfg.IdempotencyConfig.TTL}
+}
+
+// Protect runs next at most once per caller and key. It must sit inside authentication so keys are scoped to the caller.
+func (g *Guard) Protect(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(Header)
+		if key == "" {
+			next(w, r)
+			return
+		}
+		if len(key) > maxKeyLength {
+			response.WriteProblem(w, r, apperr.BadRequest(apperr.CodeInvalidIdempotencyKey, "%s must be at most %d characters", Header, maxKeyLength))
+			return
+		}
+
+		//? Buffer the body so it can be fingerprinted and still read by the handler
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			response.WriteProblem(w, r, apperr.BadRequest(apperr.CodeMalformedBody, "reading body: %v", err))
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		now := time.Now().UTC()
+		rec := &db.IdempotencyRecord{
+			Key:         db.ActorFromContext(r.Context()) + " " + key,
+			Fingerprint: fingerprint(r, body),
+			CreatedAt:   now,
+			ExpiresAt:   now.Add(g.TTL),
+		}
+
+		existing, err := g.Store.ReserveIdempotencyKey(r.Context(), rec)
+		if errors.Is(err, db.ErrConflict) {
+			//? Lost a race with a concurrent request using the same key
+			existing, err = &db.IdempotencyRecord{Fingerprint: rec.Fingerprint}, nil
+		}
+		if err != nil {
+			response.WriteProblem(w, r, apperr.Internal(err))
+			logger.Error.Println("Error reserving idempotency key:", err)
+			return
+		}
+
+		if existing != nil {
+			g.replay(w, r, rec, existing)
+			return
+		}
+
+		//? Record the outcome even if the client has gone away, or the key would stay locked until it expires
+		ctx := context.WithoutCancel(r.Context())
+
+		//? A panicking handler leaves no outcome to store; free the key so a retry runs again, and let net/http handle the panic
+		defer func() {
+			if p := recover(); p != nil {
+				g.release(ctx, rec.Key)
+				panic(p)
+			}
+		}()
+
+		rw := &recorder{ResponseWriter: w}
+		next(rw, r)
+
+		//? Server errors are not stored, so a retry runs the request again
+		if rw.status == 0 || rw.status >= http.StatusInternalServerError {
+			g.release(ctx, rec.Key)
+			return
+		}
+
+		rec.Status = rw.status
+		rec.Body = rw.body.Bytes()
+		rec.Header = map[string]string{}
+		for _, name := range replayedHeaders {
+			if value := w.Header().Get(name); value != "" {
+				rec.Header[name] = value
+			}
+		}
+		if err := g.Store.CompleteIdempotencyKey(ctx, rec); err != nil {
+			logger.Error.Println("Error storing idempotent response:", err)
+		}
+	}
+}
+
+func (g *Guard) release(ctx context.Context, key string) {
+	if err := g.Store.ReleaseIdempotencyKey(ctx, key); err != nil {
+		logger.Error.Println("Error releasing idempotency key:", err)
+	}
+}
+
+func (g *Guard) replay(w http.ResponseWriter, r *http.Request, rec *db.IdempotencyRecord, existing *db.IdempotencyRecord) {
+	if existing.Fingerprint != rec.Fingerprint {
+		response.WriteProblem(w, r, apperr.New(apperr.KindUnprocessable, apperr.CodeIdempotencyKeyReused,
+			"%s was already used with a different request", Header))
+		return
+	}
+	if existing.Status == 0 {
+		w.Header().Set("Retry-After", "1")
+		response.WriteProblem(w, r, apperr.Conflict(apperr.CodeIdempotencyInProgress,
+			"a request with this %s is still being processed", Header))
+		return
+	}
+
+	logger.Info.Println("Replaying idempotent response for", r.Method, r.URL.Path)
+	for name, value := range existing.Header {
+		w.Header().Set(name, value)
+	}
+	w.Header().Set(ReplayedHeader, "true")
+	w.WriteHeader(existing.Status)
+	w.Write(existing.Body)
+}
+
+// fingerprint identifies the request a key was first used with. The body's format and the negotiated
+// response format are part of it, since the same bytes can mean something else in another format and
+// the stored response is only in the format first asked for.
+func fingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.Path+"\n")
+	io.WriteString(h, "Content-Type: "+negotiate.BodyType(r)+"\n")
+	io.WriteString(h, "Accept: "+negotiate.FromContext(r.Context()).MediaType+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// recorder passes the response through while keeping a copy to store
+type recorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rw *recorder) WriteHeader(status int) {
+	if rw.status == 0 {
+		rw.status = status
+	}
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+func (rw *recorder) Write(b []byte) (int, error) {
+	if rw.status == 0 {
+		rw.status = http.StatusOK
+	}
+	rw.body.Write(b)
+	return rw.ResponseWriter.Write(b)
+}
@@ -0,0 +1,114 @@
+package idempotency
+
+import (
+	"github/MahfujulSagor/movies_crud/internals/apperr"
+	"github/MahfujulSagor/movies_crud/internals/config"
+	"github/MahfujulSagor/movies_crud/internals/db/sqlite"
+	"github/MahfujulSagor/movies_crud/internals/http/negotiate"
+	"github/MahfujulSagor/movies_crud/internals/logger"
+	"github/MahfujulSagor/movies_crud/internals/utils/response"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func init() {
+	logger.Info = log.New(io.Discard, "", 0)
+	logger.Error = log.New(io.Discard, "", 0)
+}
+
+// testHandler counts runs and answers in the negotiated format, behind the guard as routes mount it
+func testHandler(t *testing.T) (http.HandlerFunc, *atomic.Int32) {
+	t.Helper()
+	store, err := sqlite.New(&config.Config{DBPath: filepath.Join(t.TempDir(), "movies.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	runs := &atomic.Int32{}
+	guard := &Guard{Store: store, TTL: time.Hour}
+	handler := guard.Protect(func(w http.ResponseWriter, r *http.Request) {
+		runs.Add(1)
+		response.Write(w, r, http.StatusCreated, response.Message{Success: response.StatusOK, Message: "created"})
+	})
+	return response.Negotiate(negotiate.Formats(false), handler), runs
+}
+
+func send(handler http.Handler, contentType string, accept string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/movies", strings.NewReader(`{"name": "Heat"}`))
+	r.Header.Set(Header, "key-1")
+	r.Header.Set("Content-Type", contentType)
+	r.Header.Set("Accept", accept)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, r)
+	return rec
+}
+
+func TestRetryWithSameFormatsIsReplayed(t *testing.T) {
+	handler, runs := testHandler(t)
+
+	send(handler, "application/json", "application/json")
+	rec := send(handler, "application/json; charset=utf-8", "application/json, */*;q=0.1")
+
+	if runs.Load() != 1 || rec.Header().Get(ReplayedHeader) != "true" || rec.Code != http.StatusCreated {
+		t.Fatalf("retry ran the handler %d times and answered %d, want one run and a replayed 201", runs.Load(), rec.Code)
+	}
+}
+
+func TestRetryWithOtherFormatsIsRejected(t *testing.T) {
+	for _, tc := range []struct {
+		name, contentType, accept string
+	}{
+		//? JSON is valid YAML, so the same bytes could be read either way
+		{"body format", "application/yaml", "application/json"},
+		{"response format", "application/json", "application/xml"},
+	} {
+		handler, runs := testHandler(t)
+		send(handler, "application/json", "application/json")
+		rec := send(handler, tc.contentType, tc.accept)
+
+		if runs.Load() != 1 || rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), apperr.CodeIdempotencyKeyReused) {
+			t.Errorf("%s: retry ran the handler %d times and answered %d %s, want 422 %s",
+				tc.name, runs.Load(), rec.Code, rec.Body, apperr.CodeIdempotencyKeyReused)
+		}
+	}
+}
+
+func TestPanicReleasesTheKey(t *testing.T) {
+	store, err := sqlite.New(&config.Config{DBPath: filepath.Join(t.TempDir(), "movies.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	runs := &atomic.Int32{}
+	guard := &Guard{Store: store, TTL: time.Hour}
+	handler := response.Negotiate(negotiate.Formats(false), guard.Protect(func(w http.ResponseWriter, r *http.Request) {
+		if runs.Add(1) == 1 {
+			panic("handler bug")
+		}
+		response.Write(w, r, http.StatusCreated, response.Message{Success: response.StatusOK, Message: "created"})
+	}))
+
+	func() {
+		defer func() {
+			if p := recover(); p != "handler bug" {
+				t.Fatalf("the guard swallowed or changed the panic: %v", p)
+			}
+		}()
+		send(handler, "application/json", "application/json")
+	}()
+
+	//? Without the release the retry would get 409 idempotency_in_progress until the key expired
+	rec := send(handler, "application/json", "application/json")
+	if runs.Load() != 2 || rec.Code != http.StatusCreated || rec.Header().Get(ReplayedHeader) != "" {
+		t.Fatalf("retry after a panic ran the handler %d times and answered %d %s, want a fresh 201", runs.Load(), rec.Code, rec.Body)
+	}
+}
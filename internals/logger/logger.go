@@ -13,17 +13,20 @@ var (
 	Error *log.Logger
 )
 
+// output is where enabled loggers write; SetLevel points Info back at it
+var output io.Writer
+
 func Init(cfg *config.Config) {
 	logFilePath := cfg.LoggingConfig.File
 	if logFilePath == "" {
 		logFilePath = "logs/app.log"
 	}
 
-	if err := os.MkdirAll("logs", 0755); err != nil {
+	if err := os.MkdirAll(filepath.Dir(logFilePath), 0755); err != nil {
 		log.Fatal("Failed to create logs directory:", err)
 	}
 
-	logFile, err := os.OpenFile(filepath.Join("logs", "app.log"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	logFile, err := os.OpenFile(logFilePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
 		log.Fatal("Failed to open log file:", err)
 	}
@@ -38,4 +41,17 @@ func Init(cfg *config.Config) {
 	flags := log.Ldate | log.Ltime | log.Lshortfile
 	Info = log.New(writer, "INFO: ", flags)
 	Error = log.New(writer, "ERROR: ", flags)
+	output = writer
+	SetLevel(cfg.LoggingConfig.Level)
+}
+
+// SetLevel silences Info at "warn" and "error", and enables it at "debug" and "info". It is safe
+// to call while other goroutines log.
+func SetLevel(level string) {
+	switch level {
+	case "warn", "error":
+		Info.SetOutput(io.Discard)
+	default:
+		Info.SetOutput(output)
+	}
 }
@@ -0,0 +1,36 @@
+package logger
+
+import (
+	"github/MahfujulSagor/movies_crud/internals/config"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInitWritesToTheConfiguredFile(t *testing.T) {
+	cfg := &config.Config{Env: "production"}
+	cfg.LoggingConfig = config.LoggingConfig{Level: "info", File: filepath.Join(t.TempDir(), "var", "log", "movies.log")}
+	Init(cfg)
+
+	Info.Println("started")
+	SetLevel("warn")
+	Info.Println("hidden")
+	Error.Println("failed")
+	SetLevel("debug")
+	Info.Println("back")
+
+	data, err := os.ReadFile(cfg.LoggingConfig.File)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := string(data)
+	for _, want := range []string{"INFO: ", "started", "ERROR: ", "failed", "back"} {
+		if !strings.Contains(log, want) {
+			t.Errorf("log is missing %q:\n%s", want, log)
+		}
+	}
+	if strings.Contains(log, "hidden") {
+		t.Errorf("Info logged at warn level:\n%s", log)
+	}
+}
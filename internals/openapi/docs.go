@@ -0,0 +1,45 @@
+package openapi
+
+import (
+	"embed"
+	"encoding/json"
+	"io/fs"
+	"net/http"
+)
+
+//go:embed swagger.html
+var swaggerPage []byte
+
+// swaggerUI holds the swagger-ui-dist 5.18.2 bundle and stylesheet, so /docs works offline
+//
+//go:embed swagger-ui
+var swaggerUI embed.FS
+
+// Spec serves the document as JSON; it is encoded once since routes are fixed at startup
+func Spec(doc *Document) http.HandlerFunc {
+	body, err := json.MarshalIndent(doc, "", "  ")
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err != nil {
+			http.Error(w, "failed to encode OpenAPI document", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+	}
+}
+
+// UI serves a Swagger UI page pointed at /openapi.json; its scripts and styles come from Assets
+func UI() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(swaggerPage)
+	}
+}
+
+// Assets serves the files UI's page loads, as /docs/{file}
+func Assets() http.HandlerFunc {
+	files, _ := fs.Sub(swaggerUI, "swagger-ui")
+	return http.StripPrefix("/docs/", http.FileServerFS(files)).ServeHTTP
+}
@@ -0,0 +1,42 @@
+package openapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestUIIsSelfContained(t *testing.T) {
+	rec := httptest.NewRecorder()
+	UI()(rec, httptest.NewRequest(http.MethodGet, "/docs", nil))
+	page := rec.Body.String()
+
+	if strings.Contains(page, "://") {
+		t.Fatalf("docs page loads from another origin:\n%s", page)
+	}
+	//? Inline scripts would need 'unsafe-inline' in the page CSP
+	if regexp.MustCompile(`<script>`).MatchString(page) {
+		t.Fatal("docs page has an inline script")
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /docs/{file}", Assets())
+	for _, src := range regexp.MustCompile(`(?:src|href)="([^"]+)"`).FindAllStringSubmatch(page, -1) {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, src[1], nil))
+		if rec.Code != http.StatusOK || rec.Body.Len() == 0 {
+			t.Errorf("%s: status %d", src[1], rec.Code)
+		}
+		if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/javascript") && !strings.HasPrefix(ct, "text/css") {
+			t.Errorf("%s: Content-Type %q", src[1], ct)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/docs/missing.js", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("missing asset returned %d", rec.Code)
+	}
+}
@@ -0,0 +1,86 @@
+package openapi
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Param documents a query parameter
+type Param struct {
+	Name        string
+	Description string
+	Type        string
+}
+
+// Route is a single API operation. Registering routes only through Router keeps the
+// served handlers and the generated specification in sync.
+type Route struct {
+	Method    string
+	Pattern   string
+	Summary   string
+	Tag       string
+	Scope     string
+	RateGroup string
+	Query     []Param
+	// Request and Response are zero values of the body types, e.g. types.Movie{} or []*types.Movie{}
+	Request  any
+	Response any
+	Status   int
+	// MediaType of the success response, application/json when empty
+	MediaType string
+	// Produces lists the media types a client may pick with Accept; empty means MediaType only
+	Produces []string
+	// Consumes lists the accepted request body media types, application/json when empty
+	Consumes []string
+	// MaxBody caps the decoded request body in bytes; zero leaves it unchecked
+	MaxBody int64
+	// QueryToken also accepts credentials as ?access_token= for clients that cannot set headers
+	QueryToken bool
+	// CacheControl applies to successful responses; empty means no-store
+	CacheControl string
+	// Conditional routes send Last-Modified and answer If-Modified-Since with 304
+	Conditional bool
+	// Idempotent routes honour the Idempotency-Key header
+	Idempotent bool
+	// Hidden routes are served but left out of the specification
+	Hidden  bool
+	Handler http.HandlerFunc
+}
+
+// pathParams returns the {name} wildcards in the route pattern
+func (rt Route) pathParams() []string {
+	var params []string
+	for _, seg := range strings.Split(rt.Pattern, "/") {
+		if name, ok := strings.CutPrefix(seg, "{"); ok {
+			params = append(params, strings.TrimSuffix(strings.TrimSuffix(name, "}"), "..."))
+		}
+	}
+	return params
+}
+
+// Middleware wraps a route's handler, typically with authentication and rate limiting
+type Middleware func(rt Route, next http.HandlerFunc) http.HandlerFunc
+
+type Router struct {
+	mux        *http.ServeMux
+	middleware Middleware
+	routes     []Route
+}
+
+func NewRouter(mux *http.ServeMux, middleware Middleware) *Router {
+	return &Router{mux: mux, middleware: middleware}
+}
+
+func (r *Router) Handle(rt Route) {
+	handler := rt.Handler
+	if r.middleware != nil {
+		handler = r.middleware(rt, handler)
+	}
+
+	r.mux.HandleFunc(rt.Method+" "+rt.Pattern, handler)
+	r.routes = append(r.routes, rt)
+}
+
+func (r *Router) Routes() []Route {
+	return r.routes
+}
@@ -0,0 +1,232 @@
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const Version = "3.1.0"
+
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+}
+
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type PathItem map[string]*Operation
+
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]Response   `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+	Scope       string                `json:"x-required-scope,omitempty"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Required    bool    `json:"required,omitempty"`
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes"`
+}
+
+type SecurityScheme struct {
+	Type        string `json:"type"`
+	Scheme      string `json:"scheme,omitempty"`
+	In          string `json:"in,omitempty"`
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// Generate builds the specification for every non-hidden route
+func Generate(info Info, routes []Route, problem any) *Document {
+	g := newGenerator()
+
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   map[string]*PathItem{},
+		Components: Components{
+			Schemas: g.schemas,
+			SecuritySchemes: map[string]SecurityScheme{
+				"bearerAuth": {Type: "http", Scheme: "bearer", Description: "API key, session token or SSO JWT"},
+				"apiKeyAuth": {Type: "apiKey", In: "header", Name: "X-API-Key"},
+			},
+		},
+	}
+
+	problemSchema := g.schemaFor(reflect.TypeOf(problem))
+	errorResponse := func(description string) Response {
+		return Response{
+			Description: description,
+			Content:     map[string]MediaType{"application/problem+json": {Schema: problemSchema}},
+		}
+	}
+
+	for _, rt := range routes {
+		if rt.Hidden {
+			continue
+		}
+
+		item, ok := doc.Paths[rt.Pattern]
+		if !ok {
+			item = &PathItem{}
+			doc.Paths[rt.Pattern] = item
+		}
+
+		op := &Operation{
+			OperationID: operationID(rt),
+			Summary:     rt.Summary,
+			Responses:   map[string]Response{},
+			Scope:       rt.Scope,
+		}
+		if rt.Tag != "" {
+			op.Tags = []string{rt.Tag}
+		}
+
+		for _, name := range rt.pathParams() {
+			op.Parameters = append(op.Parameters, Parameter{
+				Name: name, In: "path", Required: true,
+				Schema: &Schema{Type: "integer", Format: "int64"},
+			})
+		}
+		for _, q := range rt.Query {
+			op.Parameters = append(op.Parameters, Parameter{
+				Name: q.Name, In: "query", Description: q.Description,
+				Schema: &Schema{Type: q.Type},
+			})
+		}
+
+		if rt.QueryToken {
+			op.Parameters = append(op.Parameters, Parameter{
+				Name: "access_token", In: "query", Description: "Credential for clients that cannot set headers",
+				Schema: &Schema{Type: "string"},
+			})
+		}
+
+		if rt.Conditional {
+			op.Parameters = append(op.Parameters, Parameter{
+				Name: "If-Modified-Since", In: "header", Description: "Answered with 304 when nothing changed since this date",
+				Schema: &Schema{Type: "string"},
+			})
+			op.Responses["304"] = Response{Description: "Not modified since If-Modified-Since"}
+		}
+
+		if rt.Idempotent {
+			op.Parameters = append(op.Parameters, Parameter{
+				Name: "Idempotency-Key", In: "header", Description: "Retries with the same key and body replay the first response",
+				Schema: &Schema{Type: "string", MaxLength: ptr(255)},
+			})
+			op.Responses["409"] = errorResponse("A request with this key is still in progress")
+			op.Responses["422"] = errorResponse("The key was used with a different request")
+		}
+
+		if rt.Request != nil {
+			op.RequestBody = &RequestBody{
+				Required: true,
+				Content:  content(rt.Consumes, "application/json", g.schemaFor(reflect.TypeOf(rt.Request))),
+			}
+			op.Responses["400"] = errorResponse("Malformed or invalid request")
+			op.Responses["415"] = errorResponse("Unsupported Content-Type or Content-Encoding")
+			if rt.MaxBody > 0 {
+				op.Responses["413"] = errorResponse(fmt.Sprintf("Body larger than %d bytes", rt.MaxBody))
+			}
+		}
+
+		status := rt.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		success := Response{Description: http.StatusText(status)}
+		if rt.Response != nil {
+			mediaType := rt.MediaType
+			if mediaType == "" {
+				mediaType = "application/json"
+			}
+			success.Content = content(rt.Produces, mediaType, g.schemaFor(reflect.TypeOf(rt.Response)))
+		}
+		op.Responses[strconv.Itoa(status)] = success
+		if len(rt.Produces) > 0 {
+			op.Responses["406"] = errorResponse("None of the formats in Accept can be produced")
+		}
+
+		if len(rt.pathParams()) > 0 {
+			op.Responses["404"] = errorResponse("Not found")
+		}
+		if rt.Scope != "" {
+			op.Security = []map[string][]string{{"bearerAuth": {}}, {"apiKeyAuth": {}}}
+			op.Responses["401"] = errorResponse("Missing or invalid credentials")
+			op.Responses["403"] = errorResponse("Missing scope " + rt.Scope)
+		}
+		//? Authenticated routes are also limited per client IP before credentials are checked
+		if rt.RateGroup != "" || rt.Scope != "" {
+			op.Responses["429"] = errorResponse("Rate limit exceeded")
+		}
+		op.Responses["default"] = errorResponse("Error")
+
+		(*item)[strings.ToLower(rt.Method)] = op
+	}
+
+	return doc
+}
+
+// content describes one schema under each media type, or under fallback when there are none
+func content(mediaTypes []string, fallback string, schema *Schema) map[string]MediaType {
+	if len(mediaTypes) == 0 {
+		mediaTypes = []string{fallback}
+	}
+	c := map[string]MediaType{}
+	for _, mediaType := range mediaTypes {
+		c[mediaType] = MediaType{Schema: schema}
+	}
+	return c
+}
+
+// operationID derives a stable identifier such as "getApiV1MoviesById"
+func operationID(rt Route) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(rt.Method))
+
+	segments := strings.FieldsFunc(rt.Pattern, func(r rune) bool { return r == '/' || r == '-' })
+	for _, seg := range segments {
+		if name, ok := strings.CutPrefix(seg, "{"); ok {
+			b.WriteString("By")
+			seg = strings.TrimSuffix(name, "}")
+		}
+		b.WriteString(strings.ToUpper(seg[:1]) + seg[1:])
+	}
+
+	return b.String()
+}
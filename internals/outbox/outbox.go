@@ -0,0 +1,139 @@
+// Package outbox relays events committed to the outbox table to their sinks, at least once
+// and in commit order per aggregate.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github/MahfujulSagor/movies_crud/internals/config"
+	"github/MahfujulSagor/movies_crud/internals/db"
+	"github/MahfujulSagor/movies_crud/internals/events"
+	"github/MahfujulSagor/movies_crud/internals/logger"
+	"time"
+)
+
+// Sink receives relayed events. Send must be safe to repeat: a message is retried on every
+// sink whenever any of them fails.
+type Sink interface {
+	Name() string
+	Send(ctx context.Context, e events.Event) error
+}
+
+// Relay polls the outbox and forwards each message to every sink before marking it relayed
+type Relay struct {
+	Store        db.OutboxStore
+	Sinks        []Sink
+	PollInterval time.Duration
+	BatchSize    int
+	MaxBackoff   time.Duration
+	Retention    time.Duration
+}
+
+func New(cfg *config.Config, store db.OutboxStore, sinks []Sink) *Relay {
+	return &Relay{
+		Store:        store,
+		Sinks:        sinks,
+		PollInterval: cfg.OutboxConfig.PollInterval,
+		BatchSize:    cfg.OutboxConfig.BatchSize,
+		MaxBackoff:   cfg.OutboxConfig.MaxBackoff,
+		Retention:    cfg.OutboxConfig.Retention,
+	}
+}
+
+// Run relays until ctx is done; anything left over is picked up on the next start
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.PollInterval)
+	defer ticker.Stop()
+	purge := time.NewTicker(time.Hour)
+	defer purge.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.relay(ctx)
+		case <-purge.C:
+			r.purge(ctx)
+		}
+	}
+}
+
+type aggregate struct {
+	kind string
+	id   int64
+}
+
+func (r *Relay) relay(ctx context.Context) {
+	messages, err := r.Store.PendingOutbox(ctx, time.Now().UTC(), r.BatchSize)
+	if err != nil {
+		logger.Error.Println("Error loading outbox:", err)
+		return
+	}
+
+	//? Once a message fails, later messages for the same aggregate wait behind it
+	blocked := map[aggregate]bool{}
+
+	for _, m := range messages {
+		key := aggregate{m.AggregateType, m.AggregateID}
+		if blocked[key] {
+			continue
+		}
+
+		if err := r.deliver(ctx, m); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			blocked[key] = true
+
+			next := time.Now().UTC().Add(r.backoff(m.Attempts + 1))
+			logger.Error.Println("Error relaying outbox message", m.ID, "attempt", m.Attempts+1, err)
+			if err := r.Store.MarkOutboxFailed(ctx, m.ID, err.Error(), next); err != nil {
+				logger.Error.Println("Error recording outbox failure", m.ID, err)
+			}
+			continue
+		}
+
+		if err := r.Store.MarkRelayed(ctx, m.ID, time.Now().UTC()); err != nil {
+			//? The message will be sent again; sinks tolerate duplicates
+			logger.Error.Println("Error marking outbox message", m.ID, "relayed:", err)
+			blocked[key] = true
+		}
+	}
+}
+
+func (r *Relay) deliver(ctx context.Context, m *db.OutboxMessage) error {
+	var e events.Event
+	if err := json.Unmarshal([]byte(m.Payload), &e); err != nil {
+		return fmt.Errorf("decoding payload: %w", err)
+	}
+	e.ID = uint64(m.ID)
+
+	for _, sink := range r.Sinks {
+		if err := sink.Send(ctx, e); err != nil {
+			return fmt.Errorf("%s sink: %w", sink.Name(), err)
+		}
+	}
+	return nil
+}
+
+func (r *Relay) purge(ctx context.Context) {
+	removed, err := r.Store.PurgeOutbox(ctx, time.Now().Add(-r.Retention))
+	if err != nil {
+		logger.Error.Println("Error purging outbox:", err)
+		return
+	}
+	if removed > 0 {
+		logger.Info.Println("Purged", removed, "relayed outbox messages")
+	}
+}
+
+// backoff doubles per attempt from one second up to MaxBackoff
+func (r *Relay) backoff(attempts int) time.Duration {
+	delay := time.Second << (attempts - 1)
+	if delay <= 0 || delay > r.MaxBackoff {
+		delay = r.MaxBackoff
+	}
+	return delay
+}
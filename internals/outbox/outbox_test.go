@@ -0,0 +1,242 @@
+package outbox
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"github/MahfujulSagor/movies_crud/internals/config"
+	"github/MahfujulSagor/movies_crud/internals/db/sqlite"
+	"github/MahfujulSagor/movies_crud/internals/events"
+	"github/MahfujulSagor/movies_crud/internals/logger"
+	"github/MahfujulSagor/movies_crud/internals/types"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func init() {
+	logger.Info = log.New(io.Discard, "", 0)
+	logger.Error = log.New(io.Discard, "", 0)
+}
+
+// recordingSink keeps what it is sent and fails while err is set
+type recordingSink struct {
+	sent []events.Event
+	err  error
+}
+
+func (*recordingSink) Name() string { return "recording" }
+
+func (s *recordingSink) Send(_ context.Context, e events.Event) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.sent = append(s.sent, e)
+	return nil
+}
+
+func newStore(t *testing.T) *sqlite.SQLite {
+	t.Helper()
+	store, err := sqlite.New(&config.Config{DBPath: filepath.Join(t.TempDir(), "movies.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func testMovie(title string) *types.Movie {
+	return &types.Movie{
+		Title:    title,
+		Rating:   7,
+		Director: &types.Director{Name: "Director of " + title, Age: 50},
+		Cast:     &types.Cast{Actor: "Actor", Actress: "Actress"},
+	}
+}
+
+// newRelay relays store's outbox to sinks, retrying failures after 50ms
+func newRelay(store *sqlite.SQLite, sinks ...Sink) *Relay {
+	return &Relay{Store: store, Sinks: sinks, PollInterval: time.Millisecond, BatchSize: 10, MaxBackoff: 50 * time.Millisecond, Retention: time.Hour}
+}
+
+func pending(t *testing.T, store *sqlite.SQLite) int {
+	t.Helper()
+	messages, err := store.PendingOutbox(context.Background(), time.Now().UTC().Add(time.Second), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(messages)
+}
+
+func TestRelayForwardsWithOutboxIDs(t *testing.T) {
+	ctx := context.Background()
+	store := newStore(t)
+	id, err := store.CreateMovie(ctx, testMovie("Heat"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.DeleteMovieByID(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+
+	sink := &recordingSink{}
+	newRelay(store, sink).relay(ctx)
+
+	if len(sink.sent) != 2 || sink.sent[0].Type != events.MovieCreated || sink.sent[1].Type != events.MovieDeleted {
+		t.Fatalf("sent %+v, want created then deleted", sink.sent)
+	}
+	if sink.sent[0].ID == 0 || sink.sent[1].ID <= sink.sent[0].ID || sink.sent[0].MovieID != id {
+		t.Fatalf("events %+v do not carry increasing outbox IDs for movie %d", sink.sent, id)
+	}
+	if n := pending(t, store); n != 0 {
+		t.Fatalf("%d messages still pending after relaying", n)
+	}
+}
+
+func TestRelayKeepsOrderPerMovieOnFailure(t *testing.T) {
+	ctx := context.Background()
+	store := newStore(t)
+	first, err := store.CreateMovie(ctx, testMovie("First"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.UpdateMovie(ctx, first, testMovie("First again")); err != nil {
+		t.Fatal(err)
+	}
+
+	sink := &recordingSink{err: errors.New("sink down")}
+	relay := newRelay(store, sink)
+	relay.relay(ctx)
+
+	//? Another movie goes ahead while the first one's events wait
+	sink.err = nil
+	second, err := store.CreateMovie(ctx, testMovie("Second"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	relay.relay(ctx)
+	if len(sink.sent) != 1 || sink.sent[0].MovieID != second {
+		t.Fatalf("sent %+v during the backoff, want only movie %d", sink.sent, second)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	relay.relay(ctx)
+	if len(sink.sent) != 3 || sink.sent[1].Type != events.MovieCreated || sink.sent[2].Type != events.MovieUpdated {
+		t.Fatalf("after the backoff sent %+v, want movie %d created then updated", sink.sent[1:], first)
+	}
+	if n := pending(t, store); n != 0 {
+		t.Fatalf("%d messages still pending", n)
+	}
+}
+
+func TestRelayRetriesEverySink(t *testing.T) {
+	ctx := context.Background()
+	store := newStore(t)
+	if _, err := store.CreateMovie(ctx, testMovie("Heat")); err != nil {
+		t.Fatal(err)
+	}
+
+	bus := events.NewBus(10)
+	sub := bus.Subscribe(events.Filter{}, 0)
+	defer sub.Close()
+	first, second := &recordingSink{}, &recordingSink{err: errors.New("sink down")}
+	relay := newRelay(store, BusSink{Bus: bus}, first, second)
+
+	relay.relay(ctx)
+	second.err = nil
+	time.Sleep(60 * time.Millisecond)
+	relay.relay(ctx)
+
+	//? At least once: sinks before the failing one see the event again, except the bus, which drops repeated IDs
+	if len(first.sent) != 2 || first.sent[0].ID != first.sent[1].ID || len(second.sent) != 1 {
+		t.Fatalf("first sink got %d events and second %d, want 2 and 1", len(first.sent), len(second.sent))
+	}
+	if len(sub.Events()) != 1 {
+		t.Fatalf("bus delivered %d events, want 1", len(sub.Events()))
+	}
+}
+
+func TestPurgeKeepsRecentMessages(t *testing.T) {
+	ctx := context.Background()
+	store := newStore(t)
+	if _, err := store.CreateMovie(ctx, testMovie("Heat")); err != nil {
+		t.Fatal(err)
+	}
+	relay := newRelay(store, &recordingSink{})
+	relay.relay(ctx)
+
+	relay.purge(ctx)
+	var count int
+	store.DB.QueryRow("SELECT COUNT(*) FROM outbox").Scan(&count)
+	if count != 1 {
+		t.Fatalf("purge within the retention left %d messages, want 1", count)
+	}
+
+	relay.Retention = -time.Minute
+	relay.purge(ctx)
+	store.DB.QueryRow("SELECT COUNT(*) FROM outbox").Scan(&count)
+	if count != 0 {
+		t.Fatalf("purge past the retention left %d messages", count)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	r := &Relay{MaxBackoff: 10 * time.Second}
+	for attempts, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 4: 8 * time.Second, 5: 10 * time.Second, 80: 10 * time.Second} {
+		if got := r.backoff(attempts); got != want {
+			t.Errorf("backoff(%d) = %s, want %s", attempts, got, want)
+		}
+	}
+}
+
+func TestNewSinks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	cfg := &config.Config{}
+	cfg.OutboxConfig.Sinks = []string{"bus", "log", "file"}
+	cfg.OutboxConfig.File = path
+
+	sinks, err := NewSinks(cfg, events.NewBus(10), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sinks) != 3 || sinks[0].Name() != "bus" || sinks[1].Name() != "log" || sinks[2].Name() != "file" {
+		t.Fatalf("sinks %v are not in config order", sinks)
+	}
+
+	file := sinks[2].(*FileSink)
+	for _, id := range []uint64{1, 2} {
+		if err := file.Send(context.Background(), events.Event{ID: id, Type: events.MovieCreated}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	file.Close()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var ids []uint64
+	for scanner := bufio.NewScanner(f); scanner.Scan(); {
+		var e events.Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, e.ID)
+	}
+	if len(ids) != 2 || ids[0] != 1 || ids[1] != 2 {
+		t.Fatalf("file holds events %v, want 1 and 2 as JSON lines", ids)
+	}
+
+	for _, sinks := range [][]string{{"kafka"}, {"file"}} {
+		cfg := &config.Config{}
+		cfg.OutboxConfig.Sinks = sinks
+		if _, err := NewSinks(cfg, events.NewBus(10), nil); err == nil {
+			t.Errorf("NewSinks(%v) accepted an unusable config", sinks)
+		}
+	}
+}
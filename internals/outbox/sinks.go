@@ -0,0 +1,96 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github/MahfujulSagor/movies_crud/internals/config"
+	"github/MahfujulSagor/movies_crud/internals/events"
+	"github/MahfujulSagor/movies_crud/internals/logger"
+	"os"
+	"sync"
+)
+
+// NewSinks builds the sinks named in the config, in order. hooks is the webhook dispatcher.
+func NewSinks(cfg *config.Config, bus *events.Bus, hooks Sink) ([]Sink, error) {
+	var sinks []Sink
+	for _, name := range cfg.OutboxConfig.Sinks {
+		switch name {
+		case "bus":
+			sinks = append(sinks, BusSink{Bus: bus})
+		case "webhook":
+			sinks = append(sinks, hooks)
+		case "log":
+			sinks = append(sinks, LogSink{})
+		case "file":
+			if cfg.OutboxConfig.File == "" {
+				return nil, fmt.Errorf("outbox file sink needs outbox.file")
+			}
+			file, err := NewFileSink(cfg.OutboxConfig.File)
+			if err != nil {
+				return nil, err
+			}
+			sinks = append(sinks, file)
+		default:
+			return nil, fmt.Errorf("unknown outbox sink %q", name)
+		}
+	}
+	return sinks, nil
+}
+
+// BusSink feeds the in-process bus behind the SSE and WebSocket change feed. Events keep their
+// outbox IDs, and the bus drops an ID it has already published, so a retried relay is not delivered twice.
+type BusSink struct {
+	Bus *events.Bus
+}
+
+func (BusSink) Name() string { return "bus" }
+
+func (s BusSink) Send(_ context.Context, e events.Event) error {
+	s.Bus.Publish(e)
+	return nil
+}
+
+// LogSink writes a line per event to the application log
+type LogSink struct{}
+
+func (LogSink) Name() string { return "log" }
+
+func (LogSink) Send(_ context.Context, e events.Event) error {
+	logger.Info.Println("Event", e.ID, e.Type, "movie", e.MovieID, "by", e.Actor)
+	return nil
+}
+
+// FileSink appends each event as a JSON line and syncs before reporting success
+type FileSink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+func NewFileSink(path string) (*FileSink, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &FileSink{file: file}, nil
+}
+
+func (*FileSink) Name() string { return "file" }
+
+func (s *FileSink) Send(_ context.Context, e events.Event) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return s.file.Sync()
+}
+
+func (s *FileSink) Close() error {
+	return s.file.Close()
+}
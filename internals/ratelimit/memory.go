@@ -0,0 +1,49 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// sweepEvery controls how often idle buckets are dropped from MemoryStore
+const sweepEvery = time.Minute
+
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets:   map[string]*bucket{},
+		lastSweep: time.Now(),
+	}
+}
+
+func (m *MemoryStore) Take(key string, rule Rule, now time.Time) (Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if now.Sub(m.lastSweep) > sweepEvery {
+		m.sweep(now)
+	}
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(rule.burst()), last: now}
+		m.buckets[key] = b
+	}
+
+	return b.take(rule, now), nil
+}
+
+// sweep removes buckets that have refilled, since they are indistinguishable from new ones
+func (m *MemoryStore) sweep(now time.Time) {
+	for key, b := range m.buckets {
+		if now.After(b.full) {
+			delete(m.buckets, key)
+		}
+	}
+	m.lastSweep = now
+}
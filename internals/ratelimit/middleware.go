@@ -0,0 +1,137 @@
+package ratelimit
+
+import (
+	"fmt"
+	"github/MahfujulSagor/movies_crud/internals/apperr"
+	"github/MahfujulSagor/movies_crud/internals/auth"
+	"github/MahfujulSagor/movies_crud/internals/config"
+	"github/MahfujulSagor/movies_crud/internals/http/clientip"
+	"github/MahfujulSagor/movies_crud/internals/logger"
+	"github/MahfujulSagor/movies_crud/internals/utils/response"
+	"math"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	GroupRead  string = "read"
+	GroupWrite string = "write"
+	GroupAuth  string = "auth"
+	GroupAdmin string = "admin"
+	// GroupIP limits each client IP before authentication, across all authenticated routes
+	GroupIP string = "ip"
+)
+
+// DefaultRules apply to groups missing from the configuration
+var DefaultRules = map[string]Rule{
+	GroupRead:  {Requests: 300, Per: time.Minute, Burst: 50},
+	GroupWrite: {Requests: 60, Per: time.Minute, Burst: 20},
+	GroupAuth:  {Requests: 10, Per: time.Minute, Burst: 5},
+	GroupAdmin: {Requests: 60, Per: time.Minute, Burst: 20},
+	GroupIP:    {Requests: 600, Per: time.Minute, Burst: 100},
+}
+
+type Limiter struct {
+	Store    Store
+	ClientIP *clientip.Resolver
+	// settings holds the rules in force, swapped by Reload
+	settings atomic.Pointer[settings]
+}
+
+type settings struct {
+	enabled bool
+	rules   map[string]Rule
+	// policies holds each group's RateLimit-Policy header
+	policies map[string]string
+}
+
+func New(cfg *config.Config, store Store, resolver *clientip.Resolver) *Limiter {
+	l := &Limiter{Store: store, ClientIP: resolver}
+	l.Reload(cfg)
+	return l
+}
+
+// Reload swaps in the enabled flag and rules for the next request. Existing buckets are kept
+// and refill at the new rate.
+func (l *Limiter) Reload(cfg *config.Config) {
+	s := &settings{enabled: cfg.RateLimitConfig.Enabled, rules: map[string]Rule{}, policies: map[string]string{}}
+	for group, rule := range DefaultRules {
+		s.rules[group] = rule
+	}
+	for group, rule := range cfg.RateLimitConfig.Groups {
+		s.rules[group] = Rule{Requests: rule.Requests, Per: rule.Per, Burst: rule.Burst}
+	}
+
+	for group, rule := range s.rules {
+		if rule.Requests <= 0 || rule.Per <= 0 {
+			logger.Error.Println("Rate limit group", group, "has no valid rule; requests are not limited")
+			delete(s.rules, group)
+			continue
+		}
+		s.policies[group] = fmt.Sprintf("%d;w=%d;burst=%d", rule.Requests, int(rule.Per.Seconds()), rule.burst())
+	}
+
+	l.settings.Store(s)
+}
+
+// Limit applies the rule for group to next, keyed by the authenticated caller or else the client IP.
+// Wrap it inside auth middleware so the caller identity is available.
+func (l *Limiter) Limit(group string, next http.HandlerFunc) http.HandlerFunc {
+	return l.limit(group, l.clientKey, next)
+}
+
+// LimitIP applies the rule for group keyed by client IP alone. Wrap it outside auth middleware, so
+// requests with missing or wrong credentials, which never reach Limit, are limited too.
+func (l *Limiter) LimitIP(group string, next http.HandlerFunc) http.HandlerFunc {
+	return l.limit(group, func(r *http.Request) string { return "ip:" + l.ClientIP.ClientIP(r) }, next)
+}
+
+func (l *Limiter) limit(group string, clientKey func(*http.Request) string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		//? Read the settings once, so a reload mid-request cannot mix old and new rules
+		s := l.settings.Load()
+		rule, ok := s.rules[group]
+		if !s.enabled || !ok {
+			next(w, r)
+			return
+		}
+
+		key := group + "|" + clientKey(r)
+
+		res, err := l.Store.Take(key, rule, time.Now())
+		if err != nil {
+			//? Fail open: a broken shared store should not take the API down
+			logger.Error.Println("Rate limit store error:", err)
+			next(w, r)
+			return
+		}
+
+		h := w.Header()
+		h.Set("RateLimit-Policy", s.policies[group])
+		h.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+		h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		h.Set("RateLimit-Reset", ceilSeconds(res.Reset))
+
+		if !res.Allowed {
+			h.Set("Retry-After", ceilSeconds(res.RetryAfter))
+			response.WriteProblem(w, r, apperr.New(apperr.KindRateLimited, apperr.CodeRateLimited, "rate limit exceeded, retry in %s seconds", ceilSeconds(res.RetryAfter)))
+			logger.Error.Println("Rate limit exceeded for", key)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+func (l *Limiter) clientKey(r *http.Request) string {
+	if id, ok := auth.FromContext(r.Context()); ok {
+		return id.Subject
+	}
+	return "ip:" + l.ClientIP.ClientIP(r)
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,76 @@
+package ratelimit
+
+import (
+	"math"
+	"time"
+)
+
+// Rule is a token bucket: Requests per Per refill rate, holding at most Burst tokens
+type Rule struct {
+	Requests int
+	Per      time.Duration
+	Burst    int
+}
+
+func (r Rule) rate() float64 {
+	return float64(r.Requests) / r.Per.Seconds()
+}
+
+func (r Rule) burst() int {
+	if r.Burst > 0 {
+		return r.Burst
+	}
+	return r.Requests
+}
+
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration
+	// Reset is how long until the bucket is full again
+	Reset time.Duration
+}
+
+// Store holds bucket state. The in-process MemoryStore suits a single instance;
+// a shared implementation (e.g. Redis) lets several instances enforce one quota.
+type Store interface {
+	Take(key string, rule Rule, now time.Time) (Result, error)
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	// full is when the bucket will have refilled completely
+	full time.Time
+}
+
+// take refills b for the time elapsed since the last call and tries to consume one token
+func (b *bucket) take(rule Rule, now time.Time) Result {
+	rate := rule.rate()
+	burst := float64(rule.burst())
+
+	elapsed := now.Sub(b.last).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(burst, b.tokens+elapsed*rate)
+		b.last = now
+	}
+
+	res := Result{Limit: rule.burst()}
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = seconds((1 - b.tokens) / rate)
+	}
+
+	res.Remaining = int(math.Floor(b.tokens))
+	res.Reset = seconds((burst - b.tokens) / rate)
+	b.full = now.Add(res.Reset)
+
+	return res
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
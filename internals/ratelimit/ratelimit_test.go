@@ -0,0 +1,187 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"github/MahfujulSagor/movies_crud/internals/apperr"
+	"github/MahfujulSagor/movies_crud/internals/auth"
+	"github/MahfujulSagor/movies_crud/internals/config"
+	"github/MahfujulSagor/movies_crud/internals/http/clientip"
+	"github/MahfujulSagor/movies_crud/internals/logger"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func init() {
+	logger.Info = log.New(io.Discard, "", 0)
+	logger.Error = log.New(io.Discard, "", 0)
+}
+
+func TestBucketRefill(t *testing.T) {
+	//? One token a second, up to three
+	rule := Rule{Requests: 60, Per: time.Minute, Burst: 3}
+	start := time.Now()
+	b := &bucket{tokens: 3, last: start}
+
+	for _, step := range []struct {
+		after     time.Duration
+		allowed   bool
+		remaining int
+		retry     time.Duration
+		reset     time.Duration
+	}{
+		{0, true, 2, 0, time.Second},
+		{0, true, 1, 0, 2 * time.Second},
+		{0, true, 0, 0, 3 * time.Second},
+		{0, false, 0, time.Second, 3 * time.Second},
+		{500 * time.Millisecond, false, 0, 500 * time.Millisecond, 2500 * time.Millisecond},
+		{time.Second, true, 0, 0, 2500 * time.Millisecond},
+		//? Long idle periods refill to the burst, never beyond it
+		{time.Hour, true, 2, 0, time.Second},
+	} {
+		start = start.Add(step.after)
+		res := b.take(rule, start)
+		if res.Allowed != step.allowed || res.Remaining != step.remaining || res.Limit != 3 ||
+			!near(res.RetryAfter, step.retry) || !near(res.Reset, step.reset) {
+			t.Fatalf("after %s: got %+v, want allowed %v, remaining %d, retry %s, reset %s",
+				step.after, res, step.allowed, step.remaining, step.retry, step.reset)
+		}
+	}
+}
+
+func near(a, b time.Duration) bool {
+	return (a - b).Abs() < time.Millisecond
+}
+
+func TestRuleBurstDefaultsToRequests(t *testing.T) {
+	if got := (Rule{Requests: 10, Per: time.Minute}).burst(); got != 10 {
+		t.Fatalf("burst = %d, want 10", got)
+	}
+}
+
+func TestMemoryStoreSweepsFullBuckets(t *testing.T) {
+	rule := Rule{Requests: 60, Per: time.Minute, Burst: 1}
+	m := NewMemoryStore()
+	now := m.lastSweep
+
+	m.Take("a", rule, now)
+	m.Take("b", rule, now.Add(sweepEvery-500*time.Millisecond))
+	//? This one triggers the sweep: a refilled long ago, b is still half a second short
+	m.Take("c", rule, now.Add(sweepEvery+time.Millisecond))
+
+	if _, ok := m.buckets["a"]; ok {
+		t.Fatal("refilled bucket was not swept")
+	}
+	if _, ok := m.buckets["b"]; !ok {
+		t.Fatal("bucket was swept while refilling")
+	}
+}
+
+func newLimiter(t *testing.T, rule config.RateLimitRule) *Limiter {
+	t.Helper()
+	resolver, err := clientip.New([]string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{}
+	cfg.RateLimitConfig = config.RateLimitConfig{Enabled: true, Groups: config.Map[config.RateLimitRule]{GroupWrite: rule}}
+	return New(cfg, NewMemoryStore(), resolver)
+}
+
+// request sends a request from remote, forwarded for xff when set, as subject when set
+func request(handler http.HandlerFunc, remote, xff, subject string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/movies", nil)
+	r.RemoteAddr = remote
+	if xff != "" {
+		r.Header.Set("X-Forwarded-For", xff)
+	}
+	if subject != "" {
+		r = r.WithContext(auth.WithIdentity(r.Context(), &auth.Identity{Subject: subject}))
+	}
+	rec := httptest.NewRecorder()
+	handler(rec, r)
+	return rec
+}
+
+func ok(w http.ResponseWriter, r *http.Request) {}
+
+func TestLimitHeaders(t *testing.T) {
+	l := newLimiter(t, config.RateLimitRule{Requests: 60, Per: time.Minute, Burst: 2})
+	handler := l.Limit(GroupWrite, ok)
+
+	rec := request(handler, "203.0.113.7:1", "", "")
+	want := map[string]string{
+		"RateLimit-Policy":    "60;w=60;burst=2",
+		"RateLimit-Limit":     "2",
+		"RateLimit-Remaining": "1",
+		"RateLimit-Reset":     "1",
+		"Retry-After":         "",
+	}
+	for name, value := range want {
+		if got := rec.Header().Get(name); got != value {
+			t.Errorf("%s = %q, want %q", name, got, value)
+		}
+	}
+
+	request(handler, "203.0.113.7:1", "", "")
+	rec = request(handler, "203.0.113.7:1", "", "")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "1" || rec.Header().Get("RateLimit-Remaining") != "0" {
+		t.Fatalf("third request got %d with Retry-After %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	var problem struct {
+		Code string `json:"code"`
+	}
+	if json.Unmarshal(rec.Body.Bytes(), &problem); problem.Code != apperr.CodeRateLimited {
+		t.Fatalf("429 body %s", rec.Body)
+	}
+}
+
+func TestLimitKeys(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		limit  func(*Limiter) http.HandlerFunc
+		first  [3]string
+		second [3]string
+		shared bool
+	}{
+		{"callers share nothing on one IP", limitByCaller,
+			[3]string{"203.0.113.7:1", "", "apikey:1"}, [3]string{"203.0.113.7:1", "", "apikey:2"}, false},
+		{"a caller is limited across IPs", limitByCaller,
+			[3]string{"203.0.113.7:1", "", "apikey:1"}, [3]string{"198.51.100.1:1", "", "apikey:1"}, true},
+		{"anonymous requests are keyed by IP", limitByCaller,
+			[3]string{"203.0.113.7:1", "", ""}, [3]string{"198.51.100.1:1", "", ""}, false},
+		{"LimitIP ignores the caller", limitByIP,
+			[3]string{"203.0.113.7:1", "", "apikey:1"}, [3]string{"203.0.113.7:1", "", "apikey:2"}, true},
+		{"clients behind a trusted proxy are told apart", limitByIP,
+			[3]string{"10.0.0.2:1", "203.0.113.7", ""}, [3]string{"10.0.0.2:1", "198.51.100.1", ""}, false},
+		{"spoofed XFF from an untrusted peer is ignored", limitByIP,
+			[3]string{"203.0.113.7:1", "198.51.100.1", ""}, [3]string{"203.0.113.7:1", "198.51.100.2", ""}, true},
+	} {
+		l := newLimiter(t, config.RateLimitRule{Requests: 1, Per: time.Hour, Burst: 1})
+		handler := tc.limit(l)
+
+		request(handler, tc.first[0], tc.first[1], tc.first[2])
+		rec := request(handler, tc.second[0], tc.second[1], tc.second[2])
+		if limited := rec.Code == http.StatusTooManyRequests; limited != tc.shared {
+			t.Errorf("%s: second request limited = %v, want %v", tc.name, limited, tc.shared)
+		}
+	}
+}
+
+func limitByCaller(l *Limiter) http.HandlerFunc { return l.Limit(GroupWrite, ok) }
+func limitByIP(l *Limiter) http.HandlerFunc     { return l.LimitIP(GroupWrite, ok) }
+
+func TestReloadDisablesLimits(t *testing.T) {
+	l := newLimiter(t, config.RateLimitRule{Requests: 1, Per: time.Hour, Burst: 1})
+	handler := l.Limit(GroupWrite, ok)
+	request(handler, "203.0.113.7:1", "", "")
+
+	l.Reload(&config.Config{})
+	rec := request(handler, "203.0.113.7:1", "", "")
+	if rec.Code != http.StatusOK || rec.Header().Get("RateLimit-Limit") != "" {
+		t.Fatalf("disabled limiter answered %d with RateLimit-Limit %q", rec.Code, rec.Header().Get("RateLimit-Limit"))
+	}
+}
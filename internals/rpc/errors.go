@@ -0,0 +1,60 @@
+package rpc
+
+import (
+	"github/MahfujulSagor/movies_crud/internals/apperr"
+	"github/MahfujulSagor/movies_crud/internals/logger"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+)
+
+var grpcCodes = map[apperr.Kind]codes.Code{
+	apperr.KindBadRequest:   codes.InvalidArgument,
+	apperr.KindValidation:   codes.InvalidArgument,
+	apperr.KindUnauthorized: codes.Unauthenticated,
+	apperr.KindForbidden:    codes.PermissionDenied,
+	apperr.KindNotFound:     codes.NotFound,
+	apperr.KindConflict:     codes.AlreadyExists,
+	apperr.KindRateLimited:  codes.ResourceExhausted,
+	apperr.KindUnavailable:  codes.Unavailable,
+	apperr.KindInternal:     codes.Internal,
+}
+
+// toStatus maps an error to a gRPC status; the stable API code travels as ErrorInfo.reason
+// and validation failures as BadRequest field violations
+func toStatus(err error) error {
+	appErr := apperr.From(err)
+
+	code, ok := grpcCodes[appErr.Kind]
+	if !ok {
+		code = codes.Internal
+	}
+	//? Constraint violations are not duplicates; the request conflicts with existing state
+	if appErr.Code == apperr.CodeConstraint {
+		code = codes.FailedPrecondition
+	}
+	if code == codes.Internal {
+		logger.Error.Println("gRPC internal error:", err)
+	} else if appErr.Kind == apperr.KindConflict {
+		//? The client only sees a fixed message, so the storage error is logged
+		logger.Error.Println("gRPC conflict:", err)
+	}
+
+	st := status.New(code, appErr.Detail)
+
+	details := []protoadapt.MessageV1{&errdetails.ErrorInfo{Reason: appErr.Code, Domain: "movies"}}
+	if len(appErr.Fields) > 0 {
+		violations := make([]*errdetails.BadRequest_FieldViolation, len(appErr.Fields))
+		for i, f := range appErr.Fields {
+			violations[i] = &errdetails.BadRequest_FieldViolation{Field: f.Field, Description: f.Message}
+		}
+		details = append(details, &errdetails.BadRequest{FieldViolations: violations})
+	}
+
+	if withDetails, err := st.WithDetails(details...); err == nil {
+		st = withDetails
+	}
+	return st.Err()
+}
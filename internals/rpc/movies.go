@@ -0,0 +1,169 @@
+package rpc
+
+import (
+	"context"
+	"github/MahfujulSagor/movies_crud/internals/apperr"
+	"github/MahfujulSagor/movies_crud/internals/db"
+	"github/MahfujulSagor/movies_crud/internals/rpc/moviespb"
+	"github/MahfujulSagor/movies_crud/internals/types"
+	"github/MahfujulSagor/movies_crud/internals/validation"
+
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	defaultLimit = 10
+	maxLimit     = 50
+)
+
+type movieService struct {
+	moviespb.UnimplementedMovieServiceServer
+	store db.DB
+}
+
+func (s *movieService) CreateMovie(ctx context.Context, req *moviespb.CreateMovieRequest) (*moviespb.Movie, error) {
+	movie, err := validMovie(ctx, req.GetMovie())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	id, err := s.store.CreateMovie(ctx, movie)
+	if err != nil {
+		return nil, toStatus(apperr.FromDB(err, apperr.NotFound(apperr.CodeNotFound, "referenced record not found")))
+	}
+
+	return s.get(ctx, id)
+}
+
+func (s *movieService) GetMovie(ctx context.Context, req *moviespb.GetMovieRequest) (*moviespb.Movie, error) {
+	return s.get(ctx, req.GetId())
+}
+
+func (s *movieService) ListMovies(ctx context.Context, req *moviespb.ListMoviesRequest) (*moviespb.ListMoviesResponse, error) {
+	limit, offset := int(req.GetLimit()), int(req.GetOffset())
+	if limit == 0 {
+		limit = defaultLimit
+	}
+	if limit < 0 || offset < 0 {
+		return nil, toStatus(apperr.BadRequest(apperr.CodeInvalidQuery, "limit and offset must not be negative"))
+	}
+
+	movies, err := s.store.GetMovieList(ctx, min(limit, maxLimit), offset)
+	if err != nil {
+		return nil, toStatus(apperr.Internal(err))
+	}
+
+	resp := &moviespb.ListMoviesResponse{Movies: make([]*moviespb.Movie, len(movies))}
+	for i, m := range movies {
+		resp.Movies[i] = toProto(m)
+	}
+	return resp, nil
+}
+
+func (s *movieService) StreamMovies(req *moviespb.StreamMoviesRequest, stream moviespb.MovieService_StreamMoviesServer) error {
+	ctx := stream.Context()
+	offset := int(req.GetOffset())
+	if offset < 0 {
+		return toStatus(apperr.BadRequest(apperr.CodeInvalidQuery, "offset must not be negative"))
+	}
+
+	for {
+		movies, err := s.store.GetMovieList(ctx, maxLimit, offset)
+		if err != nil {
+			return toStatus(apperr.Internal(err))
+		}
+
+		for _, m := range movies {
+			if err := stream.Send(toProto(m)); err != nil {
+				return err
+			}
+		}
+
+		if len(movies) < maxLimit {
+			return nil
+		}
+		offset += len(movies)
+	}
+}
+
+func (s *movieService) UpdateMovie(ctx context.Context, req *moviespb.UpdateMovieRequest) (*moviespb.Movie, error) {
+	movie, err := validMovie(ctx, req.GetMovie())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	if _, err := s.store.UpdateMovie(ctx, req.GetId(), movie); err != nil {
+		return nil, toStatus(apperr.FromDB(err, movieNotFound(req.GetId())))
+	}
+
+	return s.get(ctx, req.GetId())
+}
+
+func (s *movieService) DeleteMovie(ctx context.Context, req *moviespb.DeleteMovieRequest) (*moviespb.DeleteMovieResponse, error) {
+	id, err := s.store.DeleteMovieByID(ctx, req.GetId())
+	if err != nil {
+		return nil, toStatus(apperr.FromDB(err, movieNotFound(req.GetId())))
+	}
+
+	return &moviespb.DeleteMovieResponse{Id: id}, nil
+}
+
+func (s *movieService) get(ctx context.Context, id int64) (*moviespb.Movie, error) {
+	movie, err := s.store.GetMovieByID(ctx, id)
+	if err != nil {
+		return nil, toStatus(apperr.FromDB(err, movieNotFound(id)))
+	}
+	return toProto(movie), nil
+}
+
+// validMovie converts the message and applies the same rules as the HTTP API,
+// localized from the accept-language metadata
+func validMovie(ctx context.Context, pb *moviespb.Movie) (*types.Movie, error) {
+	if pb == nil {
+		return nil, apperr.BadRequest(apperr.CodeEmptyBody, "movie is required")
+	}
+
+	movie := fromProto(pb)
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	if err := validation.Struct(*movie, validation.Negotiate(first(md, "accept-language"))); err != nil {
+		return nil, err
+	}
+	return movie, nil
+}
+
+func toProto(m *types.Movie) *moviespb.Movie {
+	pb := &moviespb.Movie{
+		Id:          m.ID,
+		Title:       m.Title,
+		Rating:      int32(m.Rating),
+		ReleaseYear: int32(m.ReleaseYear),
+	}
+	if m.Director != nil {
+		pb.Director = &moviespb.Director{Id: m.Director.ID, Name: m.Director.Name, Age: int32(m.Director.Age)}
+	}
+	if m.Cast != nil {
+		pb.Cast = &moviespb.Cast{Id: m.Cast.ID, Actor: m.Cast.Actor, Actress: m.Cast.Actress}
+	}
+	return pb
+}
+
+// fromProto leaves Director and Cast nil when absent so validation reports them as required
+func fromProto(pb *moviespb.Movie) *types.Movie {
+	m := &types.Movie{
+		Title:       pb.GetTitle(),
+		Rating:      int(pb.GetRating()),
+		ReleaseYear: int(pb.GetReleaseYear()),
+	}
+	if d := pb.GetDirector(); d != nil {
+		m.Director = &types.Director{Name: d.GetName(), Age: int(d.GetAge())}
+	}
+	if c := pb.GetCast(); c != nil {
+		m.Cast = &types.Cast{Actor: c.GetActor(), Actress: c.GetActress()}
+	}
+	return m
+}
+
+func movieNotFound(id int64) *apperr.Error {
+	return apperr.NotFound(apperr.CodeMovieNotFound, "movie %d not found", id)
+}
@@ -0,0 +1,131 @@
+// Package rpc serves the movie catalogue over gRPC next to the HTTP API.
+package rpc
+
+//go:generate protoc -I ../../api/proto --go_out=../.. --go_opt=module=github/MahfujulSagor/movies_crud --go-grpc_out=../.. --go-grpc_opt=module=github/MahfujulSagor/movies_crud movies/v1/movies.proto
+
+import (
+	"context"
+	"github/MahfujulSagor/movies_crud/internals/auth"
+	"github/MahfujulSagor/movies_crud/internals/db"
+	"github/MahfujulSagor/movies_crud/internals/logger"
+	"github/MahfujulSagor/movies_crud/internals/rpc/moviespb"
+	"net"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+)
+
+// methodScopes lists the scope each catalogue RPC requires. Methods not listed here
+// belong to the health and reflection services, which are open like /healthz.
+var methodScopes = map[string]string{
+	moviespb.MovieService_CreateMovie_FullMethodName:  auth.ScopeMoviesWrite,
+	moviespb.MovieService_GetMovie_FullMethodName:     auth.ScopeMoviesRead,
+	moviespb.MovieService_ListMovies_FullMethodName:   auth.ScopeMoviesRead,
+	moviespb.MovieService_StreamMovies_FullMethodName: auth.ScopeMoviesRead,
+	moviespb.MovieService_UpdateMovie_FullMethodName:  auth.ScopeMoviesWrite,
+	moviespb.MovieService_DeleteMovie_FullMethodName:  auth.ScopeMoviesDelete,
+}
+
+type Server struct {
+	grpc   *grpc.Server
+	health *health.Server
+}
+
+func New(store db.DB, authn *auth.Authenticator, opts ...grpc.ServerOption) *Server {
+	opts = append(opts,
+		grpc.ChainUnaryInterceptor(unaryAuth(authn)),
+		grpc.ChainStreamInterceptor(streamAuth(authn)),
+	)
+	s := &Server{
+		grpc:   grpc.NewServer(opts...),
+		health: health.NewServer(),
+	}
+
+	moviespb.RegisterMovieServiceServer(s.grpc, &movieService{store: store})
+	healthpb.RegisterHealthServer(s.grpc, s.health)
+	reflection.Register(s.grpc)
+
+	s.health.SetServingStatus(moviespb.MovieService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+
+	return s
+}
+
+func (s *Server) Serve(lis net.Listener) error {
+	return s.grpc.Serve(lis)
+}
+
+// Shutdown reports NOT_SERVING, then drains in-flight calls until ctx expires
+func (s *Server) Shutdown(ctx context.Context) {
+	s.health.Shutdown()
+
+	done := make(chan struct{})
+	go func() {
+		s.grpc.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		s.grpc.Stop()
+	}
+}
+
+// authorize resolves the caller from metadata and returns a context carrying their identity
+func authorize(ctx context.Context, authn *auth.Authenticator, method string) (context.Context, error) {
+	scope, ok := methodScopes[method]
+	if !ok {
+		return ctx, nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	identity, err := authn.Check(first(md, "x-api-key"), first(md, "authorization"), scope)
+	if err != nil {
+		logger.Error.Println("gRPC authentication failed for", method+":", err)
+		return nil, toStatus(err)
+	}
+
+	logger.Info.Println("Authenticated", identity.Subject, "via", identity.Method, "for", method)
+
+	ctx = auth.WithIdentity(ctx, identity)
+	return db.WithActor(ctx, identity.Subject), nil
+}
+
+func unaryAuth(authn *auth.Authenticator) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := authorize(ctx, authn, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func streamAuth(authn *auth.Authenticator) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authorize(ss.Context(), authn, info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+func first(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
@@ -0,0 +1,273 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github/MahfujulSagor/movies_crud/internals/apperr"
+	"github/MahfujulSagor/movies_crud/internals/auth"
+	"github/MahfujulSagor/movies_crud/internals/config"
+	"github/MahfujulSagor/movies_crud/internals/db"
+	"github/MahfujulSagor/movies_crud/internals/db/sqlite"
+	"github/MahfujulSagor/movies_crud/internals/logger"
+	"github/MahfujulSagor/movies_crud/internals/rpc/moviespb"
+	"github/MahfujulSagor/movies_crud/internals/types"
+	"io"
+	"log"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func init() {
+	logger.Info = log.New(io.Discard, "", 0)
+	logger.Error = log.New(io.Discard, "", 0)
+}
+
+// reason returns the stable API code carried in the status's ErrorInfo
+func reason(st *status.Status) string {
+	for _, d := range st.Details() {
+		if info, ok := d.(*errdetails.ErrorInfo); ok {
+			return info.Reason
+		}
+	}
+	return ""
+}
+
+func TestToStatus(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		err    error
+		code   codes.Code
+		reason string
+	}{
+		{"not found", apperr.FromDB(db.ErrNotFound, movieNotFound(7)), codes.NotFound, apperr.CodeMovieNotFound},
+		{"conflict", apperr.FromDB(fmt.Errorf("%w: UNIQUE constraint failed: movies.title", db.ErrConflict), nil), codes.AlreadyExists, apperr.CodeConflict},
+		{"constraint", apperr.FromDB(fmt.Errorf("%w: FOREIGN KEY constraint failed", db.ErrConstraint), nil), codes.FailedPrecondition, apperr.CodeConstraint},
+		{"unauthenticated", apperr.Unauthorized(apperr.CodeInvalidToken, "bad key"), codes.Unauthenticated, apperr.CodeInvalidToken},
+		{"forbidden", apperr.Forbidden("missing scope"), codes.PermissionDenied, apperr.CodeForbidden},
+		{"rate limited", apperr.New(apperr.KindRateLimited, apperr.CodeRateLimited, "slow down"), codes.ResourceExhausted, apperr.CodeRateLimited},
+		{"unmapped kind", apperr.New(apperr.KindTooLarge, apperr.CodeBadRequest, "too large"), codes.Internal, apperr.CodeBadRequest},
+		{"storage failure", apperr.FromDB(errors.New("disk I/O error"), nil), codes.Internal, apperr.CodeInternal},
+		{"plain error", errors.New("boom"), codes.Internal, apperr.CodeInternal},
+	} {
+		st := status.Convert(toStatus(tc.err))
+		if st.Code() != tc.code || reason(st) != tc.reason {
+			t.Errorf("%s: got %s %q, want %s %q", tc.name, st.Code(), reason(st), tc.code, tc.reason)
+		}
+		//? Driver text never reaches the client
+		if tc.code == codes.AlreadyExists || tc.code == codes.FailedPrecondition || tc.code == codes.Internal {
+			if msg := st.Message(); msg != apperr.From(tc.err).Detail || msg == "" {
+				t.Errorf("%s: message %q", tc.name, msg)
+			}
+		}
+	}
+
+	st := status.Convert(toStatus(apperr.Validation([]apperr.FieldError{{Field: "director.age", Rule: "lte", Message: "too old"}})))
+	var violations []*errdetails.BadRequest_FieldViolation
+	for _, d := range st.Details() {
+		if br, ok := d.(*errdetails.BadRequest); ok {
+			violations = br.FieldViolations
+		}
+	}
+	if st.Code() != codes.InvalidArgument || len(violations) != 1 || violations[0].Field != "director.age" {
+		t.Errorf("validation became %s with violations %v", st.Code(), violations)
+	}
+}
+
+// testServer serves the gRPC API over an in-memory listener and returns a client and keys
+// with all movie scopes and with read only
+func testServer(t *testing.T) (*sqlite.SQLite, *grpc.ClientConn, string, string) {
+	t.Helper()
+	store, err := sqlite.New(&config.Config{DBPath: filepath.Join(t.TempDir(), "movies.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	lis := bufconn.Listen(1 << 20)
+	server := New(store, &auth.Authenticator{Keys: store, Users: store})
+	go server.Serve(lis)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		server.Shutdown(ctx)
+	})
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return store, conn, newKey(t, store, auth.ScopeMoviesRead, auth.ScopeMoviesWrite, auth.ScopeMoviesDelete), newKey(t, store, auth.ScopeMoviesRead)
+}
+
+func newKey(t *testing.T, store db.APIKeyStore, scopes ...string) string {
+	t.Helper()
+	key, prefix, err := auth.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.CreateAPIKey(&types.APIKey{Name: "rpc", Prefix: prefix, Scopes: scopes, CreatedAt: time.Now()}, auth.HashKey(key)); err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func withKey(key string, pairs ...string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), append([]string{"x-api-key", key}, pairs...)...)
+}
+
+func testMovie(title string) *moviespb.Movie {
+	return &moviespb.Movie{
+		Title:    title,
+		Rating:   7,
+		Director: &moviespb.Director{Name: "Director of " + title, Age: 50},
+		Cast:     &moviespb.Cast{Actor: "Actor", Actress: "Actress"},
+	}
+}
+
+func TestStreamMoviesSendsEveryPage(t *testing.T) {
+	store, conn, _, readKey := testServer(t)
+
+	//? More than two of the stream's internal pages
+	total := 2*maxLimit + 7
+	for i := range total {
+		if _, err := store.CreateMovie(context.Background(), fromProto(testMovie(fmt.Sprintf("Movie %03d", i)))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	client := moviespb.NewMovieServiceClient(conn)
+	for _, offset := range []int{0, 5, total} {
+		stream, err := client.StreamMovies(withKey(readKey), &moviespb.StreamMoviesRequest{Offset: int32(offset)})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var got []*moviespb.Movie
+		for {
+			m, err := stream.Recv()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, m)
+		}
+
+		if len(got) != total-offset {
+			t.Fatalf("offset %d: streamed %d movies, want %d", offset, len(got), total-offset)
+		}
+		for i, m := range got {
+			if want := fmt.Sprintf("Movie %03d", offset+i); m.Title != want {
+				t.Fatalf("offset %d: movie %d is %q, want %q", offset, i, m.Title, want)
+			}
+		}
+	}
+
+	stream, err := client.StreamMovies(withKey(readKey), &moviespb.StreamMoviesRequest{Offset: -1})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("negative offset returned %v", err)
+	}
+}
+
+func TestMovieServiceErrors(t *testing.T) {
+	_, conn, key, readKey := testServer(t)
+	client := moviespb.NewMovieServiceClient(conn)
+
+	created, err := client.CreateMovie(withKey(key), &moviespb.CreateMovieRequest{Movie: testMovie("Heat")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	invalid := testMovie("Heat")
+	invalid.Director.Age = 200
+
+	for _, tc := range []struct {
+		name   string
+		call   func() error
+		code   codes.Code
+		reason string
+	}{
+		{"missing movie", func() error {
+			_, err := client.GetMovie(withKey(readKey), &moviespb.GetMovieRequest{Id: created.Id + 1})
+			return err
+		}, codes.NotFound, apperr.CodeMovieNotFound},
+		{"duplicate", func() error {
+			_, err := client.CreateMovie(withKey(key), &moviespb.CreateMovieRequest{Movie: testMovie("Heat")})
+			return err
+		}, codes.AlreadyExists, apperr.CodeConflict},
+		{"invalid", func() error {
+			_, err := client.CreateMovie(withKey(key), &moviespb.CreateMovieRequest{Movie: invalid})
+			return err
+		}, codes.InvalidArgument, apperr.CodeValidationFailed},
+		{"no movie", func() error {
+			_, err := client.CreateMovie(withKey(key), &moviespb.CreateMovieRequest{})
+			return err
+		}, codes.InvalidArgument, apperr.CodeEmptyBody},
+		{"no key", func() error {
+			_, err := client.GetMovie(context.Background(), &moviespb.GetMovieRequest{Id: created.Id})
+			return err
+		}, codes.Unauthenticated, apperr.CodeUnauthorized},
+		{"unknown key", func() error {
+			_, err := client.GetMovie(withKey("mvk_unknown"), &moviespb.GetMovieRequest{Id: created.Id})
+			return err
+		}, codes.Unauthenticated, apperr.CodeInvalidToken},
+		{"missing scope", func() error {
+			_, err := client.DeleteMovie(withKey(readKey), &moviespb.DeleteMovieRequest{Id: created.Id})
+			return err
+		}, codes.PermissionDenied, apperr.CodeForbidden},
+		{"missing scope on a stream", func() error {
+			stream, err := client.StreamMovies(context.Background(), &moviespb.StreamMoviesRequest{})
+			if err == nil {
+				_, err = stream.Recv()
+			}
+			return err
+		}, codes.Unauthenticated, apperr.CodeUnauthorized},
+	} {
+		st := status.Convert(tc.call())
+		if st.Code() != tc.code || reason(st) != tc.reason {
+			t.Errorf("%s: got %s %q (%s), want %s %q", tc.name, st.Code(), reason(st), st.Message(), tc.code, tc.reason)
+		}
+	}
+
+	//? Validation messages follow accept-language, as on the HTTP API
+	_, err = client.CreateMovie(withKey(key, "accept-language", "fr"), &moviespb.CreateMovieRequest{Movie: invalid})
+	var violations []*errdetails.BadRequest_FieldViolation
+	for _, d := range status.Convert(err).Details() {
+		if br, ok := d.(*errdetails.BadRequest); ok {
+			violations = br.FieldViolations
+		}
+	}
+	if len(violations) != 1 || violations[0].Field != "director.age" || violations[0].Description != "director.age doit être inférieur ou égal à 110" {
+		t.Errorf("violations %v", violations)
+	}
+}
+
+func TestHealthIsOpen(t *testing.T) {
+	_, conn, _, _ := testServer(t)
+
+	resp, err := healthpb.NewHealthClient(conn).Check(context.Background(),
+		&healthpb.HealthCheckRequest{Service: moviespb.MovieService_ServiceDesc.ServiceName})
+	if err != nil || resp.Status != healthpb.HealthCheckResponse_SERVING {
+		t.Fatalf("health check returned %v, %v", resp, err)
+	}
+}
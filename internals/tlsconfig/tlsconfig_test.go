@@ -0,0 +1,256 @@
+package tlsconfig
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github/MahfujulSagor/movies_crud/internals/config"
+	"github/MahfujulSagor/movies_crud/internals/logger"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func init() {
+	logger.Info = log.New(io.Discard, "", 0)
+	logger.Error = log.New(io.Discard, "", 0)
+}
+
+// issued is a generated certificate with its key, signed by its parent or by itself
+type issued struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	der  []byte
+}
+
+func issue(t *testing.T, name string, parent *issued, usage ...x509.ExtKeyUsage) *issued {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  usage,
+	}
+
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA, template.BasicConstraintsValid = true, true
+		template.KeyUsage |= x509.KeyUsageCertSign
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &issued{cert: cert, key: key, der: der}
+}
+
+func (c *issued) tlsCertificate() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.der}, PrivateKey: c.key, Leaf: c.cert}
+}
+
+// write stores the certificate and key as PEM, dating the files at so reloads see them change
+func (c *issued) write(t *testing.T, certFile, keyFile string, at time.Time) {
+	t.Helper()
+	keyDER, err := x509.MarshalECPrivateKey(c.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.der}), at)
+	if keyFile != "" {
+		writeFile(t, keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), at)
+	}
+}
+
+func writeFile(t *testing.T, path string, data []byte, at time.Time) {
+	t.Helper()
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, at, at); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// testConfig writes a server certificate into a temp dir and returns the config pointing at it
+func testConfig(t *testing.T, server *issued) *config.Config {
+	t.Helper()
+	dir := t.TempDir()
+	cfg := &config.Config{}
+	cfg.HTTPConfig.TLS = config.TLSConfig{
+		CertFile:   filepath.Join(dir, "server.crt"),
+		KeyFile:    filepath.Join(dir, "server.key"),
+		MinVersion: "1.2",
+		ClientAuth: "require",
+	}
+	server.write(t, cfg.HTTPConfig.TLS.CertFile, cfg.HTTPConfig.TLS.KeyFile, time.Now().Add(-time.Minute))
+	return cfg
+}
+
+func served(t *testing.T, m *Manager) *x509.Certificate {
+	t.Helper()
+	cert, err := m.Config().GetCertificate(&tls.ClientHelloInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return leaf
+}
+
+func TestWatchReloadsAndKeepsCertificateOnFailure(t *testing.T) {
+	first := issue(t, "movies.example.com", nil)
+	cfg := testConfig(t, first)
+	m, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !served(t, m).Equal(first.cert) {
+		t.Fatal("the loaded certificate is not served")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go m.Watch(ctx, 5*time.Millisecond)
+
+	waitFor := func(want *x509.Certificate) {
+		t.Helper()
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+			if served(t, m).Equal(want) {
+				return
+			}
+		}
+		t.Fatalf("still serving %s, want serial %s", served(t, m).SerialNumber, want.SerialNumber)
+	}
+
+	second := issue(t, "movies.example.com", nil)
+	second.write(t, cfg.HTTPConfig.TLS.CertFile, cfg.HTTPConfig.TLS.KeyFile, time.Now())
+	waitFor(second.cert)
+
+	//? A certificate that no longer matches its key is refused, and the working pair stays
+	third := issue(t, "movies.example.com", nil)
+	third.write(t, cfg.HTTPConfig.TLS.CertFile, "", time.Now().Add(time.Minute))
+	time.Sleep(50 * time.Millisecond)
+	if !served(t, m).Equal(second.cert) {
+		t.Fatal("a broken reload replaced the working certificate")
+	}
+
+	//? Once the key catches up, the pair is loaded
+	third.write(t, cfg.HTTPConfig.TLS.CertFile, cfg.HTTPConfig.TLS.KeyFile, time.Now().Add(2*time.Minute))
+	waitFor(third.cert)
+}
+
+func TestNewRejectsBadSettings(t *testing.T) {
+	server := issue(t, "movies.example.com", nil)
+
+	for name, change := range map[string]func(*config.TLSConfig){
+		"old version":         func(tc *config.TLSConfig) { tc.MinVersion = "1.0" },
+		"insecure suite":      func(tc *config.TLSConfig) { tc.CipherSuites = []string{"TLS_RSA_WITH_RC4_128_SHA"} },
+		"CBC-SHA1 RSA suite":  func(tc *config.TLSConfig) { tc.CipherSuites = []string{"TLS_RSA_WITH_3DES_EDE_CBC_SHA"} },
+		"unknown suite":       func(tc *config.TLSConfig) { tc.CipherSuites = []string{"TLS_MADE_UP"} },
+		"unknown client auth": func(tc *config.TLSConfig) { tc.ClientCAFile, tc.ClientAuth = tc.CertFile, "sometimes" },
+		"missing key":         func(tc *config.TLSConfig) { tc.KeyFile += ".missing" },
+		"empty CA bundle":     func(tc *config.TLSConfig) { tc.ClientCAFile = tc.KeyFile },
+	} {
+		cfg := testConfig(t, server)
+		change(&cfg.HTTPConfig.TLS)
+		if _, err := New(cfg); err == nil {
+			t.Errorf("%s: New accepted the config", name)
+		}
+	}
+
+	cfg := testConfig(t, server)
+	cfg.HTTPConfig.TLS.MinVersion = "1.3"
+	cfg.HTTPConfig.TLS.CipherSuites = []string{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256"}
+	m, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := m.Config(); c.MinVersion != tls.VersionTLS13 || len(c.CipherSuites) != 1 || c.ClientAuth != tls.NoClientCert || c.VerifyConnection != nil {
+		t.Fatalf("config %+v", c)
+	}
+}
+
+// handshake connects a client presenting certs to a server using m and returns the server's error
+func handshake(t *testing.T, m *Manager, ca *issued, certs ...tls.Certificate) error {
+	t.Helper()
+	serverConn, clientConn := net.Pipe()
+	defer serverConn.Close()
+	defer clientConn.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	client := tls.Client(clientConn, &tls.Config{ServerName: "movies.example.com", RootCAs: roots, Certificates: certs})
+	go func() {
+		client.Handshake()
+		//? TLS 1.3 clients finish before the server has checked their certificate; wait for its verdict
+		client.Read(make([]byte, 1))
+		client.Close()
+	}()
+
+	return tls.Server(serverConn, m.Config()).Handshake()
+}
+
+func TestClientCertificates(t *testing.T) {
+	ca := issue(t, "Movies CA", nil)
+	server := issue(t, "movies.example.com", ca, x509.ExtKeyUsageServerAuth)
+	trusted := issue(t, "ci", ca, x509.ExtKeyUsageClientAuth)
+	serverOnly := issue(t, "proxy", ca, x509.ExtKeyUsageServerAuth)
+	stranger := issue(t, "stranger", issue(t, "Other CA", nil), x509.ExtKeyUsageClientAuth)
+
+	for _, mode := range []string{"require", "optional"} {
+		cfg := testConfig(t, server)
+		cfg.HTTPConfig.TLS.ClientAuth = mode
+		cfg.HTTPConfig.TLS.ClientCAFile = filepath.Join(filepath.Dir(cfg.HTTPConfig.TLS.CertFile), "ca.crt")
+		ca.write(t, cfg.HTTPConfig.TLS.ClientCAFile, "", time.Now())
+		m, err := New(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, tc := range []struct {
+			name string
+			cert *issued
+			ok   bool
+		}{
+			{"trusted client", trusted, true},
+			{"no certificate", nil, mode == "optional"},
+			{"other CA", stranger, false},
+			{"not for client auth", serverOnly, false},
+		} {
+			var certs []tls.Certificate
+			if tc.cert != nil {
+				certs = append(certs, tc.cert.tlsCertificate())
+			}
+			if err := handshake(t, m, ca, certs...); (err == nil) != tc.ok {
+				t.Errorf("%s, %s: handshake error %v, want success %v", mode, tc.name, err, tc.ok)
+			}
+		}
+	}
+}
@@ -1,21 +1,68 @@
 package types
 
+import "time"
+
 type Movie struct {
-	ID       int64     `json:"id"`
-	Title    string    `json:"name" validate:"required"`
-	Rating   int       `json:"rating" validate:"required,gte=0,lte=10"`
-	Director *Director `json:"director"`
-	Cast     *Cast     `json:"cast"`
+	ID          int64     `json:"id" xml:"id"`
+	Title       string    `json:"name" xml:"name" validate:"required,title_length,no_leading_space"`
+	Rating      int       `json:"rating" xml:"rating" validate:"required,gte=0,lte=10"`
+	ReleaseYear int       `json:"release_year,omitempty" xml:"release_year,omitempty" validate:"omitempty,release_year"`
+	Director    *Director `json:"director" xml:"director" validate:"required"`
+	Cast        *Cast     `json:"cast" xml:"cast" validate:"required"`
+	// CreatedAt and UpdatedAt are set by storage; values sent by clients are ignored
+	CreatedAt time.Time `json:"created_at" xml:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" xml:"updated_at"`
 }
 
 type Director struct {
-	ID   int64  `json:"id"`
-	Name string `json:"name" validate:"required"`
-	Age  int    `json:"age" validate:"required,gte=0,lte=110"`
+	ID   int64  `json:"id" xml:"id"`
+	Name string `json:"name" xml:"name" validate:"required,no_leading_space"`
+	Age  int    `json:"age" xml:"age" validate:"required,gte=0,lte=110"`
 }
 
 type Cast struct {
-	ID      int64  `json:"id"`
-	Actor   string `json:"actor" validate:"required"`
-	Actress string `json:"actress" validate:"required"`
+	ID      int64  `json:"id" xml:"id"`
+	Actor   string `json:"actor" xml:"actor" validate:"required,no_leading_space"`
+	Actress string `json:"actress" xml:"actress" validate:"required,no_leading_space"`
+}
+
+type APIKey struct {
+	ID        int64      `json:"id"`
+	Name      string     `json:"name" validate:"required"`
+	Prefix    string     `json:"prefix"`
+	Scopes    []string   `json:"scopes" validate:"required,min=1"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+type User struct {
+	ID        int64     `json:"id"`
+	Username  string    `json:"username" validate:"required"`
+	Role      string    `json:"role" validate:"required,oneof=viewer editor admin"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Webhook struct {
+	ID     int64    `json:"id"`
+	URL    string   `json:"url" validate:"required,url,max=2048"`
+	Events []string `json:"events" validate:"required,min=1,dive,oneof=movie.created movie.updated movie.deleted"`
+	// Secret signs deliveries; it is only returned when the webhook is created
+	Secret    string    `json:"secret,omitempty" validate:"omitempty,min=16"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type WebhookDelivery struct {
+	ID        int64 `json:"id"`
+	WebhookID int64 `json:"webhook_id"`
+	// EventID is the outbox event delivered; zero for manual redeliveries
+	EventID       uint64     `json:"event_id,omitempty"`
+	EventType     string     `json:"event_type"`
+	Payload       string     `json:"payload"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt time.Time  `json:"next_attempt_at"`
+	LastStatus    int        `json:"last_status,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	DeliveredAt   *time.Time `json:"delivered_at,omitempty"`
 }
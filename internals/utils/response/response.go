@@ -2,11 +2,10 @@ package response
 
 import (
 	"encoding/json"
-	"fmt"
+	"github/MahfujulSagor/movies_crud/internals/apperr"
+	"github/MahfujulSagor/movies_crud/internals/http/negotiate"
 	"net/http"
 	"strings"
-
-	"github.com/go-playground/validator"
 )
 
 const (
@@ -19,6 +18,17 @@ type Response struct {
 	Error  string `json:"error"`
 }
 
+// Problem is an RFC 9457 problem details object with the stable code and field errors as extensions
+type Problem struct {
+	Type     string              `json:"type"`
+	Title    string              `json:"title"`
+	Status   int                 `json:"status"`
+	Detail   string              `json:"detail,omitempty"`
+	Instance string              `json:"instance,omitempty"`
+	Code     string              `json:"code"`
+	Errors   []apperr.FieldError `json:"errors,omitempty"`
+}
+
 func WriteJson(w http.ResponseWriter, status int, data interface{}) error {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
@@ -26,29 +36,54 @@ func WriteJson(w http.ResponseWriter, status int, data interface{}) error {
 	return json.NewEncoder(w).Encode(data)
 }
 
-func GeneralError(err error) Response {
-	return Response{
-		Status: StatusError,
-		Error:  err.Error(),
-	}
+// Write encodes data in the format negotiated for the request, JSON on routes that do not negotiate
+func Write(w http.ResponseWriter, r *http.Request, status int, data interface{}) error {
+	codec := negotiate.FromContext(r.Context())
+	w.Header().Set("Content-Type", codec.MediaType)
+	w.WriteHeader(status)
+
+	return codec.Encode(w, data)
 }
 
-func ValidationError(errs validator.ValidationErrors) Response {
-	var errMsgs []string
-	for _, err := range errs {
-		switch err.ActualTag() {
-		case "required":
-			errMsgs = append(errMsgs, fmt.Sprintf("%s is required", err.Field()))
-		case "gte":
-			errMsgs = append(errMsgs, fmt.Sprintf("%s must be greater than or equal to %s", err.Field(), err.Param()))
-		case "lte":
-			errMsgs = append(errMsgs, fmt.Sprintf("%s must be less than or equal to %s", err.Field(), err.Param()))
-		default:
-			errMsgs = append(errMsgs, fmt.Sprintf("%s is not valid", err.Field()))
+// Negotiate selects the response format from the offered media types by the request's Accept header,
+// answering 406 when none is acceptable
+func Negotiate(offered []string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		codec, ok := negotiate.Select(r.Header.Get("Accept"), offered)
+		if !ok {
+			WriteProblem(w, r, apperr.New(apperr.KindNotAcceptable, apperr.CodeNotAcceptable,
+				"cannot respond with %q, use one of %s", r.Header.Get("Accept"), strings.Join(offered, ", ")))
+			return
 		}
+		next(w, r.WithContext(negotiate.WithCodec(r.Context(), codec)))
 	}
-	return Response{
-		Status: StatusError,
-		Error:  strings.Join(errMsgs, ", "),
+}
+
+// WriteProblem renders err as application/problem+json; unclassified errors become a generic 500
+func WriteProblem(w http.ResponseWriter, r *http.Request, err error) error {
+	appErr := apperr.From(err)
+	status := appErr.Status()
+
+	problem := Problem{
+		Type:     "/problems/" + appErr.Code,
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   appErr.Detail,
+		Instance: r.URL.Path,
+		Code:     appErr.Code,
+		Errors:   appErr.Fields,
 	}
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	//? Errors are never cached, whatever the route's policy
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+
+	return json.NewEncoder(w).Encode(problem)
+}
+
+// Message is the body returned by endpoints that have no resource to echo back
+type Message struct {
+	Success string `json:"success" xml:"success"`
+	Message string `json:"message" xml:"message"`
 }
@@ -0,0 +1,82 @@
+package response
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github/MahfujulSagor/movies_crud/internals/apperr"
+	"github/MahfujulSagor/movies_crud/internals/http/negotiate"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWriteProblem(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		err    error
+		status int
+		code   string
+		detail string
+	}{
+		{"not found", apperr.NotFound(apperr.CodeMovieNotFound, "movie %d not found", 7), http.StatusNotFound, apperr.CodeMovieNotFound, "movie 7 not found"},
+		{"wrapped", fmt.Errorf("handler: %w", apperr.Forbidden("missing required scope: movies:delete")), http.StatusForbidden, apperr.CodeForbidden, "missing required scope: movies:delete"},
+		{"unclassified errors are hidden", errors.New("database is locked"), http.StatusInternalServerError, apperr.CodeInternal, "internal server error"},
+		{"validation", apperr.Validation([]apperr.FieldError{{Field: "name", Rule: "required", Message: "name is required"}}), http.StatusBadRequest, apperr.CodeValidationFailed, "request validation failed"},
+	} {
+		rec := httptest.NewRecorder()
+		WriteProblem(rec, httptest.NewRequest(http.MethodGet, "/api/v1/movies/7?x=1", nil), tc.err)
+
+		if ct := rec.Header().Get("Content-Type"); ct != "application/problem+json" {
+			t.Errorf("%s: Content-Type %q", tc.name, ct)
+		}
+		if cc := rec.Header().Get("Cache-Control"); cc != "no-store" {
+			t.Errorf("%s: Cache-Control %q", tc.name, cc)
+		}
+
+		var p Problem
+		if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		want := Problem{Type: "/problems/" + tc.code, Title: http.StatusText(tc.status), Status: tc.status,
+			Detail: tc.detail, Instance: "/api/v1/movies/7", Code: tc.code}
+		if rec.Code != tc.status || p.Type != want.Type || p.Title != want.Title || p.Status != want.Status ||
+			p.Detail != want.Detail || p.Instance != want.Instance || p.Code != want.Code {
+			t.Errorf("%s: got %d %+v, want %+v", tc.name, rec.Code, p, want)
+		}
+		if tc.code == apperr.CodeValidationFailed && (len(p.Errors) != 1 || p.Errors[0].Field != "name") {
+			t.Errorf("%s: field errors %+v", tc.name, p.Errors)
+		}
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	handler := Negotiate(negotiate.Formats(false), func(w http.ResponseWriter, r *http.Request) {
+		Write(w, r, http.StatusOK, Message{Success: StatusOK, Message: "hello"})
+	})
+
+	for accept, want := range map[string]string{
+		"":                             negotiate.JSON,
+		"application/xml":              negotiate.XML,
+		"text/csv;q=1, application/*":  negotiate.JSON,
+		"application/yaml, */*;q=0.1":  negotiate.YAML,
+		"application/msgpack;q=0.5, *": negotiate.MsgPack,
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept", accept)
+		rec := httptest.NewRecorder()
+		handler(rec, r)
+		if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != want {
+			t.Errorf("Accept %q: got %d %s, want %s", accept, rec.Code, rec.Header().Get("Content-Type"), want)
+		}
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept", "text/csv")
+	rec := httptest.NewRecorder()
+	handler(rec, r)
+	if rec.Code != http.StatusNotAcceptable || !strings.Contains(rec.Body.String(), apperr.CodeNotAcceptable) {
+		t.Errorf("CSV for a single resource got %d %s, want 406", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,84 @@
+package validation
+
+import (
+	"cmp"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Supported lists the locales with translated validation messages
+var Supported = []string{"en", "fr", "nl", "pt_BR"}
+
+// Locale picks the best supported locale from Accept-Language, honouring q-values
+func Locale(r *http.Request) string {
+	return Negotiate(r.Header.Get("Accept-Language"))
+}
+
+func Negotiate(header string) string {
+	type candidate struct {
+		tag string
+		q   float64
+	}
+
+	var candidates []candidate
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q > 0 {
+			candidates = append(candidates, candidate{tag: tag, q: q})
+		}
+	}
+
+	//? Stable sort keeps header order for equal weights
+	slices.SortStableFunc(candidates, func(a, b candidate) int {
+		return cmp.Compare(b.q, a.q)
+	})
+
+	for _, c := range candidates {
+		if locale, ok := match(c.tag); ok {
+			return locale
+		}
+	}
+
+	return DefaultLocale
+}
+
+// match maps a BCP 47 tag such as "pt-BR" or "fr-CA" to a supported locale
+func match(tag string) (string, bool) {
+	normalized := strings.ReplaceAll(tag, "-", "_")
+	for _, s := range Supported {
+		if strings.EqualFold(s, normalized) {
+			return s, true
+		}
+	}
+
+	base, _, _ := strings.Cut(normalized, "_")
+	for _, s := range Supported {
+		if strings.EqualFold(s, base) {
+			return s, true
+		}
+	}
+
+	//? Any Portuguese variant is closer to pt_BR than to the default
+	for _, s := range Supported {
+		if sBase, _, _ := strings.Cut(s, "_"); strings.EqualFold(sBase, base) {
+			return s, true
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,93 @@
+package validation
+
+import (
+	"strconv"
+	"time"
+
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator"
+)
+
+// fallbackKey is used for rules without a dedicated message
+const fallbackKey = "invalid"
+
+// messages holds a translation per locale for every rule used on request types.
+// {0} is the JSON field name and {1} the rule's parameter or bound.
+var messages = map[string]map[string]string{
+	"en": {
+		"required":        "{0} is required",
+		"gte":             "{0} must be greater than or equal to {1}",
+		"lte":             "{0} must be less than or equal to {1}",
+		"min":             "{0} must be at least {1}",
+		"max":             "{0} must be at most {1}",
+		"oneof":           "{0} must be one of [{1}]",
+		TagTitleLength:    "{0} must be between 1 and {1} characters long",
+		TagNoLeadingSpace: "{0} must not start with whitespace",
+		TagReleaseYear:    "{0} must be a year between 1888 and {1}",
+		fallbackKey:       "{0} is not valid",
+	},
+	"fr": {
+		"required":        "{0} est obligatoire",
+		"gte":             "{0} doit être supérieur ou égal à {1}",
+		"lte":             "{0} doit être inférieur ou égal à {1}",
+		"min":             "{0} doit valoir au moins {1}",
+		"max":             "{0} doit valoir au plus {1}",
+		"oneof":           "{0} doit être l'une des valeurs [{1}]",
+		TagTitleLength:    "{0} doit contenir entre 1 et {1} caractères",
+		TagNoLeadingSpace: "{0} ne doit pas commencer par un espace",
+		TagReleaseYear:    "{0} doit être une année comprise entre 1888 et {1}",
+		fallbackKey:       "{0} n'est pas valide",
+	},
+	"nl": {
+		"required":        "{0} is een verplicht veld",
+		"gte":             "{0} moet groter dan of gelijk aan {1} zijn",
+		"lte":             "{0} moet kleiner dan of gelijk aan {1} zijn",
+		"min":             "{0} moet minimaal {1} zijn",
+		"max":             "{0} mag maximaal {1} zijn",
+		"oneof":           "{0} moet een van de volgende waarden zijn [{1}]",
+		TagTitleLength:    "{0} moet tussen 1 en {1} tekens lang zijn",
+		TagNoLeadingSpace: "{0} mag niet met een spatie beginnen",
+		TagReleaseYear:    "{0} moet een jaartal tussen 1888 en {1} zijn",
+		fallbackKey:       "{0} is niet geldig",
+	},
+	"pt_BR": {
+		"required":        "{0} é um campo obrigatório",
+		"gte":             "{0} deve ser maior ou igual a {1}",
+		"lte":             "{0} deve ser menor ou igual a {1}",
+		"min":             "{0} deve ser no mínimo {1}",
+		"max":             "{0} deve ser no máximo {1}",
+		"oneof":           "{0} deve ser um de [{1}]",
+		TagTitleLength:    "{0} deve ter entre 1 e {1} caracteres",
+		TagNoLeadingSpace: "{0} não deve começar com espaço em branco",
+		TagReleaseYear:    "{0} deve ser um ano entre 1888 e {1}",
+		fallbackKey:       "{0} não é válido",
+	},
+}
+
+func registerTranslations(trans ut.Translator, locale string) error {
+	for key, msg := range messages[locale] {
+		if err := trans.Add(key, msg, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// translate renders fe for field in the translator's language, falling back to the generic message
+func translate(trans ut.Translator, fe validator.FieldError, field string) string {
+	msg, err := trans.T(fe.Tag(), field, param(fe))
+	if err != nil {
+		msg, _ = trans.T(fallbackKey, field)
+	}
+	return msg
+}
+
+func param(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case TagTitleLength:
+		return strconv.Itoa(maxTitleLength)
+	case TagReleaseYear:
+		return strconv.Itoa(time.Now().Year() + maxYearsAhead)
+	}
+	return fe.Param()
+}
@@ -0,0 +1,144 @@
+package validation
+
+import (
+	"errors"
+	"github/MahfujulSagor/movies_crud/internals/apperr"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/fr"
+	"github.com/go-playground/locales/nl"
+	"github.com/go-playground/locales/pt_BR"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator"
+)
+
+const (
+	TagTitleLength    string = "title_length"
+	TagNoLeadingSpace string = "no_leading_space"
+	TagReleaseYear    string = "release_year"
+)
+
+const (
+	maxTitleLength = 200
+	// firstFilmYear is the year of the earliest surviving motion picture
+	firstFilmYear = 1888
+	// maxYearsAhead allows announced but unreleased films
+	maxYearsAhead = 5
+)
+
+const DefaultLocale = "en"
+
+var (
+	once      sync.Once
+	validate  *validator.Validate
+	universal *ut.UniversalTranslator
+	setupErr  error
+)
+
+// setup builds the shared validator; validator caches struct metadata so it must be reused
+func setup() {
+	validate = validator.New()
+
+	//? Report JSON names (e.g. "director.age") instead of Go field names
+	validate.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return f.Name
+		}
+		return name
+	})
+
+	setupErr = errors.Join(
+		validate.RegisterValidation(TagTitleLength, titleLength),
+		validate.RegisterValidation(TagNoLeadingSpace, noLeadingSpace),
+		validate.RegisterValidation(TagReleaseYear, releaseYear),
+	)
+	if setupErr != nil {
+		return
+	}
+
+	enLocale := en.New()
+	universal = ut.New(enLocale, enLocale, fr.New(), nl.New(), pt_BR.New())
+
+	for _, locale := range Supported {
+		trans, _ := universal.GetTranslator(locale)
+		if err := registerTranslations(trans, locale); err != nil {
+			setupErr = err
+			return
+		}
+	}
+}
+
+// Struct validates s, returning nil or a validation error whose messages use locale
+func Struct(s any, locale string) error {
+	once.Do(setup)
+	if setupErr != nil {
+		return apperr.Internal(setupErr)
+	}
+
+	err := validate.Struct(s)
+	if err == nil {
+		return nil
+	}
+
+	var errs validator.ValidationErrors
+	if !errors.As(err, &errs) {
+		return apperr.Internal(err)
+	}
+
+	trans, _ := universal.GetTranslator(locale)
+
+	fields := make([]apperr.FieldError, 0, len(errs))
+	for _, fe := range errs {
+		path := jsonPath(fe.Namespace())
+		fields = append(fields, apperr.FieldError{
+			Field:   path,
+			Rule:    fe.Tag(),
+			Message: translate(trans, fe, path),
+		})
+	}
+
+	return apperr.Validation(fields)
+}
+
+// Request validates s using the best locale from the request's Accept-Language header
+func Request(r *http.Request, s any) error {
+	return Struct(s, Locale(r))
+}
+
+// jsonPath drops the top-level struct name: "Movie.director.age" becomes "director.age"
+func jsonPath(namespace string) string {
+	if _, path, ok := strings.Cut(namespace, "."); ok {
+		return path
+	}
+	return namespace
+}
+
+func titleLength(fl validator.FieldLevel) bool {
+	n := utf8.RuneCountInString(strings.TrimSpace(fl.Field().String()))
+	return n >= 1 && n <= maxTitleLength
+}
+
+func noLeadingSpace(fl validator.FieldLevel) bool {
+	s := fl.Field().String()
+	if s == "" {
+		return true
+	}
+	r, _ := utf8.DecodeRuneInString(s)
+	return !unicode.IsSpace(r)
+}
+
+func releaseYear(fl validator.FieldLevel) bool {
+	year := fl.Field().Int()
+	return year >= firstFilmYear && year <= int64(time.Now().Year()+maxYearsAhead)
+}
@@ -0,0 +1,153 @@
+package validation
+
+import (
+	"errors"
+	"github/MahfujulSagor/movies_crud/internals/apperr"
+	"github/MahfujulSagor/movies_crud/internals/types"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestNegotiate(t *testing.T) {
+	for header, want := range map[string]string{
+		"":                             "en",
+		"fr":                           "fr",
+		"fr-CA":                        "fr",
+		"nl-BE,en;q=0.5":               "nl",
+		"en;q=0.4, fr;q=0.8":           "fr",
+		"pt-BR":                        "pt_BR",
+		"pt-PT":                        "pt_BR",
+		"de, nl;q=0.3":                 "nl",
+		"de, ja":                       "en",
+		"fr;q=0, nl":                   "nl",
+		"fr;q=abc, nl;q=0.1":           "nl",
+		"*":                            "en",
+		"nl;q=0.5, fr;q=0.5, pt;q=0.5": "nl",
+		" FR ; q=0.9 , en-GB ; q=0.8 ": "fr",
+	} {
+		if got := Negotiate(header); got != want {
+			t.Errorf("Negotiate(%q) = %s, want %s", header, got, want)
+		}
+	}
+}
+
+func validMovie() types.Movie {
+	return types.Movie{
+		Title:       "Heat",
+		Rating:      8,
+		ReleaseYear: 1995,
+		Director:    &types.Director{Name: "Michael Mann", Age: 81},
+		Cast:        &types.Cast{Actor: "Al Pacino", Actress: "Ashley Judd"},
+	}
+}
+
+// fields returns the field errors of a validation error, keyed by field
+func fields(t *testing.T, err error) map[string]apperr.FieldError {
+	t.Helper()
+	var appErr *apperr.Error
+	if !errors.As(err, &appErr) || appErr.Code != apperr.CodeValidationFailed {
+		t.Fatalf("got %v, want a validation error", err)
+	}
+	byField := map[string]apperr.FieldError{}
+	for _, fe := range appErr.Fields {
+		byField[fe.Field] = fe
+	}
+	return byField
+}
+
+func TestCustomRules(t *testing.T) {
+	nextYear := time.Now().Year() + 1
+	tooLate := time.Now().Year() + maxYearsAhead + 1
+
+	for _, tc := range []struct {
+		name  string
+		edit  func(*types.Movie)
+		field string
+		rule  string
+	}{
+		{"blank title", func(m *types.Movie) { m.Title = "   " }, "name", TagTitleLength},
+		{"leading space", func(m *types.Movie) { m.Title = " Heat" }, "name", TagNoLeadingSpace},
+		{"title too long", func(m *types.Movie) { m.Title = string(make([]rune, maxTitleLength+1)) }, "name", TagTitleLength},
+		{"year before film", func(m *types.Movie) { m.ReleaseYear = 1887 }, "release_year", TagReleaseYear},
+		{"year too far ahead", func(m *types.Movie) { m.ReleaseYear = tooLate }, "release_year", TagReleaseYear},
+		{"leading tab in director", func(m *types.Movie) { m.Director.Name = "\tMann" }, "director.name", TagNoLeadingSpace},
+		{"nested bound", func(m *types.Movie) { m.Director.Age = 111 }, "director.age", "lte"},
+		{"nested required", func(m *types.Movie) { m.Cast.Actress = "" }, "cast.actress", "required"},
+	} {
+		movie := validMovie()
+		tc.edit(&movie)
+		got := fields(t, Struct(movie, "en"))
+		if fe, ok := got[tc.field]; !ok || fe.Rule != tc.rule || len(got) != 1 {
+			t.Errorf("%s: got %+v, want only %s failing %s", tc.name, got, tc.field, tc.rule)
+		}
+	}
+
+	for _, edit := range []func(*types.Movie){
+		func(m *types.Movie) { m.ReleaseYear = 0 },
+		func(m *types.Movie) { m.ReleaseYear = nextYear },
+		func(m *types.Movie) { m.ReleaseYear = firstFilmYear },
+		func(m *types.Movie) { m.Title = string(make([]rune, maxTitleLength)) },
+		func(m *types.Movie) { m.Title = "Amélie" },
+	} {
+		movie := validMovie()
+		edit(&movie)
+		if err := Struct(movie, "en"); err != nil {
+			t.Errorf("valid movie %+v rejected: %v", movie, err)
+		}
+	}
+}
+
+func TestFieldPathsIncludeIndexes(t *testing.T) {
+	hook := types.Webhook{URL: "https://example.com/hook", Events: []string{"movie.created", "movie.renamed"}}
+	got := fields(t, Struct(hook, "en"))
+	if fe, ok := got["events[1]"]; !ok || fe.Rule != "oneof" {
+		t.Fatalf("got %+v, want events[1] failing oneof", got)
+	}
+}
+
+func TestMessagesPerLocale(t *testing.T) {
+	movie := validMovie()
+	movie.Director.Age = 111
+	movie.Title = ""
+	year := strconv.Itoa(time.Now().Year() + maxYearsAhead)
+
+	for locale, want := range map[string]map[string]string{
+		"en":    {"name": "name is required", "director.age": "director.age must be less than or equal to 110"},
+		"fr":    {"name": "name est obligatoire", "director.age": "director.age doit être inférieur ou égal à 110"},
+		"nl":    {"name": "name is een verplicht veld", "director.age": "director.age moet kleiner dan of gelijk aan 110 zijn"},
+		"pt_BR": {"name": "name é um campo obrigatório", "director.age": "director.age deve ser menor ou igual a 110"},
+	} {
+		got := fields(t, Struct(movie, locale))
+		for field, message := range want {
+			if got[field].Message != message {
+				t.Errorf("%s %s: %q, want %q", locale, field, got[field].Message, message)
+			}
+		}
+	}
+
+	movie = validMovie()
+	movie.ReleaseYear = 1800
+	if got := fields(t, Struct(movie, "fr"))["release_year"].Message; got != "release_year doit être une année comprise entre 1888 et "+year {
+		t.Errorf("release year message %q", got)
+	}
+}
+
+func TestRequestUsesAcceptLanguage(t *testing.T) {
+	r := httptest.NewRequest("POST", "/api/v1/movies", nil)
+	r.Header.Set("Accept-Language", "nl-NL, en;q=0.5")
+
+	movie := validMovie()
+	movie.Cast.Actor = ""
+	if got := fields(t, Request(r, movie))["cast.actor"].Message; got != "cast.actor is een verplicht veld" {
+		t.Fatalf("message %q", got)
+	}
+}
+
+func TestUntranslatedRulesFallBack(t *testing.T) {
+	hook := types.Webhook{URL: "not a url", Events: []string{"movie.created"}}
+	if got := fields(t, Struct(hook, "fr"))["url"]; got.Rule != "url" || got.Message != "url n'est pas valide" {
+		t.Fatalf("got %+v", got)
+	}
+}
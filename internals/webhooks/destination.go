@@ -0,0 +1,89 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// ErrPrivateDestination is returned for webhook URLs, and connections, that reach a loopback, private,
+// link-local or otherwise internal address
+var ErrPrivateDestination = errors.New("destination is not a public address")
+
+// internalPrefixes are ranges the netip predicates do not cover: "this network" and carrier-grade NAT
+var internalPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+}
+
+// publicAddr reports whether addr may receive webhooks; cloud metadata endpoints such as
+// 169.254.169.254 are link-local and excluded
+func publicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || addr.IsUnspecified() || addr.IsLoopback() || addr.IsPrivate() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() || addr.IsMulticast() {
+		return false
+	}
+	for _, prefix := range internalPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckURL rejects webhook URLs that are not http(s) or whose host resolves to any internal address.
+// Deliveries check again when connecting, since DNS can change after the webhook is created.
+func CheckURL(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return fmt.Errorf("not an http or https URL")
+	}
+
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", u.Hostname())
+	if err != nil {
+		return fmt.Errorf("resolving %s: %w", u.Hostname(), err)
+	}
+	for _, addr := range addrs {
+		if !publicAddr(addr) {
+			return fmt.Errorf("%s resolves to %s: %w", u.Hostname(), addr.Unmap(), ErrPrivateDestination)
+		}
+	}
+	return nil
+}
+
+// publicOnly is a net.Dialer Control that refuses connections to internal addresses. It sees the
+// address actually dialled, so it also covers redirects and DNS answers that changed since creation.
+func publicOnly(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !publicAddr(addrPort.Addr()) {
+		return fmt.Errorf("dialing %s: %w", address, ErrPrivateDestination)
+	}
+	return nil
+}
+
+// newClient returns the delivery client; unless allowPrivate is set it only connects to public addresses
+func newClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	if allowPrivate {
+		return &http.Client{Timeout: timeout}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	//? Connect directly, so the check sees the receiver's address rather than a proxy's
+	transport.Proxy = nil
+	transport.DialContext = (&net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   publicOnly,
+	}).DialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
@@ -0,0 +1,213 @@
+// Package webhooks queues catalogue events for subscribers and delivers them with signed, retried POSTs.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	crand "crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github/MahfujulSagor/movies_crud/internals/config"
+	"github/MahfujulSagor/movies_crud/internals/db"
+	"github/MahfujulSagor/movies_crud/internals/events"
+	"github/MahfujulSagor/movies_crud/internals/logger"
+	"github/MahfujulSagor/movies_crud/internals/types"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	SignatureHeader = "X-Movies-Signature"
+	TimestampHeader = "X-Movies-Timestamp"
+	EventHeader     = "X-Movies-Event"
+	DeliveryHeader  = "X-Movies-Delivery"
+
+	secretPrefix = "whsec_"
+)
+
+// Dispatcher turns relayed events into persisted deliveries and works through the queue
+type Dispatcher struct {
+	Store  db.WebhookStore
+	Client *http.Client
+	// MaxAttempts is how many failed attempts dead-letter a delivery
+	MaxAttempts  int
+	BaseBackoff  time.Duration
+	MaxBackoff   time.Duration
+	PollInterval time.Duration
+	BatchSize    int
+}
+
+func New(cfg *config.Config, store db.WebhookStore) *Dispatcher {
+	return &Dispatcher{
+		Store:        store,
+		Client:       newClient(cfg.WebhookConfig.Timeout, cfg.WebhookConfig.AllowPrivateNetworks),
+		MaxAttempts:  cfg.WebhookConfig.MaxAttempts,
+		BaseBackoff:  cfg.WebhookConfig.BaseBackoff,
+		MaxBackoff:   cfg.WebhookConfig.MaxBackoff,
+		PollInterval: time.Second,
+		BatchSize:    20,
+	}
+}
+
+// GenerateSecret returns a random signing secret for a new webhook
+func GenerateSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := crand.Read(buf); err != nil {
+		return "", err
+	}
+	return secretPrefix + base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// Sign computes the signature header value: HMAC-SHA256 over "<timestamp>.<body>", hex encoded
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (d *Dispatcher) Name() string { return "webhook" }
+
+// Send queues a delivery of e for every webhook subscribed to its type; it is the outbox relay's webhook sink.
+// Deliveries are keyed by the event ID, so when the relay retries after a partial failure the
+// webhooks already queued are skipped rather than sent the event twice.
+func (d *Dispatcher) Send(ctx context.Context, e events.Event) error {
+	hooks, err := d.Store.WebhooksForEvent(ctx, e.Type)
+	if err != nil {
+		return fmt.Errorf("finding webhooks for %s: %w", e.Type, err)
+	}
+	if len(hooks) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	for _, hook := range hooks {
+		_, err := d.Store.EnqueueDelivery(ctx, &types.WebhookDelivery{
+			WebhookID:     hook.ID,
+			EventID:       e.ID,
+			EventType:     e.Type,
+			Payload:       string(payload),
+			NextAttemptAt: now,
+			CreatedAt:     now,
+		})
+		if err != nil {
+			return fmt.Errorf("enqueueing delivery for webhook %d: %w", hook.ID, err)
+		}
+	}
+	return nil
+}
+
+// Run polls for due deliveries until ctx is done
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.deliverDue(ctx)
+		}
+	}
+}
+
+func (d *Dispatcher) deliverDue(ctx context.Context) {
+	due, err := d.Store.DueDeliveries(ctx, time.Now().UTC(), d.BatchSize)
+	if err != nil {
+		logger.Error.Println("Error loading due webhook deliveries:", err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, delivery := range due {
+		wg.Go(func() { d.attempt(ctx, delivery) })
+	}
+	wg.Wait()
+}
+
+// attempt sends one delivery and records the outcome, scheduling a retry or dead-lettering it
+func (d *Dispatcher) attempt(ctx context.Context, delivery *types.WebhookDelivery) {
+	status, err := d.send(ctx, delivery)
+	if ctx.Err() != nil {
+		//? Shutting down; leave the delivery pending for the next start
+		return
+	}
+
+	now := time.Now().UTC()
+	delivery.Attempts++
+	delivery.LastStatus = status
+	delivery.LastError = ""
+
+	switch {
+	case err == nil:
+		delivery.Status = db.DeliverySucceeded
+		delivery.DeliveredAt = &now
+	case delivery.Attempts >= d.MaxAttempts:
+		delivery.Status = db.DeliveryDead
+		delivery.LastError = err.Error()
+		logger.Error.Println("Webhook delivery", delivery.ID, "dead after", delivery.Attempts, "attempts:", err)
+	default:
+		delivery.LastError = err.Error()
+		delivery.NextAttemptAt = now.Add(d.backoff(delivery.Attempts))
+	}
+
+	if err := d.Store.UpdateDelivery(ctx, delivery); err != nil {
+		logger.Error.Println("Error recording webhook delivery", delivery.ID, err)
+	}
+}
+
+func (d *Dispatcher) send(ctx context.Context, delivery *types.WebhookDelivery) (int, error) {
+	hook, err := d.Store.GetWebhook(ctx, delivery.WebhookID)
+	if err != nil {
+		return 0, err
+	}
+
+	body := []byte(delivery.Payload)
+	timestamp := time.Now().Unix()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "movies-webhooks")
+	req.Header.Set(EventHeader, delivery.EventType)
+	req.Header.Set(DeliveryHeader, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(hook.Secret, timestamp, body))
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("receiver responded %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff doubles per attempt from BaseBackoff up to MaxBackoff, with up to 20% jitter
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.BaseBackoff << (attempts - 1)
+	if delay <= 0 || delay > d.MaxBackoff {
+		delay = d.MaxBackoff
+	}
+	return delay + rand.N(delay/5+1)
+}
@@ -0,0 +1,329 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"github/MahfujulSagor/movies_crud/internals/config"
+	"github/MahfujulSagor/movies_crud/internals/db"
+	"github/MahfujulSagor/movies_crud/internals/db/sqlite"
+	"github/MahfujulSagor/movies_crud/internals/events"
+	"github/MahfujulSagor/movies_crud/internals/logger"
+	"github/MahfujulSagor/movies_crud/internals/types"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func init() {
+	logger.Info = log.New(io.Discard, "", 0)
+	logger.Error = log.New(io.Discard, "", 0)
+}
+
+func TestPublicAddr(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"93.184.216.34":          true,
+		"2606:2800:220:1::1":     true,
+		"127.0.0.1":              false,
+		"10.1.2.3":               false,
+		"172.16.0.1":             false,
+		"192.168.1.1":            false,
+		"169.254.169.254":        false,
+		"100.64.0.1":             false,
+		"0.0.0.0":                false,
+		"::1":                    false,
+		"fd00::1":                false,
+		"fe80::1":                false,
+		"::ffff:127.0.0.1":       false,
+		"::ffff:169.254.169.254": false,
+		"224.0.0.1":              false,
+	} {
+		if got := publicAddr(netip.MustParseAddr(addr)); got != want {
+			t.Errorf("publicAddr(%s) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	for _, rawURL := range []string{
+		"http://127.0.0.1:8080/hook",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://[::1]/hook",
+		"https://10.0.0.5/hook",
+		"http://localhost/hook",
+		"ftp://93.184.216.34/hook",
+		"http:///hook",
+	} {
+		if err := CheckURL(context.Background(), rawURL); err == nil {
+			t.Errorf("CheckURL(%q) accepted an internal or invalid URL", rawURL)
+		}
+	}
+
+	if err := CheckURL(context.Background(), "https://93.184.216.34/hook"); err != nil {
+		t.Errorf("CheckURL rejected a public address: %v", err)
+	}
+}
+
+// receiver records webhook requests and answers each with the next status, then 200
+type receiver struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   []string
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.requests = append(rc.requests, r)
+	rc.bodies = append(rc.bodies, string(body))
+
+	status := http.StatusOK
+	if len(rc.statuses) > 0 {
+		status, rc.statuses = rc.statuses[0], rc.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func (rc *receiver) count() int {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return len(rc.requests)
+}
+
+// newTestDispatcher subscribes url to movie.created and returns a dispatcher that may reach it
+// on loopback, with backoff short enough for a test to wait out
+func newTestDispatcher(t *testing.T, url string) (*Dispatcher, *types.Webhook) {
+	t.Helper()
+	store, err := sqlite.New(&config.Config{DBPath: filepath.Join(t.TempDir(), "movies.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hook := &types.Webhook{URL: url, Events: []string{events.MovieCreated}, Secret: secret, CreatedAt: time.Now().UTC()}
+	if hook.ID, err = store.CreateWebhook(context.Background(), hook); err != nil {
+		t.Fatal(err)
+	}
+
+	d := &Dispatcher{
+		Store:        store,
+		Client:       newClient(time.Second, true),
+		MaxAttempts:  3,
+		BaseBackoff:  time.Millisecond,
+		MaxBackoff:   time.Millisecond,
+		PollInterval: time.Millisecond,
+		BatchSize:    10,
+	}
+	return d, hook
+}
+
+// queue sends a movie.created event through the dispatcher and returns the delivery it queued
+func queue(t *testing.T, d *Dispatcher) *types.WebhookDelivery {
+	t.Helper()
+	e := events.Event{ID: 42, Type: events.MovieCreated, MovieID: 7, Actor: "test", Time: time.Now().UTC()}
+	if err := d.Send(context.Background(), e); err != nil {
+		t.Fatal(err)
+	}
+	due, err := d.Store.DueDeliveries(context.Background(), time.Now().UTC(), 10)
+	if err != nil || len(due) != 1 {
+		t.Fatalf("queued %d deliveries (%v), want 1", len(due), err)
+	}
+	return due[0]
+}
+
+// deliverAfterBackoff waits out the retry delay, then works through due deliveries
+func deliverAfterBackoff(d *Dispatcher) {
+	time.Sleep(5 * time.Millisecond)
+	d.deliverDue(context.Background())
+}
+
+func reload(t *testing.T, d *Dispatcher, id int64) *types.WebhookDelivery {
+	t.Helper()
+	delivery, err := d.Store.GetDelivery(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return delivery
+}
+
+func TestDeliverySignsRequests(t *testing.T) {
+	rc := &receiver{}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	d, hook := newTestDispatcher(t, server.URL)
+	queued := queue(t, d)
+	d.deliverDue(context.Background())
+
+	if rc.count() != 1 {
+		t.Fatalf("receiver got %d requests, want 1", rc.count())
+	}
+	r, body := rc.requests[0], rc.bodies[0]
+	timestamp, err := strconv.ParseInt(r.Header.Get(TimestampHeader), 10, 64)
+	if err != nil {
+		t.Fatal("bad timestamp header:", err)
+	}
+	if got, want := r.Header.Get(SignatureHeader), Sign(hook.Secret, timestamp, []byte(body)); got != want {
+		t.Fatalf("signature %q, want %q", got, want)
+	}
+	if r.Header.Get(EventHeader) != events.MovieCreated || r.Header.Get(DeliveryHeader) != strconv.FormatInt(queued.ID, 10) {
+		t.Fatalf("event and delivery headers are %q and %q", r.Header.Get(EventHeader), r.Header.Get(DeliveryHeader))
+	}
+	if body != queued.Payload {
+		t.Fatalf("body %s, want the queued payload %s", body, queued.Payload)
+	}
+
+	if delivery := reload(t, d, queued.ID); delivery.Status != db.DeliverySucceeded || delivery.DeliveredAt == nil {
+		t.Fatalf("delivery is %s, want succeeded", delivery.Status)
+	}
+}
+
+func TestDeliveryRetriesFailures(t *testing.T) {
+	rc := &receiver{statuses: []int{http.StatusInternalServerError, http.StatusServiceUnavailable}}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	d, _ := newTestDispatcher(t, server.URL)
+	queued := queue(t, d)
+
+	d.deliverDue(context.Background())
+	delivery := reload(t, d, queued.ID)
+	if delivery.Status != db.DeliveryPending || delivery.Attempts != 1 || delivery.LastStatus != http.StatusInternalServerError {
+		t.Fatalf("after a 500 the delivery is %s with %d attempts and last status %d, want pending, 1, 500",
+			delivery.Status, delivery.Attempts, delivery.LastStatus)
+	}
+
+	deliverAfterBackoff(d)
+	deliverAfterBackoff(d)
+	delivery = reload(t, d, queued.ID)
+	if delivery.Status != db.DeliverySucceeded || delivery.Attempts != 3 {
+		t.Fatalf("after two failures and a 200 the delivery is %s with %d attempts", delivery.Status, delivery.Attempts)
+	}
+
+	//? Every attempt carries the same delivery ID, so receivers can deduplicate
+	for _, r := range rc.requests {
+		if r.Header.Get(DeliveryHeader) != strconv.FormatInt(queued.ID, 10) {
+			t.Fatalf("retry sent delivery ID %q", r.Header.Get(DeliveryHeader))
+		}
+	}
+}
+
+func TestDeliveryDeadLettersAfterMaxAttempts(t *testing.T) {
+	rc := &receiver{statuses: []int{500, 500, 500, 500}}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	d, _ := newTestDispatcher(t, server.URL)
+	queued := queue(t, d)
+
+	d.deliverDue(context.Background())
+	for range d.MaxAttempts {
+		deliverAfterBackoff(d)
+	}
+
+	delivery := reload(t, d, queued.ID)
+	if delivery.Status != db.DeliveryDead || delivery.Attempts != d.MaxAttempts || delivery.LastError == "" {
+		t.Fatalf("delivery is %s after %d attempts with error %q, want dead after %d",
+			delivery.Status, delivery.Attempts, delivery.LastError, d.MaxAttempts)
+	}
+	if rc.count() != d.MaxAttempts {
+		t.Fatalf("receiver got %d requests, want %d", rc.count(), d.MaxAttempts)
+	}
+}
+
+func TestDeliveryRefusesPrivateAddresses(t *testing.T) {
+	rc := &receiver{}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	//? As if DNS pointed the host at loopback after the webhook was accepted; redirects are dialled the same way
+	d, _ := newTestDispatcher(t, server.URL)
+	d.Client = newClient(time.Second, false)
+	queued := queue(t, d)
+
+	status, err := d.send(context.Background(), queued)
+	if !errors.Is(err, ErrPrivateDestination) || status != 0 {
+		t.Fatalf("send to loopback returned %d, %v; want ErrPrivateDestination", status, err)
+	}
+	if rc.count() != 0 {
+		t.Fatal("the loopback receiver was reached")
+	}
+}
+
+// failingStore fails the enqueue for one webhook, like a write error partway through Send
+type failingStore struct {
+	db.WebhookStore
+	failFor int64
+}
+
+func (s *failingStore) EnqueueDelivery(ctx context.Context, d *types.WebhookDelivery) (int64, error) {
+	if d.WebhookID == s.failFor {
+		return 0, errors.New("database is locked")
+	}
+	return s.WebhookStore.EnqueueDelivery(ctx, d)
+}
+
+func TestSendRetryDoesNotQueueTwice(t *testing.T) {
+	d, first := newTestDispatcher(t, "http://first.example/hook")
+	second := &types.Webhook{URL: "http://second.example/hook", Events: []string{events.MovieCreated}, Secret: "whsec_second_secret", CreatedAt: time.Now().UTC()}
+	var err error
+	if second.ID, err = d.Store.CreateWebhook(context.Background(), second); err != nil {
+		t.Fatal(err)
+	}
+
+	e := events.Event{ID: 42, Type: events.MovieCreated, MovieID: 7, Time: time.Now().UTC()}
+	store := d.Store
+	d.Store = &failingStore{WebhookStore: store, failFor: second.ID}
+	if err := d.Send(context.Background(), e); err == nil {
+		t.Fatal("Send succeeded although an enqueue failed")
+	}
+
+	//? The relay retries the whole event once the store recovers
+	d.Store = store
+	for range 2 {
+		if err := d.Send(context.Background(), e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, hook := range []*types.Webhook{first, second} {
+		deliveries, err := store.ListDeliveries(context.Background(), hook.ID, 10, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(deliveries) != 1 || deliveries[0].EventID != e.ID {
+			t.Errorf("webhook %d has %d deliveries, want one for event %d", hook.ID, len(deliveries), e.ID)
+		}
+	}
+
+	//? A different event is still queued
+	if err := d.Send(context.Background(), events.Event{ID: 43, Type: events.MovieCreated, MovieID: 7}); err != nil {
+		t.Fatal(err)
+	}
+	if deliveries, _ := store.ListDeliveries(context.Background(), first.ID, 10, 0); len(deliveries) != 2 {
+		t.Errorf("webhook %d has %d deliveries after a second event, want 2", first.ID, len(deliveries))
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	d := &Dispatcher{BaseBackoff: time.Second, MaxBackoff: 10 * time.Second}
+	for attempts, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 4: 8 * time.Second, 5: 10 * time.Second, 80: 10 * time.Second} {
+		//? Jitter adds up to a fifth on top
+		if got := d.backoff(attempts); got < want || got > want+want/5 {
+			t.Errorf("backoff(%d) = %s, want %s plus up to 20%%", attempts, got, want)
+		}
+	}
+}